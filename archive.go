@@ -0,0 +1,621 @@
+package main
+
+import (
+    "bytes"
+    "context"
+    "crypto/sha256"
+    "database/sql"
+    "encoding/base32"
+    "encoding/base64"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "internal/database"
+    "io"
+    "log/slog"
+    "mime"
+    "net/http"
+    "net/url"
+    "os"
+    "regexp"
+    "strings"
+    "time"
+    "github.com/google/uuid"
+    "golang.org/x/net/html"
+    "golang.org/x/net/html/atom"
+    "golang.org/x/net/html/charset"
+)
+
+// A snapshot saves at most this many stylesheets, images and fonts besides the page
+const maxArchiveResources = 50
+
+// How many posts agg archives after each feed it fetches
+const archivePerRound = 5
+
+// How long a whole snapshot, page and resources, may take
+const archiveTimeout = 2 * time.Minute
+
+// References to other files inside css: url(...) and @import "..."
+var cssURLPattern = regexp.MustCompile(`url\(\s*(?:"([^"]*)"|'([^']*)'|([^)'"\s]*))\s*\)|@import\s+(?:"([^"]*)"|'([^']*)')`)
+
+// archivedResource is one response saved in a snapshot, the page itself or a file it uses
+type archivedResource struct {
+    url         string
+    status      int
+    contentType string
+    data        []byte
+    fetchedAt   time.Time
+}
+
+// archivePending snapshots up to limit starred posts and posts from archive feeds that have no
+// snapshot yet, and returns how many were saved
+func archivePending(ctx context.Context, s *state, limit int) (int, error) {
+    // Snapshots deleted along with their posts leave files nothing uses any more
+    if _, err := s.dbState.DeleteUnusedArchiveBlobs(ctx); err != nil {
+        return 0, fmt.Errorf("%w | Reason: %w", ErrorArchivingPost, err)
+    }
+
+    posts, err := s.dbState.GetPostsToArchive(ctx, int32(limit))
+    if err != nil {
+        return 0, fmt.Errorf("%w | Reason: %w", ErrorGettingPosts, err)
+    }
+
+    saved := 0
+    for _, post := range posts {
+        ok, err := archivePost(ctx, s, post.ID, post.Url, post.FeedUrl)
+        if err != nil {
+            return saved, err
+        }
+        if ok {
+            saved++
+        }
+    }
+    return saved, nil
+}
+
+// archivePost takes a snapshot of a post's page and saves it, replacing any earlier one.  A page
+// that cannot be fetched is saved as a failed archive and reported as false, only database
+// failures and ctx being cancelled are errors.
+func archivePost(ctx context.Context, s *state, postID uuid.UUID, postURL, feedURL string) (bool, error) {
+    pageURL := postURL
+    if base := postBase(postURL, feedURL); base != nil {
+        pageURL = base.String()
+    }
+
+    // The time limit is for fetching only, saving a snapshot that took too long still has to work
+    snapCtx, cancel := context.WithTimeout(ctx, archiveTimeout)
+    resources, snapErr := snapshotPage(snapCtx, pageURL)
+    cancel()
+    if ctx.Err() != nil {
+        return false, fmt.Errorf("%w | Reason: %w", ErrorArchivingPost, ctx.Err())
+    }
+    if snapErr != nil {
+        slog.Warn("could not archive post", "post_id", postID, "url", pageURL, "error", snapErr)
+    }
+
+    qtx, tx, err := s.dbState.begin(ctx)
+    if err != nil {
+        return false, fmt.Errorf("%w | Reason: %w", ErrorArchivingPost, err)
+    }
+    defer tx.Rollback()
+
+    if err = qtx.DeleteArchiveForPost(ctx, postID); err != nil {
+        return false, fmt.Errorf("%w | Reason: %w", ErrorArchivingPost, err)
+    }
+
+    params := database.CreateArchiveParams{ ID: uuid.New(), PostID: postID, Url: pageURL, ArchivedAt: time.Now() }
+    if snapErr != nil {
+        params.Error = nullString(snapErr.Error())
+    } else {
+        // The page is saved under the url it ended up at, after redirects
+        params.Url = resources[0].url
+    }
+    archive, err := qtx.CreateArchive(ctx, params)
+    if err != nil {
+        return false, fmt.Errorf("%w | Reason: %w", ErrorArchivingPost, err)
+    }
+
+    for _, resource := range resources {
+        sum := sha256.Sum256(resource.data)
+        key := hex.EncodeToString(sum[:])
+        if err = qtx.CreateArchiveBlob(ctx, database.CreateArchiveBlobParams{ Sha256: key, Data: resource.data }); err != nil {
+            return false, fmt.Errorf("%w | Reason: %w", ErrorArchivingPost, err)
+        }
+        err = qtx.CreateArchiveResource(ctx, database.CreateArchiveResourceParams{ ArchiveID: archive.ID, Url: resource.url, Status: int32(resource.status),
+                                                                                   ContentType: resource.contentType, Sha256: key, FetchedAt: resource.fetchedAt })
+        if err != nil {
+            return false, fmt.Errorf("%w | Reason: %w", ErrorArchivingPost, err)
+        }
+    }
+
+    // Files only the replaced snapshot used go with it
+    if _, err = qtx.DeleteUnusedArchiveBlobs(ctx); err != nil {
+        return false, fmt.Errorf("%w | Reason: %w", ErrorArchivingPost, err)
+    }
+
+    if err = tx.Commit(); err != nil {
+        return false, fmt.Errorf("%w | Reason: %w", ErrorArchivingPost, err)
+    }
+    if snapErr == nil {
+        slog.Info("post archived", "post_id", postID, "url", params.Url, "resources", len(resources))
+    }
+    return snapErr == nil, nil
+}
+
+// snapshotPage fetches a page with the stylesheets and images it uses, and the fonts, images and
+// imported stylesheets those stylesheets use in turn.  The page comes first.  Files that cannot
+// be fetched are left out of the snapshot rather than failing it.
+func snapshotPage(ctx context.Context, pageURL string) ([]archivedResource, error) {
+    page, err := fetchResource(ctx, pageURL)
+    if err != nil {
+        return nil, err
+    }
+    if page.status != http.StatusOK {
+        return nil, fmt.Errorf("Error while fetching page: %v %v", page.status, http.StatusText(page.status))
+    }
+    if !strings.Contains(page.contentType, "html") {
+        return nil, fmt.Errorf("Error while fetching page: %v is not html", page.contentType)
+    }
+
+    base, _ := url.Parse(page.url)
+    doc, err := parsePage(page)
+    if err != nil {
+        return nil, err
+    }
+
+    resources := []archivedResource{ page }
+    queue     := pageReferences(doc, base)
+    seen      := map[string]bool{ page.url: true }
+    for len(queue) > 0 && len(resources) <= maxArchiveResources {
+        ref := queue[0]
+        queue = queue[1:]
+        if seen[ref] {
+            continue
+        }
+        seen[ref] = true
+
+        resource, err := fetchResource(ctx, ref)
+        if err != nil || resource.status != http.StatusOK {
+            slog.Debug("could not archive resource", "url", ref, "status", resource.status, "error", err)
+            continue
+        }
+        // Saved under the url the page refers to it by, so it can be found again when exporting
+        resource.url = ref
+        resources = append(resources, resource)
+
+        if isCSS(resource.contentType) {
+            cssBase, _ := url.Parse(ref)
+            queue = append(queue, cssReferences(string(resource.data), cssBase)...)
+        }
+    }
+    return resources, nil
+}
+
+func fetchResource(ctx context.Context, resourceURL string) (archivedResource, error) {
+    req, err := http.NewRequestWithContext(ctx, "GET", resourceURL, nil)
+    if err != nil {
+        return archivedResource{}, fmt.Errorf("Error formulating request: %w", err)
+    }
+
+    req.Header.Add("User-Agent", "gator")
+
+    resp, err := httpClient.Do(req)
+    if err != nil {
+        return archivedResource{}, fmt.Errorf("Error while fetching page: %w", err)
+    }
+    defer resp.Body.Close()
+
+    // One byte past the limit tells a file that is too big from one that just fits, a cut off
+    // copy would be saved as if it were the whole file
+    data, err := io.ReadAll(io.LimitReader(resp.Body, maxArticleBytes + 1))
+    if err != nil {
+        return archivedResource{}, fmt.Errorf("Error while reading page: %w", err)
+    }
+    if len(data) > maxArticleBytes {
+        return archivedResource{}, fmt.Errorf("Error while reading page: %v is larger than %v bytes", resourceURL, maxArticleBytes)
+    }
+
+    contentType := resp.Header.Get("Content-Type")
+    if contentType == "" {
+        contentType = http.DetectContentType(data)
+    }
+    return archivedResource{ url: resp.Request.URL.String(), status: resp.StatusCode, contentType: contentType, data: data, fetchedAt: time.Now() }, nil
+}
+
+// parsePage parses a saved page, decoding it from whatever charset it was sent in
+func parsePage(page archivedResource) (*html.Node, error) {
+    body, err := charset.NewReader(bytes.NewReader(page.data), page.contentType)
+    if err != nil {
+        return nil, fmt.Errorf("Error while reading page: %w", err)
+    }
+    doc, err := html.Parse(body)
+    if err != nil {
+        return nil, fmt.Errorf("Error while parsing page: %w", err)
+    }
+    return doc, nil
+}
+
+// pageReferences lists the stylesheets and images a page uses, as absolute urls
+func pageReferences(doc *html.Node, base *url.URL) []string {
+    refs := []string{}
+    add  := func(raw string) {
+        if resolved, ok := resolveURL(raw, base, sanitizeURLAttrs["src"]); ok {
+            refs = append(refs, resolved)
+        }
+    }
+
+    for _, n := range elements(doc) {
+        switch n.DataAtom {
+        case atom.Link:
+            if isStylesheet(n) {
+                add(htmlAttr(n, "href"))
+            }
+        case atom.Img:
+            add(imageSource(n))
+        case atom.Style:
+            refs = append(refs, cssReferences(innerText(n), base)...)
+        }
+        if style := htmlAttr(n, "style"); style != "" {
+            refs = append(refs, cssReferences(style, base)...)
+        }
+    }
+    return refs
+}
+
+// cssReferences lists the files a stylesheet refers to, as absolute urls
+func cssReferences(css string, base *url.URL) []string {
+    refs := []string{}
+    for _, match := range cssURLPattern.FindAllStringSubmatch(css, -1) {
+        if resolved, ok := resolveURL(strings.Join(match[1:], ""), base, sanitizeURLAttrs["src"]); ok {
+            refs = append(refs, resolved)
+        }
+    }
+    return refs
+}
+
+// imageSource is where an image loads from, looking past the placeholders lazy loaders use
+func imageSource(n *html.Node) string {
+    src := htmlAttr(n, "src")
+    if lazy := htmlAttr(n, "data-src"); lazy != "" && (src == "" || strings.HasPrefix(src, "data:")) {
+        return lazy
+    }
+    return src
+}
+
+func isStylesheet(n *html.Node) bool {
+    for _, rel := range strings.Fields(strings.ToLower(htmlAttr(n, "rel"))) {
+        if rel == "stylesheet" {
+            return true
+        }
+    }
+    return false
+}
+
+func isCSS(contentType string) bool {
+    mediaType, _, _ := mime.ParseMediaType(contentType)
+    return mediaType == "text/css"
+}
+
+// loadArchive reads a snapshot back from the database
+func loadArchive(s *state, archiveID uuid.UUID) ([]archivedResource, error) {
+    rows, err := s.dbState.GetArchiveResources(context.Background(), archiveID)
+    if err != nil {
+        return nil, fmt.Errorf("%w | Reason: %w", ErrorGettingArchive, err)
+    }
+    resources := []archivedResource{}
+    for _, row := range rows {
+        resources = append(resources, archivedResource{ url: row.Url, status: int(row.Status), contentType: row.ContentType, data: row.Data, fetchedAt: row.FetchedAt })
+    }
+    return resources, nil
+}
+
+// singleFileHTML rebuilds a snapshot as one html file that needs nothing else: stylesheets become
+// <style> elements, images and the files stylesheets use become data: urls, and scripts, frames
+// and other live content are left out.  Links are made absolute so they still lead somewhere.
+func singleFileHTML(pageURL string, archivedAt time.Time, resources []archivedResource) ([]byte, error) {
+    files := map[string]archivedResource{}
+    for _, resource := range resources {
+        files[resource.url] = resource
+    }
+    page, ok := files[pageURL]
+    if !ok {
+        return nil, fmt.Errorf("%w | Reason: the snapshot has no page", ErrorGettingArchive)
+    }
+
+    doc, err := parsePage(page)
+    if err != nil {
+        return nil, err
+    }
+    base, _ := url.Parse(pageURL)
+    inline  := &inliner{ files: files }
+
+    removeNodes(doc, func(n *html.Node) bool {
+        switch n.DataAtom {
+        case atom.Script, atom.Noscript, atom.Iframe, atom.Frame, atom.Object, atom.Embed, atom.Base, atom.Source:
+            return true
+        case atom.Meta:
+            // The file is written as utf-8 whatever the page was sent in
+            return hasAttr(n, "charset") || strings.EqualFold(htmlAttr(n, "http-equiv"), "content-type")
+        case atom.Link:
+            return !isStylesheet(n) && !strings.Contains(strings.ToLower(htmlAttr(n, "rel")), "canonical")
+        }
+        return false
+    })
+
+    for _, n := range elements(doc) {
+        n.Attr = dropAttrs(n.Attr, func(a html.Attribute) bool { return strings.HasPrefix(strings.ToLower(a.Key), "on") || a.Key == "srcset" })
+        if style := htmlAttr(n, "style"); style != "" {
+            setAttr(n, "style", inline.css(style, base, 0))
+        }
+
+        switch n.DataAtom {
+        case atom.Link:
+            if !isStylesheet(n) {
+                break
+            }
+            href, ok := resolveURL(htmlAttr(n, "href"), base, sanitizeURLAttrs["src"])
+            css, found := files[href]
+            if !ok || !found {
+                break
+            }
+            cssBase, _ := url.Parse(href)
+            style := &html.Node{ Type: html.ElementNode, Data: "style", DataAtom: atom.Style }
+            style.AppendChild(&html.Node{ Type: html.TextNode, Data: inline.css(string(css.data), cssBase, 0) })
+            n.Parent.InsertBefore(style, n)
+            n.Parent.RemoveChild(n)
+        case atom.Style:
+            if n.FirstChild != nil && n.FirstChild.Type == html.TextNode {
+                n.FirstChild.Data = inline.css(n.FirstChild.Data, base, 0)
+            }
+        case atom.Img:
+            src, ok := resolveURL(imageSource(n), base, sanitizeURLAttrs["src"])
+            if !ok {
+                break
+            }
+            n.Attr = dropAttrs(n.Attr, func(a html.Attribute) bool { return a.Key == "data-src" })
+            if image, found := files[src]; found {
+                src = dataURL(image)
+            }
+            setAttr(n, "src", src)
+        case atom.A:
+            // Links that could run something, javascript: ones say, lose their href
+            if href, ok := resolveURL(htmlAttr(n, "href"), base, sanitizeURLAttrs["href"]); ok {
+                setAttr(n, "href", href)
+            } else {
+                n.Attr = dropAttrs(n.Attr, func(a html.Attribute) bool { return a.Key == "href" })
+            }
+        case atom.Head:
+            n.InsertBefore(&html.Node{ Type: html.ElementNode, Data: "meta", DataAtom: atom.Meta, Attr: []html.Attribute{ { Key: "charset", Val: "utf-8" } } }, n.FirstChild)
+        }
+    }
+
+    note := &html.Node{ Type: html.CommentNode, Data: fmt.Sprintf(" Saved by gator from %v on %v ", pageURL, archivedAt.UTC().Format(time.RFC3339)) }
+    if doc.FirstChild != nil && doc.FirstChild.Type == html.DoctypeNode {
+        doc.InsertBefore(note, doc.FirstChild.NextSibling)
+    } else {
+        doc.InsertBefore(note, doc.FirstChild)
+    }
+
+    out := &bytes.Buffer{}
+    if err := html.Render(out, doc); err != nil {
+        return nil, fmt.Errorf("%w | Reason: %w", ErrorExportingArchive, err)
+    }
+    return out.Bytes(), nil
+}
+
+// inliner replaces the files css refers to with data: urls of the saved copies
+type inliner struct {
+    files map[string]archivedResource
+}
+
+func (in *inliner) css(css string, base *url.URL, depth int) string {
+    return cssURLPattern.ReplaceAllStringFunc(css, func(ref string) string {
+        match := cssURLPattern.FindStringSubmatch(ref)
+        resolved, ok := resolveURL(strings.Join(match[1:], ""), base, sanitizeURLAttrs["src"])
+        file, found := in.files[resolved]
+        if !ok || !found {
+            return ref
+        }
+
+        // Imported stylesheets have their own files inlined, a few levels deep
+        if isCSS(file.contentType) && depth < 3 {
+            fileBase, _ := url.Parse(resolved)
+            file.data = []byte(in.css(string(file.data), fileBase, depth + 1))
+        }
+        if strings.HasPrefix(ref, "@import") {
+            return `@import url("` + dataURL(file) + `")`
+        }
+        return `url("` + dataURL(file) + `")`
+    })
+}
+
+func dataURL(file archivedResource) string {
+    mediaType, _, err := mime.ParseMediaType(file.contentType)
+    if err != nil {
+        mediaType = http.DetectContentType(file.data)
+    }
+    return "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(file.data)
+}
+
+func dropAttrs(attrs []html.Attribute, drop func(html.Attribute) bool) []html.Attribute {
+    kept := attrs[:0]
+    for _, a := range attrs {
+        if !drop(a) {
+            kept = append(kept, a)
+        }
+    }
+    return kept
+}
+
+// writeWARC writes snapshots as a WARC 1.1 file, the format web archives use: a warcinfo record
+// describing the file, then a response record for every page, stylesheet and image saved
+func writeWARC(w io.Writer, snapshots [][]archivedResource) error {
+    info := "software: gator\r\nformat: WARC File Format 1.1\r\n"
+    err  := writeWARCRecord(w, []string{ "WARC-Type: warcinfo", "WARC-Date: " + time.Now().UTC().Format(time.RFC3339),
+                                         "Content-Type: application/warc-fields" }, []byte(info))
+    if err != nil {
+        return err
+    }
+
+    for _, resources := range snapshots {
+        for _, resource := range resources {
+            response := &bytes.Buffer{}
+            fmt.Fprintf(response, "HTTP/1.1 %v %v\r\n", resource.status, http.StatusText(resource.status))
+            fmt.Fprintf(response, "Content-Type: %v\r\nContent-Length: %v\r\n\r\n", resource.contentType, len(resource.data))
+            response.Write(resource.data)
+
+            digest := sha256.Sum256(resource.data)
+            err := writeWARCRecord(w, []string{ "WARC-Type: response",
+                                                "WARC-Date: " + resource.fetchedAt.UTC().Format(time.RFC3339),
+                                                "WARC-Target-URI: " + resource.url,
+                                                "WARC-Payload-Digest: sha256:" + base32.StdEncoding.EncodeToString(digest[:]),
+                                                "Content-Type: application/http;msgtype=response" }, response.Bytes())
+            if err != nil {
+                return err
+            }
+        }
+    }
+    return nil
+}
+
+func writeWARCRecord(w io.Writer, headers []string, block []byte) error {
+    record := &bytes.Buffer{}
+    record.WriteString("WARC/1.1\r\n")
+    fmt.Fprintf(record, "WARC-Record-ID: <urn:uuid:%v>\r\n", uuid.New())
+    for _, header := range headers {
+        record.WriteString(header + "\r\n")
+    }
+    fmt.Fprintf(record, "Content-Length: %v\r\n\r\n", len(block))
+    record.Write(block)
+    record.WriteString("\r\n\r\n")
+
+    _, err := w.Write(record.Bytes())
+    return err
+}
+
+func archiveList(s *state, cmd command, user database.User) error {
+    archives, err := s.dbState.GetArchivesForUser(context.Background(), user.ID)
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorGettingArchive, err)
+    }
+    if len(archives) == 0 {
+        fmt.Println("No archived posts, star a post or turn on archive for a feed (gator feed settings --archive) and run agg")
+        return nil
+    }
+
+    for _, archive := range archives {
+        fmt.Printf("%v | %v | %v\n", archive.ArchivedAt.Format("2006-01-02 15:04"), archive.Title, archive.Url)
+        if archive.Error.Valid {
+            fmt.Printf("    failed: %v\n", archive.Error.String)
+        }
+    }
+    return nil
+}
+
+// archiveSave snapshots the posts given now, replacing earlier snapshots, or with no posts given
+// the starred and archive feed posts still waiting for one
+func archiveSave(s *state, cmd command, user database.User) error {
+    if len(cmd.args) == 0 {
+        saved, err := archivePending(context.Background(), s, cmd.flagInt("limit"))
+        if err != nil {
+            return err
+        }
+        fmt.Printf("Archived %v posts\n", saved)
+        return nil
+    }
+
+    for _, postURL := range cmd.args {
+        post, err := s.dbState.GetPostToArchiveByUrl(context.Background(), database.GetPostToArchiveByUrlParams{ Url: postURL, UserID: user.ID })
+        if err != nil {
+            return fmt.Errorf("%w | Reason: %w", ErrorGettingPosts, err)
+        }
+        ok, err := archivePost(context.Background(), s, post.ID, post.Url, post.FeedUrl)
+        if err != nil {
+            return err
+        }
+        if !ok {
+            fmt.Printf("Could not archive %v, see gator archive list for why\n", postURL)
+            continue
+        }
+        fmt.Printf("Archived %v\n", postURL)
+    }
+    return nil
+}
+
+// archiveExport writes one post's snapshot as a single html file, or snapshots as a WARC file:
+// the one post's, or with no post given every snapshot of the user's posts
+func archiveExport(s *state, cmd command, user database.User) error {
+    format := cmd.flagString("format")
+    if format != "html" && format != "warc" {
+        return fmt.Errorf("%w | Reason: unknown format %q, use html or warc", ErrorExportingArchive, format)
+    }
+
+    type snapshot struct {
+        id         uuid.UUID
+        url        string
+        archivedAt time.Time
+    }
+    snapshots := []snapshot{}
+    if len(cmd.args) == 1 {
+        archive, err := s.dbState.GetArchiveForPostUrl(context.Background(), database.GetArchiveForPostUrlParams{ Url: cmd.args[0], UserID: user.ID })
+        if errors.Is(err, sql.ErrNoRows) {
+            return fmt.Errorf("%w | Reason: %v has not been archived, gator archive save %v archives it", ErrorGettingArchive, cmd.args[0], cmd.args[0])
+        }
+        if err != nil {
+            return fmt.Errorf("%w | Reason: %w", ErrorGettingArchive, err)
+        }
+        if archive.Error.Valid {
+            return fmt.Errorf("%w | Reason: the snapshot of %v failed: %v", ErrorGettingArchive, cmd.args[0], archive.Error.String)
+        }
+        snapshots = append(snapshots, snapshot{ archive.ID, archive.Url, archive.ArchivedAt })
+    } else {
+        if format == "html" {
+            return fmt.Errorf("%w | Reason: --format html exports one post, give its url", ErrorExportingArchive)
+        }
+        archives, err := s.dbState.GetArchivesForUser(context.Background(), user.ID)
+        if err != nil {
+            return fmt.Errorf("%w | Reason: %w", ErrorGettingArchive, err)
+        }
+        for _, archive := range archives {
+            if !archive.Error.Valid {
+                snapshots = append(snapshots, snapshot{ archive.ID, archive.Url, archive.ArchivedAt })
+            }
+        }
+    }
+
+    out := &bytes.Buffer{}
+    if format == "html" {
+        resources, err := loadArchive(s, snapshots[0].id)
+        if err != nil {
+            return err
+        }
+        page, err := singleFileHTML(snapshots[0].url, snapshots[0].archivedAt, resources)
+        if err != nil {
+            return err
+        }
+        out.Write(page)
+    } else {
+        all := [][]archivedResource{}
+        for _, snap := range snapshots {
+            resources, err := loadArchive(s, snap.id)
+            if err != nil {
+                return err
+            }
+            all = append(all, resources)
+        }
+        if err := writeWARC(out, all); err != nil {
+            return fmt.Errorf("%w | Reason: %w", ErrorExportingArchive, err)
+        }
+    }
+
+    path := cmd.flagString("file")
+    if path == "" {
+        _, err := os.Stdout.Write(out.Bytes())
+        return err
+    }
+    if err := os.WriteFile(path, out.Bytes(), 0o644); err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorExportingArchive, err)
+    }
+    fmt.Printf("Exported %v snapshots to %v\n", len(snapshots), path)
+    return nil
+}
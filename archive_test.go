@@ -0,0 +1,291 @@
+package main
+
+import (
+    "bufio"
+    "bytes"
+    "context"
+    "database/sql"
+    "internal/database"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "testing"
+    "time"
+)
+
+// archiveSite serves a post page with a stylesheet, images, a script and a font, and counts requests
+func archiveSite(t *testing.T) (*httptest.Server, map[string]int) {
+    t.Helper()
+    files := map[string][2]string{
+        "/post": { "text/html; charset=utf-8", `<!DOCTYPE html>
+<html><head>
+<meta charset="utf-8">
+<title>Saved post</title>
+<link rel="stylesheet" href="/css/site.css">
+<link rel="preload" href="/font.woff2">
+<script src="/app.js"></script>
+<style>body { background: url('/img/bg.png') }</style>
+</head><body onload="track()">
+<h1>Saved post</h1>
+<p>An article worth keeping, with <a href="/about">a link</a> and <a href="javascript:track()">a script</a>.</p>
+<img src="/img/photo.png" srcset="/img/photo-2x.png 2x" alt="Photo">
+<img src="data:image/gif;base64,R0lGODlhAQABAAAAACw=" data-src="img/lazy.png" alt="Lazy">
+<img src="/img/missing.png" alt="Gone">
+<script>track()</script>
+</body></html>` },
+        "/css/site.css":  { "text/css", `@import "print.css"; h1 { font-family: Serif; } p { background: url(../img/dot.png) }` },
+        "/css/print.css": { "text/css", `@font-face { src: url("/font.woff2") }` },
+        "/img/bg.png":    { "image/png", "bg-bytes" },
+        "/img/photo.png": { "image/png", "photo-bytes" },
+        "/img/lazy.png":  { "image/png", "lazy-bytes" },
+        "/img/dot.png":   { "image/png", "dot-bytes" },
+        "/font.woff2":    { "font/woff2", "font-bytes" },
+        "/app.js":        { "text/javascript", "track()" },
+    }
+
+    requests := map[string]int{}
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        requests[r.URL.Path]++
+        file, ok := files[r.URL.Path]
+        if !ok {
+            http.NotFound(w, r)
+            return
+        }
+        w.Header().Set("Content-Type", file[0])
+        w.Write([]byte(file[1]))
+    }))
+    t.Cleanup(server.Close)
+    return server, requests
+}
+
+func TestSnapshotPage(t *testing.T) {
+    server, requests := archiveSite(t)
+
+    resources, err := snapshotPage(context.Background(), server.URL + "/post")
+    if err != nil {
+        t.Fatal(err)
+    }
+    urls := []string{}
+    for _, resource := range resources {
+        urls = append(urls, strings.TrimPrefix(resource.url, server.URL))
+    }
+    if got := strings.Join(urls, " "); got != "/post /css/site.css /img/bg.png /img/photo.png /img/lazy.png /css/print.css /img/dot.png /font.woff2" {
+        t.Errorf("resources = %v", got)
+    }
+    if requests["/app.js"] != 0 || requests["/img/photo-2x.png"] != 0 {
+        t.Error("scripts and srcset images are not part of a snapshot")
+    }
+
+    if _, err := snapshotPage(context.Background(), server.URL + "/missing"); err == nil {
+        t.Error("expected an error for a missing page")
+    }
+}
+
+// Files over the size limit are left out, not saved cut short
+func TestFetchResourceTooBig(t *testing.T) {
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        size, _ := strconv.Atoi(r.URL.Query().Get("size"))
+        w.Write(bytes.Repeat([]byte("x"), size))
+    }))
+    defer server.Close()
+
+    if resource, err := fetchResource(context.Background(), server.URL + "/?size=" + strconv.Itoa(maxArticleBytes)); err != nil || len(resource.data) != maxArticleBytes {
+        t.Errorf("a file at the limit = %v bytes, %v", len(resource.data), err)
+    }
+    if _, err := fetchResource(context.Background(), server.URL + "/?size=" + strconv.Itoa(maxArticleBytes + 1)); err == nil {
+        t.Error("a file over the limit was saved")
+    }
+}
+
+// archivedPost adds a post linking to the archive site's page
+func archivedPost(t *testing.T, s *state, q database.Querier, server *httptest.Server) (database.User, database.Feed, database.Post) {
+    t.Helper()
+    alice := loginAs(t, s, q, "alice", roleUser)
+    feed  := addFeed(t, q, alice, "Blog", server.URL + "/feed")
+    post  := addPost(t, q, feed, "Saved post", server.URL + "/post", "", time.Hour)
+    return alice, feed, post
+}
+
+func TestArchiveStarredPosts(t *testing.T) {
+    s, fake := newTestState(t)
+    server, requests := archiveSite(t)
+    alice, feed, post := archivedPost(t, s, fake, server)
+    addPost(t, fake, feed, "Not starred", server.URL + "/other", "", time.Hour)
+    captureLogs(t, "error")
+
+    out := run(t, s, "archive", "save")
+    assertContains(t, out, "Archived 0 posts")
+
+    fake.StarPost(context.Background(), database.StarPostParams{ UserID: alice.ID, PostID: post.ID })
+    out = run(t, s, "archive", "save")
+    assertContains(t, out, "Archived 1 posts")
+    if len(fake.archives) != 1 || fake.archives[0].PostID != post.ID || len(fake.archived) != 8 {
+        t.Fatalf("%v archives with %v resources, want the starred post with 8", len(fake.archives), len(fake.archived))
+    }
+
+    // Snapshots are taken once, and files are stored once whichever snapshots use them
+    run(t, s, "archive", "save")
+    if requests["/post"] != 1 {
+        t.Errorf("post page fetched %v times", requests["/post"])
+    }
+    run(t, s, "archive", "save", server.URL + "/post")
+    if requests["/post"] != 2 || len(fake.archives) != 1 || len(fake.archived) != 8 || len(fake.blobs) != 8 {
+        t.Errorf("resaving: %v archives, %v resources, %v blobs", len(fake.archives), len(fake.archived), len(fake.blobs))
+    }
+
+    out = run(t, s, "archive", "list")
+    assertContains(t, out, "| Saved post | " + server.URL + "/post")
+
+    // Files of a snapshot deleted with its post are cleaned up on the next round
+    fake.DeleteArchiveForPost(context.Background(), post.ID)
+    if _, err := archivePending(context.Background(), s, 0); err != nil {
+        t.Fatal(err)
+    }
+    if len(fake.blobs) != 0 {
+        t.Errorf("%v files left with no snapshot using them", len(fake.blobs))
+    }
+}
+
+func TestArchiveFeedsAndFailures(t *testing.T) {
+    s, fake := newTestState(t)
+    server, requests := archiveSite(t)
+    _, feed, _ := archivedPost(t, s, fake, server)
+    addPost(t, fake, feed, "Deleted post", server.URL + "/deleted", "", 2 * time.Hour)
+    captureLogs(t, "error")
+
+    run(t, s, "feed", "settings", "--archive", "Blog")
+    if saved, err := archivePending(context.Background(), s, archivePerRound); err != nil || saved != 1 {
+        t.Fatalf("archivePending = %v, %v, want 1 saved", saved, err)
+    }
+    if len(fake.archives) != 2 {
+        t.Fatalf("%v archives, want both posts from the archive feed", len(fake.archives))
+    }
+
+    // A page that is gone is recorded as failed and not tried again every round
+    archivePending(context.Background(), s, archivePerRound)
+    if requests["/deleted"] != 1 {
+        t.Errorf("missing page fetched %v times", requests["/deleted"])
+    }
+    out := run(t, s, "archive", "list")
+    assertContains(t, out, "| Deleted post |", "failed: Error while fetching page: 404 Not Found")
+
+    assertErr(t, runErr(s, "archive", "export", server.URL + "/deleted"), ErrorGettingArchive)
+    assertErr(t, runErr(s, "archive", "export", server.URL + "/never"),   ErrorGettingArchive)
+}
+
+func TestArchiveExportHTML(t *testing.T) {
+    eachStore(t, func(t *testing.T, s *state, q database.Querier) {
+        server, _ := archiveSite(t)
+        _, _, post := archivedPost(t, s, q, server)
+        captureLogs(t, "error")
+        run(t, s, "archive", "save", post.Url)
+
+        // Served from the snapshot, with the site gone
+        server.Close()
+        path := filepath.Join(t.TempDir(), "post.html")
+        out  := run(t, s, "archive", "export", "--file", path, post.Url)
+        assertContains(t, out, "Exported 1 snapshots to " + path)
+
+        data, err := os.ReadFile(path)
+        if err != nil {
+            t.Fatal(err)
+        }
+        page := string(data)
+        assertContains(t, page, "<!-- Saved by gator from " + server.URL + "/post on ",
+                                `<meta charset="utf-8"/>`,
+                                "h1 { font-family: Serif; }",
+                                `@import url("data:text/css;base64,`,
+                                `url("data:image/png;base64,` + "ZG90LWJ5dGVz" + `")`,
+                                `<img src="data:image/png;base64,cGhvdG8tYnl0ZXM=" alt="Photo"/>`,
+                                `<img src="data:image/png;base64,bGF6eS1ieXRlcw==" alt="Lazy"/>`,
+                                `<img src="` + server.URL + `/img/missing.png" alt="Gone"/>`,
+                                `<a href="` + server.URL + `/about">`, "<a>a script</a>")
+        for _, left := range []string{ "<script", "onload", "srcset", "preload", `href="/css/site.css"`, "javascript:" } {
+            if strings.Contains(page, left) {
+                t.Errorf("exported page still has %q:\n%v", left, page)
+            }
+        }
+
+        assertErr(t, runErr(s, "archive", "export", "--format", "pdf", post.Url), ErrorExportingArchive)
+        assertErr(t, runErr(s, "archive", "export"),                              ErrorExportingArchive)
+
+        // Only for posts you follow or starred, like archive list
+        loginAs(t, s, q, "bob", roleUser)
+        assertErr(t, runErr(s, "archive", "export", post.Url), ErrorGettingArchive)
+        assertErr(t, runErr(s, "archive", "save", post.Url),   ErrorGettingPosts)
+    })
+}
+
+func TestArchiveExportWARC(t *testing.T) {
+    eachStore(t, func(t *testing.T, s *state, q database.Querier) {
+        server, _ := archiveSite(t)
+        _, feed, post := archivedPost(t, s, q, server)
+        other := addPost(t, q, feed, "Other", server.URL + "/img/photo.png", "", time.Hour)
+        captureLogs(t, "error")
+        run(t, s, "archive", "save", post.Url)
+        q.CreateArchive(context.Background(), database.CreateArchiveParams{ ID: other.ID, PostID: other.ID, Url: other.Url, ArchivedAt: time.Now(),
+                                                                               Error: sql.NullString{ String: "not html", Valid: true } })
+
+        out := run(t, s, "archive", "export", "--format", "warc")
+        records := readWARC(t, out)
+        if len(records) != 9 || records[0]["WARC-Type"] != "warcinfo" {
+            t.Fatalf("%v records, want a warcinfo and the 8 saved responses", len(records))
+        }
+        page := records[1]
+        if page["WARC-Type"] != "response" || page["WARC-Target-URI"] != server.URL + "/post" || page["Content-Type"] != "application/http;msgtype=response" {
+            t.Errorf("page record = %v", page)
+        }
+        if !strings.HasPrefix(page["block"], "HTTP/1.1 200 OK\r\nContent-Type: text/html; charset=utf-8\r\n") || !strings.HasSuffix(page["block"], "</html>") {
+            t.Errorf("page block = %q", page["block"])
+        }
+        if !strings.HasPrefix(page["WARC-Payload-Digest"], "sha256:") {
+            t.Errorf("digest = %q", page["WARC-Payload-Digest"])
+        }
+
+        // Someone who neither follows the feed nor starred the post has nothing to export
+        loginAs(t, s, q, "bob", roleUser)
+        if records := readWARC(t, run(t, s, "archive", "export", "--format", "warc")); len(records) != 1 {
+            t.Errorf("%v records exported for bob", len(records))
+        }
+    })
+}
+
+// readWARC splits a WARC file into records, each its headers and its block
+func readWARC(t *testing.T, data string) []map[string]string {
+    t.Helper()
+    records := []map[string]string{}
+    r := bufio.NewReader(strings.NewReader(data))
+    for {
+        version, err := r.ReadString('\n')
+        if err == io.EOF && version == "" {
+            return records
+        }
+        if version != "WARC/1.1\r\n" {
+            t.Fatalf("record %v starts with %q", len(records), version)
+        }
+
+        record := map[string]string{}
+        for {
+            line, err := r.ReadString('\n')
+            if err != nil {
+                t.Fatal(err)
+            }
+            if line == "\r\n" {
+                break
+            }
+            key, value, _ := strings.Cut(strings.TrimSuffix(line, "\r\n"), ": ")
+            record[key] = value
+        }
+        length, _ := strconv.Atoi(record["Content-Length"])
+        block := make([]byte, length + 4)
+        if _, err := io.ReadFull(r, block); err != nil || !bytes.HasSuffix(block, []byte("\r\n\r\n")) {
+            t.Fatalf("record %v block is not Content-Length long", len(records))
+        }
+        record["block"] = string(block[:length])
+        records = append(records, record)
+    }
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+    "bufio"
+    "context"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "internal/database"
+    "os"
+    "strings"
+    "time"
+    "golang.org/x/crypto/bcrypt"
+    "golang.org/x/term"
+)
+
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// unknownUserHash is checked against for logins to names nobody has, so they take as long as a wrong password
+const unknownUserHash = "$2a$10$hvZ7QjujLBM5TLzUotsX6u8YDiLfQn6xWAtZVbAS5RRPS1kNF2oZm"
+
+func hashPassword(password string) (string, error) {
+    hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+    if err != nil {
+        return "", err
+    }
+    return string(hash), nil
+}
+
+func checkPassword(hash, password string) error {
+    return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+}
+
+// newSession creates a session for the user and saves its token to the config.  Only a hash
+// of the token is kept in the database, so a leaked database cannot be used to log in.
+func newSession(s *state, user database.User) error {
+    raw := make([]byte, 32)
+    if _, err := rand.Read(raw); err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorCreatingSession, err)
+    }
+    token := hex.EncodeToString(raw)
+
+    err := s.dbState.CreateSession(context.Background(), database.CreateSessionParams{ TokenHash: hashToken(token), UserID:     user.ID,
+                                                                                     CreatedAt: time.Now(),       LastUsedAt: time.Now(), })
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorCreatingSession, err)
+    }
+
+    err = s.cfgState.SetSession(token)
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorSettingUser, err)
+    }
+    return nil
+}
+
+// currentUser looks up the user owning the session token in the config
+func currentUser(s *state) (database.User, error) {
+    if s.cfgState.SessionToken == "" {
+        return database.User{}, ErrorNotLoggedIn
+    }
+
+    user, err := s.dbState.GetUserBySession(context.Background(), hashToken(s.cfgState.SessionToken))
+    if err != nil {
+        return database.User{}, fmt.Errorf("%w | Reason: %w", ErrorNotLoggedIn, err)
+    }
+    return user, nil
+}
+
+func hashToken(token string) string {
+    sum := sha256.Sum256([]byte(token))
+    return hex.EncodeToString(sum[:])
+}
+
+// promptSecret reads a password without echoing it.  When stdin is not a terminal (scripts, CI)
+// the password is read as a plain line instead.
+func promptSecret(prompt string) (string, error) {
+    fmt.Print(prompt)
+    if stdinIsTerminal() {
+        secret, err := term.ReadPassword(int(os.Stdin.Fd()))
+        fmt.Println()
+        return string(secret), err
+    }
+
+    line, err := stdinReader.ReadString('\n')
+    if err != nil && line == "" {
+        return "", err
+    }
+    return strings.TrimRight(line, "\r\n"), nil
+}
+
+// promptNewPassword asks for a password twice and returns its hash
+func promptNewPassword() (string, error) {
+    password, err := promptSecret("Password: ")
+    if err != nil {
+        return "", err
+    }
+    if len(password) < 8 {
+        return "", errors.New("password must be at least 8 characters")
+    }
+
+    confirm, err := promptSecret("Confirm password: ")
+    if err != nil {
+        return "", err
+    }
+    if password != confirm {
+        return "", errors.New("passwords do not match")
+    }
+
+    return hashPassword(password)
+}
@@ -1,21 +1,58 @@
 package main
 
 import (
+    "database/sql"
+    "flag"
     "internal/config"
-    "internal/database"
 )
 
 type command struct {
-    name   string
-    args []string
+    name    string
+    args  []string
+    flags  *flag.FlagSet // parsed flags, as declared by info
+    info   *commandInfo
 }
 
+// commandInfo declares a command: what it is for, what it takes, and what runs it.
+// Usage lines, help and argument checking are all generated from it.
+type commandInfo struct {
+    name        string
+    args        string // positional arguments for the usage line, ex: "<name> <url>"
+    description string
+    minArgs     int
+    maxArgs     int    // -1 for no limit
+    flags       func(fs *flag.FlagSet)
+    handler     func(*state, command) error
+    subcommands []*commandInfo
+    needs       requirement
+    complete    []func(*state) []string // candidates for each positional argument, for shell completion
+    hidden      bool // left out of help and completion
+    bare        bool // output is for other programs, so no blank line or timing around it
+    rawArgs     bool // arguments are handed over as typed, flags and all
+
+    path        string // full name, ex: "folder create", set by register
+}
+
+// What has to work before a command can run.  Most commands need everything, the ones
+// that set up or repair gator get by with less.
+type requirement int
+
+const (
+    needsSchema   requirement = iota // a config, a database, and an up to date schema
+    needsDatabase                    // a config and a database, the schema may be out of date
+    needsLittle                      // runs even when the config or database is broken
+    needsNothing                     // runs without reading the config at all
+)
+
 type commands struct {
-    commandList map[string]func(*state, command) error
+    commandList map[string]*commandInfo
+    globalFlags *flag.FlagSet // flags that go before the command name, shown in help
 }
 
 type state struct {
     cfgState *config.Config
-    dbState  *database.Queries
+    dbState  store
+    dbConn   *sql.DB // the raw connection, for migrations and health checks
+    cfgErr   error // why the config could not be read, for init and doctor
+    output   string // --output format, table when empty
 }
-
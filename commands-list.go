@@ -0,0 +1,275 @@
+package main
+
+import (
+    "flag"
+    "fmt"
+    "time"
+)
+
+// newCommands registers every command gator knows, along with its flags and arguments
+func newCommands(globalFlags *flag.FlagSet) commands {
+    c := commands{ commandList: map[string]*commandInfo{}, globalFlags: globalFlags }
+
+    // Users
+    c.register(&commandInfo{ name: "login", args: "<name>", minArgs: 1, maxArgs: 1,
+                             description: "Log in as a user, asking for their password",
+                             complete: []func(*state) []string{ completeUsers },
+                             handler: handlerLogin })
+    c.register(&commandInfo{ name: "logout",
+                             description: "Log out and end the current session",
+                             handler: handlerLogout })
+    c.register(&commandInfo{ name: "register", args: "<name>", minArgs: 1, maxArgs: 1,
+                             description: "Create a user and log in as them, the first user becomes an admin",
+                             handler: handlerRegister })
+    c.register(&commandInfo{ name: "reset",
+                             description: "Admins only: delete everything, or only what the flags pick",
+                             flags: func(fs *flag.FlagSet) {
+                                 fs.Bool("yes",   false, "do not ask for confirmation")
+                                 fs.Bool("posts", false, "only delete posts")
+                                 fs.String("user", "",   "only delete this user along with their follows, handing feeds they created to other followers")
+                                 fs.Bool("feeds-without-followers", false, "only delete feeds nobody follows")
+                             },
+                             handler: middlewareAdmin(handlerReset) })
+    c.register(&commandInfo{ name: "bootstrap", args: "<name>", minArgs: 1, maxArgs: 1,
+                             description: "Make a user without a password, or a new one, the first admin, only while no admin can log in",
+                             complete: []func(*state) []string{ completeUsers },
+                             handler: handlerBootstrap })
+    c.register(&commandInfo{ name: "set-role", args: "<name> <user|admin>", minArgs: 2, maxArgs: 2,
+                             description: "Admins only: make a user an admin, or a plain user again",
+                             complete: []func(*state) []string{ completeUsers, completeRoles },
+                             handler: middlewareAdmin(handlerSetRole) })
+    c.register(&commandInfo{ name: "users",
+                             description: "List all users",
+                             handler: handlerUsers })
+    c.register(&commandInfo{ name: "user",
+                             description: "Manage a user account, your own or, for admins, anyone's",
+                             subcommands: []*commandInfo{
+                                 { name: "delete", args: "<name>", minArgs: 1, maxArgs: 1,
+                                   description: "Delete a user and their follows, handing their feeds to other followers",
+                                   complete: []func(*state) []string{ completeUsers },
+                                   flags: func(fs *flag.FlagSet) {
+                                       fs.Bool("yes", false, "do not ask for confirmation")
+                                   },
+                                   handler: middlewareLoggedIn(userDelete) },
+                                 { name: "rename", args: "<old_name> <new_name>", minArgs: 2, maxArgs: 2,
+                                   description: "Rename a user",
+                                   complete: []func(*state) []string{ completeUsers },
+                                   handler: middlewareLoggedIn(userRename) },
+                                 { name: "set-password", args: "<name>", minArgs: 1, maxArgs: 1,
+                                   description: "Set a user's password, asking for it twice",
+                                   complete: []func(*state) []string{ completeUsers },
+                                   handler: middlewareLoggedIn(userSetPassword) },
+                             } })
+
+    // Feeds
+    c.register(&commandInfo{ name: "agg", args: "<time_between_requests>", minArgs: 1, maxArgs: 1,
+                             description: "Fetch feeds forever, one every time_between_requests (ex: 1m)",
+                             handler: handlerAgg })
+    c.register(&commandInfo{ name: "addfeed", args: "<name> <url>", minArgs: 2, maxArgs: 2,
+                             description: "Add a feed and follow it",
+                             handler: middlewareLoggedIn(handlerAddFeed) })
+    c.register(&commandInfo{ name: "feeds",
+                             description: "List all feeds and who added them",
+                             handler: handlerFeedsWithName })
+    c.register(&commandInfo{ name: "feed",
+                             description: "Manage a feed you own, or any feed for admins",
+                             subcommands: []*commandInfo{
+                                 { name: "transfer", args: "<url|name|id> <user>", minArgs: 2, maxArgs: 2,
+                                   description: "Give a feed to another user",
+                                   complete: []func(*state) []string{ completeFeeds, completeUsers },
+                                   handler: middlewareLoggedIn(feedTransfer) },
+                                 { name: "settings", args: "<url|name|id>", minArgs: 1, maxArgs: 1,
+                                   description: "Show a feed's settings, or change them, only the flags given are changed",
+                                   complete: []func(*state) []string{ completeFeeds },
+                                   flags: func(fs *flag.FlagSet) {
+                                       fs.Bool("full-text", false, "fetch each new post's page and keep the article found in it, for feeds that only send teasers")
+                                       fs.Bool("archive",   false, "save a snapshot of every post's page, not just starred ones")
+                                   },
+                                   handler: middlewareLoggedIn(feedSettings) },
+                                 { name: "delete", args: "<url|name|id>", minArgs: 1, maxArgs: 1,
+                                   description: "Delete a feed, or hand it to a follower if others follow it",
+                                   complete: []func(*state) []string{ completeFeeds },
+                                   flags: func(fs *flag.FlagSet) {
+                                       fs.Bool("force", false, "admins only: delete the feed even if others follow it")
+                                   },
+                                   handler: middlewareLoggedIn(feedDelete) },
+                             } })
+    c.register(&commandInfo{ name: "follow", args: "<url|name|id>", minArgs: 1, maxArgs: 1,
+                             description: "Follow a feed",
+                             complete: []func(*state) []string{ completeFeeds },
+                             handler: middlewareLoggedIn(handlerFollow) })
+    c.register(&commandInfo{ name: "following",
+                             description: "List the feeds you follow",
+                             flags: func(fs *flag.FlagSet) {
+                                 fs.String("folder", "", "only list feeds in this folder and its subfolders")
+                             },
+                             handler: middlewareLoggedIn(handlerFollowing) })
+    c.register(&commandInfo{ name: "unfollow", args: "<url|name|id>", minArgs: 1, maxArgs: 1,
+                             description: "Stop following a feed",
+                             complete: []func(*state) []string{ completeFollowedFeeds },
+                             handler: middlewareLoggedIn(handlerUnfollow) })
+    c.register(&commandInfo{ name: "follow-settings", args: "<url|name|id>", minArgs: 1, maxArgs: 1,
+                             description: "Change how a feed you follow is shown, only the flags given are changed",
+                             complete: []func(*state) []string{ completeFollowedFeeds },
+                             flags: func(fs *flag.FlagSet) {
+                                 fs.String("title",  "",    "display title for the feed, only for you (empty string restores the feed name)")
+                                 fs.Bool("muted",    false, "hide the feed's posts from browse")
+                                 fs.Int("priority",  0,     "higher priority feeds are listed first")
+                                 fs.Bool("notify",   true,  "whether new posts from this feed should notify you")
+                             },
+                             handler: middlewareLoggedIn(handlerFollowSettings) })
+
+    // Posts
+    c.register(&commandInfo{ name: "browse", args: "[limit]", minArgs: 0, maxArgs: 1,
+                             description: "Show posts from the feeds you follow",
+                             flags: func(fs *flag.FlagSet) {
+                                 fs.String("feed",   "",          "only show posts from the feed with this name or url")
+                                 fs.String("since",  "",          "only show posts on or after this date (2006-01-02, RFC3339, or a duration ago like 72h)")
+                                 fs.String("until",  "",          "only show posts before this date (2006-01-02, RFC3339, or a duration ago like 72h)")
+                                 fs.String("match",  "",          "only show posts whose title or description contains this text")
+                                 fs.String("sort",   "published", "order posts by published or fetched time")
+                                 fs.Int("limit",     2,           "number of posts to show")
+                                 fs.Int("offset",    0,           "number of posts to skip, for paging back through history")
+                                 fs.Bool("unread",   false,       "only show posts not yet marked as read")
+                                 fs.String("folder", "",          "only show posts from feeds in this folder and its subfolders")
+                                 fs.Bool("include-muted", false,  "also show posts from feeds you have muted")
+                             },
+                             handler: middlewareLoggedIn(handlerBrowse) })
+    c.register(&commandInfo{ name: "tui", bare: true,
+                             description: "Read the feeds you follow full screen, with folders, stars and live refresh",
+                             flags: func(fs *flag.FlagSet) {
+                                 fs.Int("limit",        500,              "number of recent posts to load")
+                                 fs.Duration("refresh", 30 * time.Second, "how often to look for posts agg has added, 0 to never")
+                             },
+                             handler: middlewareLoggedIn(handlerTUI) })
+    c.register(&commandInfo{ name: "mark-read", args: "<post_url>...", minArgs: 1, maxArgs: -1,
+                             description: "Mark posts as read",
+                             handler: middlewareLoggedIn(handlerMarkRead) })
+    c.register(&commandInfo{ name: "mark-all-read",
+                             description: "Mark every post from the feeds you follow as read",
+                             flags: func(fs *flag.FlagSet) {
+                                 fs.String("feed",   "", "only mark posts from the feed with this name or url")
+                                 fs.String("folder", "", "only mark posts from feeds in this folder and its subfolders")
+                             },
+                             handler: middlewareLoggedIn(handlerMarkAllRead) })
+    c.register(&commandInfo{ name: "sanitize",
+                             description: "Admins only: clean every stored post again from the html its publisher sent",
+                             handler: middlewareAdmin(handlerSanitize) })
+    c.register(&commandInfo{ name: "archive",
+                             description: "Snapshots of the pages starred posts and posts from archive feeds link to",
+                             subcommands: []*commandInfo{
+                                 { name: "list",
+                                   description: "List saved snapshots of posts you follow or starred",
+                                   handler: middlewareLoggedIn(archiveList) },
+                                 { name: "save", args: "[post_url...]", minArgs: 0, maxArgs: -1,
+                                   description: "Snapshot the posts given now, or the starred and archive feed posts still waiting for one (agg does this as it goes)",
+                                   flags: func(fs *flag.FlagSet) {
+                                       fs.Int("limit", 20, "how many waiting posts to snapshot")
+                                   },
+                                   handler: middlewareLoggedIn(archiveSave) },
+                                 { name: "export", args: "[post_url]", minArgs: 0, maxArgs: 1,
+                                   description: "Write a post's snapshot as a single html file, or snapshots as a WARC file",
+                                   flags: func(fs *flag.FlagSet) {
+                                       fs.String("format", "html", "html (one post) or warc (one post, or all of yours with no url)")
+                                       fs.String("file",   "",     "file to write to instead of stdout")
+                                   },
+                                   handler: middlewareLoggedIn(archiveExport) },
+                             } })
+    c.register(&commandInfo{ name: "search", args: "<query>...", minArgs: 1, maxArgs: -1,
+                             description: `Search posts from the feeds you follow (ex: search "rust async" -video or search go or golang)`,
+                             flags: func(fs *flag.FlagSet) {
+                                 fs.Int("limit",    10, "number of results to show")
+                                 fs.String("since", "", "only search posts on or after this date (2006-01-02, RFC3339, or a duration ago like 720h)")
+                             },
+                             handler: middlewareLoggedIn(handlerSearch) })
+
+    // Folders
+    c.register(&commandInfo{ name: "folder",
+                             description: "Organise the feeds you follow into folders",
+                             subcommands: []*commandInfo{
+                                 { name: "create", args: "<name>", minArgs: 1, maxArgs: 1,
+                                   description: "Create a folder",
+                                   flags: func(fs *flag.FlagSet) {
+                                       fs.String("parent", "", "name of the folder to nest this folder under")
+                                   },
+                                   handler: middlewareLoggedIn(folderCreate) },
+                                 { name: "rename", args: "<old_name> <new_name>", minArgs: 2, maxArgs: 2,
+                                   description: "Rename a folder",
+                                   handler: middlewareLoggedIn(folderRename) },
+                                 { name: "move", args: "<url|name|id> <folder|->", minArgs: 2, maxArgs: 2,
+                                   description: "File a feed you follow into a folder, use - to unfile it",
+                                   complete: []func(*state) []string{ completeFollowedFeeds },
+                                   handler: middlewareLoggedIn(folderMove) },
+                                 { name: "list",
+                                   description: "List your folders",
+                                   handler: middlewareLoggedIn(folderList) },
+                             } })
+
+    // Setup
+    c.register(&commandInfo{ name: "profile", needs: needsLittle,
+                             description: "Manage config profiles, each with its own database and login",
+                             subcommands: []*commandInfo{
+                                 { name: "list",
+                                   description: "List profiles, marking the current one",
+                                   handler: profileList },
+                                 { name: "add", args: "<name> <db_url>", minArgs: 2, maxArgs: 2,
+                                   description: "Add a profile, or change the database url of one",
+                                   handler: profileAdd },
+                                 { name: "use", args: "<name>", minArgs: 1, maxArgs: 1,
+                                   description: "Make a profile the current one",
+                                   handler: profileUse },
+                             } })
+    c.register(&commandInfo{ name: "init", needs: needsLittle,
+                             description: "Create a config, testing the database url first",
+                             flags: func(fs *flag.FlagSet) {
+                                 fs.String("db-url",  "",        "database url, asked for when not given")
+                                 fs.String("profile", "default", "name of the profile to create")
+                                 fs.Bool("force",     false,     "save the profile even if the database cannot be reached")
+                             },
+                             handler: handlerInit })
+    c.register(&commandInfo{ name: "doctor", needs: needsLittle,
+                             description: "Check the config, database and schema, and suggest fixes",
+                             handler: handlerDoctor })
+    c.register(&commandInfo{ name: "migrate", needs: needsDatabase,
+                             description: "Manage the database schema",
+                             subcommands: []*commandInfo{
+                                 { name: "up",
+                                   description: "Apply every pending migration",
+                                   handler: migrateUp },
+                                 { name: "down",
+                                   description: "Roll back the latest migration",
+                                   handler: migrateDown },
+                                 { name: "status",
+                                   description: "List migrations and whether each is applied",
+                                   handler: migrateStatus },
+                             } })
+
+    c.register(&commandInfo{ name: "completion", args: "<bash|zsh|fish>", minArgs: 1, maxArgs: 1, needs: needsNothing, bare: true,
+                             description: "Print a shell completion script (Ex: source <(gator completion bash))",
+                             complete: []func(*state) []string{ func(*state) []string { return []string{ "bash", "zsh", "fish" } } },
+                             handler: handlerCompletion })
+    c.register(&commandInfo{ name: "__complete", args: "[word...]", minArgs: 0, maxArgs: -1, needs: needsLittle, bare: true, hidden: true, rawArgs: true,
+                             description: "List completions for the last word, used by the completion scripts",
+                             handler: func(s *state, cmd command) error {
+                                 for _, candidate := range c.complete(s, cmd.args) {
+                                     fmt.Println(candidate)
+                                 }
+                                 return nil
+                             } })
+
+    c.register(&commandInfo{ name: "shell", bare: true,
+                             description: "Run commands at a prompt with history and completion, keeping one database connection open",
+                             flags: func(fs *flag.FlagSet) {
+                                 fs.String("history", "", "file to keep command history in (default: $XDG_STATE_HOME/gator/history)")
+                             },
+                             handler: c.handlerShell })
+
+    c.register(&commandInfo{ name: "help", args: "[command] [subcommand]", minArgs: 0, maxArgs: 2, needs: needsNothing,
+                             description: "List commands, or show the usage and flags of one",
+                             complete: []func(*state) []string{ func(*state) []string { return c.names() } },
+                             handler: func(s *state, cmd command) error {
+                                 return c.help(cmd.args)
+                             } })
+
+    return c
+}
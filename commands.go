@@ -7,20 +7,50 @@ import (
     "github.com/google/uuid"
     "time"
     "context"
-    "log"
+    "log/slog"
     "strings"
     "database/sql"
     "strconv"
+    "flag"
+    "sort"
+    "io"
+    "os"
+    "sync"
 )
 
-var ErrorParsingTime = errors.New("Error: Unable to parse time from argument")
-var ErrorParsingInt  = errors.New("Error: Unable to parse int from argument")
+const roleUser  = "user"
+const roleAdmin = "admin"
+
+var ErrorParsingTime  = errors.New("Error: Unable to parse time from argument")
+var ErrorParsingInt   = errors.New("Error: Unable to parse int from argument")
+var ErrorParsingFlags = errors.New("Error: Unable to parse flags for command")
+
+var ErrorWritingOutput = errors.New("Error: Unable to write output")
+
+var ErrorReadingConfig = errors.New("Error: Unable to read config file")
+var ErrorWritingConfig = errors.New("Error: Unable to write config file")
+var ErrorConnectingDB  = errors.New("Error: Unable to connect to database")
+var ErrorDoctor        = errors.New("Error: Setup problems found")
+var ErrorMigrating     = errors.New("Error: Failure migrating database schema")
+var ErrorSchemaMismatch = errors.New("Error: Database schema does not match this gator")
 
 var ErrorSettingUser = errors.New("Error: User unable to be set")
+var ErrorNotLoggedIn = errors.New("Error: Not logged in (run gator login <name>)")
+var ErrorNotAdmin    = errors.New("Error: Only admins can run this command")
+var ErrorSettingRole = errors.New("Error: Unable to set user role")
+var ErrorLastAdmin   = errors.New("Error: Cannot remove the last admin who can log in")
+
+var ErrorSettingPassword = errors.New("Error: Unable to set password")
+var ErrorWrongPassword   = errors.New("Error: Wrong username or password")
+var ErrorNoPassword      = errors.New("Error: User has no password yet")
+var ErrorCreatingSession = errors.New("Error: Failure to create login session")
+var ErrorDeletingSession = errors.New("Error: Failure to delete login session")
 
 var EmptyArgList  = errors.New("Error: No argument for command that takes arguments")
 var NotEnoughArgs = errors.New("Error: Not enough arguments for command that takes multiple arguments")
+var TooManyArgs   = errors.New("Error: Too many arguments for command")
 
+var ErrorCompletion    = errors.New("Error: Unable to complete command line")
 var ErrorRunningHandle = errors.New("Error: Unable to run command")
 var NoCommandExists    = errors.New("Error: Unable to find command")
 
@@ -28,174 +58,596 @@ var ErrorRegisterUser    = errors.New("Error: Failure registering user in user t
 var ErrorGettingUser     = errors.New("Error: Failure to get user from user table (user probably does not exist)")
 var ErrorGettingUsers    = errors.New("Error: Failure to get all users from user table")
 var ErrorGettingUsername = errors.New("Error: Failure to get username based on uuid from user table")
+var ErrorRenamingUser    = errors.New("Error: Failure to rename user")
 
-var ErrorFetchingFeed  = errors.New("Error: Failure to fetch feed from web")
-var ErrorCreatingFeed  = errors.New("Error: Failure to create feed in feed table")
-var ErrorGettingFeeds  = errors.New("Error: Failure to get all feeds from feed table")
-var ErrorGettingFeed   = errors.New("Error: Failure to get feed from feed table")
+var ErrorFetchingFeed     = errors.New("Error: Failure to fetch feed from web")
+var ErrorCreatingFeed     = errors.New("Error: Failure to create feed in feed table")
+var ErrorGettingFeeds     = errors.New("Error: Failure to get all feeds from feed table")
+var ErrorGettingFeed      = errors.New("Error: Failure to get feed from feed table")
+var ErrorNotFeedOwner     = errors.New("Error: Feed belongs to another user")
+var ErrorTransferringFeed = errors.New("Error: Failure to transfer feed ownership")
+var ErrorUpdatingFeed     = errors.New("Error: Failure to update feed settings")
 
 var ErrorGettingNextFeed      = errors.New("Error: Failure to get next feed from feed table")
 var ErrorMarkingFeedAsFetched = errors.New("Error: Failure to mark feed as fetched")
 
 var ErrorCreatingFeedFollows    = errors.New("Error: Failure to create feed follow table")
 var ErrorGettingUserFeedFollows = errors.New("Error: Failure to get feed follows from follow table using username")
+var ErrorUpdatingFeedFollow     = errors.New("Error: Failure to update feed follow settings")
+var ErrorNotFollowing           = errors.New("Error: User is not following that feed")
+
+var ErrorCreatingFolder = errors.New("Error: Failure to create folder in folder table")
+var ErrorGettingFolder  = errors.New("Error: Failure to get folder from folder table")
+var ErrorUpdatingFolder = errors.New("Error: Failure to update folder")
+
+var ErrorGettingPosts    = errors.New("Error: Failure to get posts")
+var ErrorMarkingPostRead = errors.New("Error: Failure to mark posts as read")
+var ErrorSearchingPosts  = errors.New("Error: Failure to search posts")
+var ErrorStarringPost    = errors.New("Error: Failure to star post")
+var ErrorSanitizingPosts = errors.New("Error: Failure to save sanitised posts")
+var ErrorRunningTUI      = errors.New("Error: Failure to run the reader")
 
-var ErrorGettingPosts = errors.New("Error: Failure to get posts")
+var ErrorArchivingPost    = errors.New("Error: Failure to save archive snapshot")
+var ErrorGettingArchive   = errors.New("Error: Failure to get archive snapshot")
+var ErrorExportingArchive = errors.New("Error: Failure to export archive snapshots")
 
+var ErrorReadingInput = errors.New("Error: Failure to read shell input")
+
+var ErrorResetting            = errors.New("Error: Failure to reset database, nothing was deleted")
+var ErrorResetNotConfirmed    = errors.New("Error: Reset not confirmed, nothing was deleted")
+var ErrorDeletingPosts        = errors.New("Error: Failure to delete posts")
 var ErrorDeletingFeeds        = errors.New("Error: Failure to truncate feeds table")
 var ErrorDeletingUsers        = errors.New("Error: Failure to truncate users table")
 var ErrorDeletingFeedFollows  = errors.New("Error: Failure to truncate feed follows table")
 
 // Handlers
 func handlerLogin(s *state, cmd command) error {
-    if len(cmd.args) < 1 {
-        fmt.Println("usage: login <name>")
-        return EmptyArgList
+    user, err := s.dbState.GetUser(context.Background(), cmd.args[0])
+    if errors.Is(err, sql.ErrNoRows) {
+        // An unknown name fails just like a wrong password, after as long, so logins do not tell
+        // anyone which names are taken
+        password, _ := promptSecret("Password: ")
+        checkPassword(unknownUserHash, password)
+        return ErrorWrongPassword
+    }
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorGettingUser, err)
+    }
+
+    // Users registered before passwords existed cannot log in until an admin gives them one,
+    // otherwise whoever logged in as them first would get to pick it
+    if !user.PasswordHash.Valid {
+        return fmt.Errorf("%w | Reason: ask an admin to run gator user set-password %v", ErrorNoPassword, user.Name)
+    }
+
+    password, err := promptSecret("Password: ")
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorWrongPassword, err)
+    }
+    if checkPassword(user.PasswordHash.String, password) != nil {
+        return ErrorWrongPassword
+    }
+
+    err = newSession(s, user)
+    if err != nil {
+        return err
+    }
+
+    fmt.Printf("User %v has been set.\n", user.Name)
+    return nil
+}
+
+func handlerLogout(s *state, cmd command) error {
+    if s.cfgState.SessionToken == "" {
+        return ErrorNotLoggedIn
     }
-    _, err := s.dbState.GetUser(context.Background(), cmd.args[0])
+
+    err := s.dbState.DeleteSession(context.Background(), hashToken(s.cfgState.SessionToken))
     if err != nil {
-        return fmt.Errorf("%v | Reason: %v", ErrorGettingUser, err)
+        return fmt.Errorf("%w | Reason: %w", ErrorDeletingSession, err)
     }
 
-    err = s.cfgState.SetUser(cmd.args[0])
+    err = s.cfgState.SetSession("")
     if err != nil {
-        return fmt.Errorf("%v | Reason: %v", ErrorSettingUser, err)
+        return fmt.Errorf("%w | Reason: %w", ErrorSettingUser, err)
     }
 
-    fmt.Printf("User %v has been set.\n", cmd.args[0])
+    fmt.Println("Logged out.")
     return nil
 }
 
 func handlerRegister(s *state, cmd command) error {
-    if len(cmd.args) < 1 {
-        fmt.Println("usage: register <name>")
-        return EmptyArgList
+    hash, err := promptNewPassword()
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorSettingPassword, err)
+    }
+
+    ctx := context.Background()
+    qtx, tx, err := s.dbState.begin(ctx)
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorRegisterUser, err)
+    }
+    defer tx.Rollback()
+
+    // The first user on a fresh database administers it.  The lock makes registrations wait for
+    // each other, so two at once cannot both find the table empty.
+    err = qtx.LockUsers(ctx)
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorRegisterUser, err)
+    }
+    role := roleUser
+    count, err := qtx.CountUsers(ctx)
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorGettingUsers, err)
+    }
+    if count == 0 {
+        role = roleAdmin
     }
 
-    user, err := s.dbState.CreateUser(context.Background(), database.CreateUserParams{ ID: uuid.New(), CreatedAt: time.Now(), UpdatedAt: time.Now(), Name: cmd.args[0]})
+    user, err := qtx.CreateUser(ctx, database.CreateUserParams{ ID: uuid.New(), CreatedAt: time.Now(), UpdatedAt: time.Now(), Name: cmd.args[0],
+                                                                PasswordHash: sql.NullString{ String: hash, Valid: true }, Role: role })
     if err != nil {
-        return fmt.Errorf("%v | Reason: %v", ErrorRegisterUser, err)
+        return fmt.Errorf("%w | Reason: %w", ErrorRegisterUser, err)
     }
-    err = s.cfgState.SetUser(cmd.args[0])
+
+    err = tx.Commit()
     if err != nil {
-        return fmt.Errorf("%v | Reason: %v", ErrorSettingUser, err)
+        return fmt.Errorf("%w | Reason: %w", ErrorRegisterUser, err)
     }
 
-    fmt.Printf("User %v has been set.  ID: %v | CreatedAt: %v | UpdatedAt: %v | Name: %v\n", cmd.args[0], user.ID, user.CreatedAt, user.UpdatedAt, user.Name)
+    err = newSession(s, user)
+    if err != nil {
+        return err
+    }
+
+    fmt.Printf("User %v has been set.  ID: %v | CreatedAt: %v | UpdatedAt: %v | Name: %v | Role: %v\n", cmd.args[0], user.ID, user.CreatedAt, user.UpdatedAt, user.Name, user.Role)
     return nil
     
 }
 
-func handlerReset(s *state, cmd command) error {
-    err := s.dbState.DeleteFeeds(context.Background())
+func handlerReset(s *state, cmd command, admin database.User) error {
+    yes      := cmd.flagBool("yes")
+    posts    := cmd.flagBool("posts")
+    userName := cmd.flagString("user")
+    orphans  := cmd.flagBool("feeds-without-followers")
+
+    // No scope means everything, as before
+    everything := !posts && userName == "" && !orphans
+
+    scope := []string{}
+    if everything {
+        scope = append(scope, "ALL users, feeds, follows and posts")
+    }
+    if posts {
+        scope = append(scope, "all posts")
+    }
+    if userName != "" {
+        scope = append(scope, fmt.Sprintf("user %v with their follows and the feeds they created that nobody else follows", userName))
+    }
+    if orphans {
+        scope = append(scope, "feeds without followers")
+    }
+
+    if !yes {
+        if !stdinIsTerminal() {
+            return fmt.Errorf("%w | Reason: refusing to delete without --yes when not run from a terminal", ErrorResetNotConfirmed)
+        }
+        fmt.Printf("This will delete %v.\nType yes to continue: ", strings.Join(scope, ", "))
+        answer, _ := stdinReader.ReadString('\n')
+        if strings.TrimSpace(answer) != "yes" {
+            return ErrorResetNotConfirmed
+        }
+    }
+
+    ctx := context.Background()
+    qtx, tx, err := s.dbState.begin(ctx)
     if err != nil {
-        return fmt.Errorf("%v | Reason: %v", ErrorDeletingFeeds, err)
+        return fmt.Errorf("%w | Reason: %w", ErrorResetting, err)
+    }
+    defer tx.Rollback()
+
+    // Deleted row counts, in the order they happened
+    type count struct {
+        what string
+        n    int64
+    }
+    counts := []count{}
+
+    if everything || posts {
+        n, err := qtx.DeletePosts(ctx)
+        if err != nil {
+            return fmt.Errorf("%w | Reason: %w", ErrorDeletingPosts, err)
+        }
+        counts = append(counts, count{ "posts", n })
+    }
+
+    if userName != "" {
+        err = qtx.LockUsers(ctx)
+        if err != nil {
+            return fmt.Errorf("%w | Reason: %w", ErrorResetting, err)
+        }
+        user, err := qtx.GetUser(ctx, userName)
+        if err != nil {
+            return fmt.Errorf("%w | Reason: %w", ErrorGettingUser, err)
+        }
+        err = keepLastAdmin(ctx, qtx, user)
+        if err != nil {
+            return err
+        }
+
+        // Feeds other people follow are handed over like user delete does, not deleted from under them
+        transferred, deleted, err := handOverFeeds(ctx, qtx, user)
+        if err != nil {
+            return err
+        }
+        counts = append(counts, count{ "feeds handed over", transferred }, count{ "feeds", deleted })
+
+        n, err := qtx.DeleteFeedFollowsForUser(ctx, user.ID)
+        if err != nil {
+            return fmt.Errorf("%w | Reason: %w", ErrorDeletingFeedFollows, err)
+        }
+        counts = append(counts, count{ "feed follows", n })
+
+        n, err = qtx.DeleteUserByName(ctx, user.Name)
+        if err != nil {
+            return fmt.Errorf("%w | Reason: %w", ErrorDeletingUsers, err)
+        }
+        counts = append(counts, count{ "users", n })
+    }
+
+    if orphans {
+        n, err := qtx.DeleteFeedsWithoutFollowers(ctx)
+        if err != nil {
+            return fmt.Errorf("%w | Reason: %w", ErrorDeletingFeeds, err)
+        }
+        counts = append(counts, count{ "feeds without followers", n })
     }
 
-    err = s.dbState.DeleteUsers(context.Background())
+    if everything {
+        n, err := qtx.DeleteFeedFollows(ctx)
+        if err != nil {
+            return fmt.Errorf("%w | Reason: %w", ErrorDeletingFeedFollows, err)
+        }
+        counts = append(counts, count{ "feed follows", n })
+
+        n, err = qtx.DeleteFeeds(ctx)
+        if err != nil {
+            return fmt.Errorf("%w | Reason: %w", ErrorDeletingFeeds, err)
+        }
+        counts = append(counts, count{ "feeds", n })
+
+        n, err = qtx.DeleteUsers(ctx)
+        if err != nil {
+            return fmt.Errorf("%w | Reason: %w", ErrorDeletingUsers, err)
+        }
+        counts = append(counts, count{ "users", n })
+    }
+
+    err = tx.Commit()
     if err != nil {
-        return fmt.Errorf("%v | Reason: %v", ErrorDeletingUsers, err)
+        return fmt.Errorf("%w | Reason: %w", ErrorResetting, err)
     }
 
-    err = s.dbState.DeleteFeedFollows(context.Background())
+    fmt.Println("Reset complete, deleted:")
+    for _, c := range counts {
+        fmt.Printf("* %-24v %v\n", c.what, c.n)
+    }
+    return nil
+}
+
+// handlerBootstrap makes the first admin on a database that has users but no admin who can log in,
+// as happens when upgrading from before passwords and roles.  Once an admin can log in it refuses,
+// and they set everyone else's passwords and roles.  It only claims a user without a password, or
+// a new name, so it cannot be used to take over an account someone can already log in to.
+func handlerBootstrap(s *state, cmd command) error {
+    hash, err := promptNewPassword()
     if err != nil {
-        return fmt.Errorf("%v | Reason: %v", ErrorDeletingFeedFollows, err)
+        return fmt.Errorf("%w | Reason: %w", ErrorSettingPassword, err)
     }
-    
+
+    ctx := context.Background()
+    qtx, tx, err := s.dbState.begin(ctx)
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorSettingRole, err)
+    }
+    defer tx.Rollback()
+
+    err = qtx.LockUsers(ctx)
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorSettingRole, err)
+    }
+    admins, err := qtx.CountUsableAdmins(ctx)
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorGettingUsers, err)
+    }
+    if admins > 0 {
+        return fmt.Errorf("%w | Reason: this database already has an admin, ask them to run gator user set-password or gator set-role", ErrorNotAdmin)
+    }
+
+    user, err := qtx.GetUser(ctx, cmd.args[0])
+    switch {
+    case errors.Is(err, sql.ErrNoRows):
+        user, err = qtx.CreateUser(ctx, database.CreateUserParams{ ID: uuid.New(), CreatedAt: time.Now(), UpdatedAt: time.Now(), Name: cmd.args[0],
+                                                                   PasswordHash: sql.NullString{ String: hash, Valid: true }, Role: roleAdmin })
+        if err != nil {
+            return fmt.Errorf("%w | Reason: %w", ErrorRegisterUser, err)
+        }
+    case err != nil:
+        return fmt.Errorf("%w | Reason: %w", ErrorGettingUser, err)
+    case user.PasswordHash.Valid:
+        return fmt.Errorf("%w | Reason: %v already has a password, log in as them or bootstrap a user without one", ErrorNotAdmin, user.Name)
+    default:
+        err = qtx.SetUserPassword(ctx, database.SetUserPasswordParams{ ID: user.ID, PasswordHash: sql.NullString{ String: hash, Valid: true } })
+        if err != nil {
+            return fmt.Errorf("%w | Reason: %w", ErrorSettingPassword, err)
+        }
+        _, err = qtx.SetUserRole(ctx, database.SetUserRoleParams{ Name: user.Name, Role: roleAdmin })
+        if err != nil {
+            return fmt.Errorf("%w | Reason: %w", ErrorSettingRole, err)
+        }
+    }
+
+    err = tx.Commit()
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorSettingRole, err)
+    }
+
+    err = newSession(s, user)
+    if err != nil {
+        return err
+    }
+
+    fmt.Printf("User %v is now an admin and has been set.\n", user.Name)
+    return nil
+}
+
+func handlerSetRole(s *state, cmd command, admin database.User) error {
+    if cmd.args[1] != roleUser && cmd.args[1] != roleAdmin {
+        return fmt.Errorf("%w | Reason: role must be %v or %v", ErrorSettingRole, roleUser, roleAdmin)
+    }
+
+    ctx := context.Background()
+    qtx, tx, err := s.dbState.begin(ctx)
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorSettingRole, err)
+    }
+    defer tx.Rollback()
+
+    err = qtx.LockUsers(ctx)
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorSettingRole, err)
+    }
+    user, err := qtx.GetUser(ctx, cmd.args[0])
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorGettingUser, err)
+    }
+    if cmd.args[1] != roleAdmin {
+        err = keepLastAdmin(ctx, qtx, user)
+        if err != nil {
+            return err
+        }
+    }
+
+    _, err = qtx.SetUserRole(ctx, database.SetUserRoleParams{ Name: user.Name, Role: cmd.args[1] })
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorSettingRole, err)
+    }
+
+    err = tx.Commit()
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorSettingRole, err)
+    }
+
+    fmt.Printf("User %v is now %v\n", user.Name, cmd.args[1])
     return nil
 }
 
 func handlerUsers(s *state, cmd command) error {
     users, err := s.dbState.GetUsers(context.Background())
     if err != nil {
-        return fmt.Errorf("%v | Reason: %v", ErrorGettingUsers, err)
+        return fmt.Errorf("%w | Reason: %w", ErrorGettingUsers, err)
     }
 
+    // Not being logged in is fine here, there is just no current user to mark
+    current, _ := currentUser(s)
+
+    list := newListing("name", "current")
     for _, user := range users {
-        fmt.Printf("* %v", user)
-        if user == s.cfgState.CurrentUserName {
-            fmt.Printf(" (current)")
-        }
-        fmt.Printf("\n")
+        list.add(user, user == current.Name)
     }
 
-    return nil
+    return printListing(s, list, func() {
+        for _, user := range users {
+            fmt.Printf("* %v", user)
+            if user == current.Name {
+                fmt.Printf(" (current)")
+            }
+            fmt.Printf("\n")
+        }
+    })
 }
 
 func handlerAgg(s *state, cmd command) error {
-    if len(cmd.args) < 1 {
-        fmt.Println("usage: agg <time_between_requests>")
-        return EmptyArgList
+    timeBetweenRequests, err := parseInterval(cmd.args[0])
+    if err != nil {
+        return err
     }
+    return aggregate(context.Background(), s, timeBetweenRequests, false)
+}
 
-    timeBetweenRequests, err := time.ParseDuration(cmd.args[0])
+func parseInterval(arg string) (time.Duration, error) {
+    interval, err := time.ParseDuration(arg)
     if err != nil {
-        return fmt.Errorf("%v | Reason: %v", ErrorParsingTime, err)
+        return 0, fmt.Errorf("%w | Reason: %w", ErrorParsingTime, err)
     }
+    if interval <= 0 {
+        return 0, fmt.Errorf("%w | Reason: time between requests must be more than 0", ErrorParsingTime)
+    }
+    return interval, nil
+}
 
-    fmt.Println("Collecting feeds every", cmd.args[0])
-
-    ticker := time.NewTicker(timeBetweenRequests)
-    for ; ; <-ticker.C {
-        feed, err := s.dbState.GetNextFeedToFetch(context.Background())
+// aggregate scrapes a feed straight away and then every interval, until ctx is done.  A failed
+// scrape stops it, unless keepGoing is set, then the failure is only logged and the next feed
+// is tried on the next tick.  Cancelling ctx also cancels the fetch or archive in progress.
+func aggregate(ctx context.Context, s *state, interval time.Duration, keepGoing bool) error {
+    slog.Info("collecting feeds", "interval", interval)
+
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+    for {
+        err := scrapeFeeds(ctx, s)
+        if ctx.Err() != nil {
+            return nil
+        }
+        if err != nil && !keepGoing {
+            return err
+        }
         if err != nil {
-            log.Printf("%v | Reason: %v\n", ErrorGettingNextFeed, err)
-            continue
+            slog.Error("scrape failed", "error", err)
         }
 
-        _, err = s.dbState.MarkFeedFetched(context.Background(), feed.ID)
-        if err != nil {
-            return fmt.Errorf("%v | Reason: %v", ErrorMarkingFeedAsFetched, err)
+        if _, err := archivePending(ctx, s, archivePerRound); err != nil && ctx.Err() == nil {
+            slog.Error("archiving failed", "error", err)
+        }
+        select {
+        case <-ctx.Done():
+            return nil
+        case <-ticker.C:
         }
+    }
+}
+
+// scrapeFeeds fetches the feed that has waited longest and saves its posts, skipping ones already saved
+func scrapeFeeds(ctx context.Context, s *state) error {
+    feed, err := s.dbState.GetNextFeedToFetch(ctx)
+    if err != nil {
+        slog.Warn("no feed to fetch", "error", fmt.Errorf("%w | Reason: %w", ErrorGettingNextFeed, err))
+        return nil
+    }
+
+    _, err = s.dbState.MarkFeedFetched(ctx, feed.ID)
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorMarkingFeedAsFetched, err)
+    }
 
-        rss, err := fetchFeed(context.Background(), feed.Url)
+    start := time.Now()
+    rss, err := fetchFeed(ctx, feed.Url)
+    if err != nil {
+        slog.Error("feed fetch failed", "feed_id", feed.ID, "url", feed.Url, "duration", time.Since(start), "error", err)
+        return fmt.Errorf("%w | Reason: %w", ErrorFetchingFeed, err)
+    }
+
+    newPosts := 0
+    fullText := []database.Post{}
+    for _, item := range rss.Channel.Item {
+        publishedAt := sql.NullTime{}
+        if t, err := time.Parse(time.RFC1123Z, item.PubDate); err == nil {
+            publishedAt = sql.NullTime{ Time:  t, Valid: true, }
+        }
+        
+        // What is stored for display is sanitised, the publisher's html is kept alongside it
+        base := postBase(item.Link, feed.Url)
+        post, err := s.dbState.CreatePost(ctx, database.CreatePostParams{ ID:    uuid.New(), CreatedAt:   time.Now(), UpdatedAt:   time.Now(), FeedID: feed.ID,
+                                                                      Title: item.Title, Url:         item.Link,  PublishedAt: publishedAt,
+                                                                      Description:    sql.NullString{ String: sanitizeHTML(item.Description, base), Valid: true, },
+                                                                      DescriptionRaw: sql.NullString{ String: item.Description,                     Valid: true, },
+                                                                      Content:        nullString(sanitizeHTML(item.Content, base)),
+                                                                      ContentRaw:     nullString(item.Content), })
         if err != nil {
-            return fmt.Errorf("%v | Reason: %v", ErrorFetchingFeed, err)
+            if isDuplicate(err) {
+                continue
+            }
+            slog.Warn("could not save post", "feed_id", feed.ID, "url", item.Link, "error", err)
+            continue
         }
+        newPosts++
 
-        for _, item := range rss.Channel.Item {
-            publishedAt := sql.NullTime{}
-            if t, err := time.Parse(time.RFC1123Z, item.PubDate); err == nil {
-                publishedAt = sql.NullTime{ Time:  t, Valid: true, }
-            }
-            
-            _, err = s.dbState.CreatePost(context.Background(), database.CreatePostParams{ ID:    uuid.New(), CreatedAt:   time.Now(), UpdatedAt:   time.Now(), FeedID: feed.ID,
-                                                                                           Title: item.Title, Url:         item.Link,  PublishedAt: publishedAt,
-                                                                                           Description: sql.NullString{ String: item.Description, Valid:  true, }, })
+        if feed.FullText {
+            fullText = append(fullText, post)
+        }
+    }
+    saveArticles(ctx, s, feed, fullText)
+    slog.Info("feed collected", "feed_id", feed.ID, "feed_name", feed.Name, "url", feed.Url, "duration", time.Since(start),
+              "posts", len(rss.Channel.Item), "new_posts", newPosts)
+    return nil
+}
+
+// saveArticles fetches new posts' pages, a few at a time, and saves the articles found in them, for
+// feeds with full text on.  Only the first articlesPerRound are fetched so a feed's first scrape
+// does not hold up agg, the rest keep their descriptions.  Failures are only logged.
+func saveArticles(ctx context.Context, s *state, feed database.Feed, posts []database.Post) {
+    if len(posts) > articlesPerRound {
+        slog.Info("full text skipped", "feed_id", feed.ID, "posts", len(posts) - articlesPerRound)
+        posts = posts[:articlesPerRound]
+    }
+
+    // Pages are fetched side by side, the articles are saved one after another afterwards
+    articles := make([]string, len(posts))
+    workers  := make(chan struct{}, articleWorkers)
+    var wg sync.WaitGroup
+    for i, post := range posts {
+        pageURL := postBase(post.Url, feed.Url)
+        if pageURL == nil {
+            continue
+        }
+        wg.Add(1)
+        workers <- struct{}{}
+        go func() {
+            defer wg.Done()
+            defer func() { <-workers }()
+
+            fetchCtx, cancel := context.WithTimeout(ctx, articleTimeout)
+            defer cancel()
+            article, err := fetchArticle(fetchCtx, pageURL.String())
             if err != nil {
-                if strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
-                    continue
-                }
-                log.Printf("Couldn't create post: %v", err)
-                continue
+                slog.Warn("could not fetch full text", "feed_id", feed.ID, "url", pageURL.String(), "error", err)
+                return
             }
+            articles[i] = article
+        }()
+    }
+    wg.Wait()
+
+    for i, post := range posts {
+        if articles[i] == "" {
+            continue
+        }
+        err := s.dbState.SetPostArticle(ctx, database.SetPostArticleParams{ ID: post.ID, Article: nullString(articles[i]) })
+        if err != nil {
+            slog.Warn("could not save full text", "feed_id", feed.ID, "url", post.Url, "error", err)
         }
-        log.Printf("Feed %s collected, %v posts found", feed.Name, len(rss.Channel.Item))
     }
-    return nil
 }
 
-func handlerAddFeed(s *state, cmd command) error {
-    if len(cmd.args) < 2 {
-        fmt.Println("usage: addfeed <name> <url>")
-        return NotEnoughArgs
+func handlerAddFeed(s *state, cmd command, user database.User) error {
+    // The url is kept as typed, only a missing scheme is filled in so there is something to fetch
+    feedURL := strings.TrimSpace(cmd.args[1])
+    if !strings.Contains(feedURL, "://") {
+        feedURL = "https://" + feedURL
+    }
+    if _, err := normalizeFeedURL(feedURL); err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorCreatingFeed, err)
     }
 
-    user, err := s.dbState.GetUser(context.Background(), s.cfgState.CurrentUserName)
-    if err != nil {
-        return fmt.Errorf("%v | Reason: %v", ErrorGettingUser, err)
+    // Another spelling of a feed already added, http instead of https say, is the same feed
+    existing, err := feedByURL(context.Background(), s.dbState, feedURL)
+    if err == nil {
+        return fmt.Errorf("%w | Reason: feed %v already has that url as %v, follow it instead", ErrorCreatingFeed, existing.Name, existing.Url)
+    }
+    if !errors.Is(err, sql.ErrNoRows) {
+        return fmt.Errorf("%w | Reason: %w", ErrorGettingFeed, err)
     }
 
     feed, err := s.dbState.CreateFeed(context.Background(), database.CreateFeedParams{ ID:   uuid.New(),  CreatedAt: time.Now(),  UpdatedAt: time.Now(), 
-                                                                                       Name: cmd.args[0], Url:       cmd.args[1], UserID:    user.ID, })
+                                                                                       Name: cmd.args[0], Url:       feedURL,     UserID:    user.ID, })
     if err != nil {
-        return fmt.Errorf("%v | Reason: %v", ErrorCreatingFeed, err)
+        return fmt.Errorf("%w | Reason: %w", ErrorCreatingFeed, err)
     }
 
     _, err = s.dbState.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{ ID:     uuid.New(), CreatedAt: time.Now(), UpdatedAt: time.Now(), 
                                                                                                UserID: user.ID,    FeedID:    feed.ID, })
     if err != nil {
-        return fmt.Errorf("%v | Reason: %v", ErrorCreatingFeedFollows, err)
+        return fmt.Errorf("%w | Reason: %w", ErrorCreatingFeedFollows, err)
     }
 
     fmt.Println("Feed created successfully:")
@@ -210,14 +662,14 @@ func handlerAddFeed(s *state, cmd command) error {
 func handlerFeeds(s *state, cmd command) error {
     feeds, err := s.dbState.GetFeeds(context.Background())
     if err != nil {
-        return fmt.Errorf("%v | Reason: %v", ErrorGettingFeeds, err)
+        return fmt.Errorf("%w | Reason: %w", ErrorGettingFeeds, err)
     }
 
     for _, feed := range feeds {
         fmt.Printf("Name: %v | URL: %v | Username: ", feed.Name, feed.Url)
         name, err := s.dbState.GetUserName(context.Background(), feed.UserID)
         if err != nil {
-            return fmt.Errorf("%v | Reason: %v", ErrorGettingUsername, err)
+            return fmt.Errorf("%w | Reason: %w", ErrorGettingUsername, err)
         }
         fmt.Printf("%v\n", name)
     }
@@ -228,42 +680,36 @@ func handlerFeeds(s *state, cmd command) error {
 func handlerFeedsWithName(s *state, cmd command) error {
     feeds, err := s.dbState.GetFeedsWithName(context.Background())
     if err != nil {
-        return fmt.Errorf("%v | Reason: %v", ErrorGettingFeeds, err)
+        return fmt.Errorf("%w | Reason: %w", ErrorGettingFeeds, err)
     }
 
+    list := newListing("id", "name", "url", "user")
     for _, feed := range feeds {
-        fmt.Printf("Name: %v | URL: %v | Username: %v\n", feed.Name, feed.Url, feed.Username)
+        list.add(feed.ID, feed.Name, feed.Url, feed.Username)
     }
 
-    return nil
+    return printListing(s, list, func() {
+        for _, feed := range feeds {
+            fmt.Printf("ID: %v | Name: %v | URL: %v | Username: %v\n", feed.ID.String()[:8], feed.Name, feed.Url, feed.Username)
+        }
+    })
 }
 
-func handlerFollow(s *state, cmd command) error {
-    if len(cmd.args) < 1 {
-        fmt.Println("usage: follow <url>")
-        return NotEnoughArgs
-    }
-
-    user, err := s.dbState.GetUser(context.Background(), s.cfgState.CurrentUserName)
-    if err != nil {
-        return fmt.Errorf("%v | Reason: %v", ErrorGettingUser, err)
-    }
-
-    
-    feed, err := s.dbState.GetFeedUrl(context.Background(), cmd.args[0])
+func handlerFollow(s *state, cmd command, user database.User) error {
+    feed, err := resolveFeed(s, cmd.args[0])
     if err != nil {
-        return fmt.Errorf("%v | Reason: %v", ErrorGettingFeed, err)
+        return err
     }
 
     feedFollow, err := s.dbState.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{ ID:     uuid.New(), CreatedAt: time.Now(), UpdatedAt: time.Now(), 
                                                                                                          UserID: user.ID,    FeedID:    feed.ID, })
     if err != nil {
-        return fmt.Errorf("%v | Reason: %v", ErrorCreatingFeedFollows, err)
+        return fmt.Errorf("%w | Reason: %w", ErrorCreatingFeedFollows, err)
     }
 
     fmt.Println("FeedFollow created successfully:")
     fmt.Println("Feed Name:", feedFollow.FeedName)
-    fmt.Println("User:", s.cfgState.CurrentUserName)
+    fmt.Println("User:", user.Name)
 
     fmt.Println()
     fmt.Println("=====================================")
@@ -271,109 +717,479 @@ func handlerFollow(s *state, cmd command) error {
     return nil
 }
 
-func handlerFollowing(s *state, cmd command) error {
-    feedFollows, err := s.dbState.GetFeedFollowsForUser(context.Background(), s.cfgState.CurrentUserName)
+func handlerFollowing(s *state, cmd command, user database.User) error {
+    folderID, err := folderIDArg(s, user, cmd.flagString("folder"))
     if err != nil {
-        return fmt.Errorf("%v | Reason: %v", ErrorGettingUserFeedFollows, err)
+        return err
     }
 
-    fmt.Printf("FeedFollows for user %v received successfully:\n", s.cfgState.CurrentUserName)
+    feedFollows, err := s.dbState.GetFeedFollowsForUser(context.Background(), database.GetFeedFollowsForUserParams{ Name: user.Name, FolderID: folderID })
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorGettingUserFeedFollows, err)
+    }
+
+    folders, err := s.dbState.GetFoldersForUser(context.Background(), user.ID)
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorGettingFolder, err)
+    }
+    paths := folderPaths(folders)
+
+    // Group follows under their folder path, unfiled feeds first
+    grouped := map[string][]database.GetFeedFollowsForUserRow{}
     for _, feedFollow := range feedFollows {
-        fmt.Println("Name:", feedFollow.FeedName)
+        path := ""
+        if feedFollow.FolderID.Valid {
+            path = paths[feedFollow.FolderID.UUID]
+        }
+        grouped[path] = append(grouped[path], feedFollow)
+    }
+    groups := make([]string, 0, len(grouped))
+    for path := range grouped {
+        groups = append(groups, path)
+    }
+    sort.Strings(groups)
+
+    list := newListing("feed_id", "name", "url", "folder", "title", "muted", "priority", "notify")
+    for _, path := range groups {
+        for _, feedFollow := range grouped[path] {
+            list.add(feedFollow.FeedID, feedFollow.FeedName, feedFollow.FeedUrl, path, nullStringValue(feedFollow.Title), feedFollow.Muted, feedFollow.Priority, feedFollow.Notify)
+        }
+    }
+
+    return printListing(s, list, func() {
+        fmt.Printf("FeedFollows for user %v received successfully:\n", user.Name)
+        for _, path := range groups {
+            indent := ""
+            if path != "" {
+                fmt.Printf("%v/\n", path)
+                indent = "    "
+            }
+            for _, feedFollow := range grouped[path] {
+                fmt.Println(indent + "Name:", feedFollow.FeedName + followSettingsNote(feedFollow))
+            }
+        }
+
+        fmt.Println()
+        fmt.Println("=====================================")
+    })
+}
+
+func handlerUnfollow(s *state, cmd command, user database.User) error {
+    feed, err := resolveFeed(s, cmd.args[0])
+    if err != nil {
+        return err
+    }
+
+    n, err := s.dbState.DeleteFeedFollowsForUserUrl(context.Background(), database.DeleteFeedFollowsForUserUrlParams{ UserID: user.ID, FeedID: feed.ID })
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorGettingUserFeedFollows, err)
+    }
+    if n == 0 {
+        return fmt.Errorf("%w | Reason: %v is not following %v", ErrorNotFollowing, user.Name, feed.Name)
+    }
+
+    fmt.Printf("Unfollowed %v\n", feed.Name)
+    return nil
+}
+
+func handlerFollowSettings(s *state, cmd command, user database.User) error {
+    feed, err := resolveFeed(s, cmd.args[0])
+    if err != nil {
+        return err
+    }
+
+    // Only change the settings that were passed on the command line
+    params := database.UpdateFeedFollowSettingsParams{ UserID: user.ID, FeedID: feed.ID }
+    cmd.flags.Visit(func(f *flag.Flag) {
+        switch f.Name {
+        case "title":
+            params.Title    = sql.NullString{ String: cmd.flagString("title"), Valid: true }
+        case "muted":
+            params.Muted    = sql.NullBool{ Bool: cmd.flagBool("muted"), Valid: true }
+        case "priority":
+            params.Priority = sql.NullInt32{ Int32: int32(cmd.flagInt("priority")), Valid: true }
+        case "notify":
+            params.Notify   = sql.NullBool{ Bool: cmd.flagBool("notify"), Valid: true }
+        }
+    })
+
+    feedFollow, err := s.dbState.UpdateFeedFollowSettings(context.Background(), params)
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorUpdatingFeedFollow, err)
     }
 
+    fmt.Println("FeedFollow updated successfully:")
+    fmt.Println("Feed Name:    ", feed.Name)
+    fmt.Println("Title:        ", feedFollow.Title.String)
+    fmt.Println("Muted:        ", feedFollow.Muted)
+    fmt.Println("Priority:     ", feedFollow.Priority)
+    fmt.Println("Notify:       ", feedFollow.Notify)
+
     fmt.Println()
     fmt.Println("=====================================")
-    
+
     return nil
 }
 
-func handlerUnfollow(s *state, cmd command) error {
-    if len(cmd.args) < 1 {
-        fmt.Println("usage: unfollow <url>")
-        return NotEnoughArgs
+func handlerBrowse(s *state, cmd command, user database.User) error {
+    limit  := cmd.flagInt("limit")
+    offset := cmd.flagInt("offset")
+    sortBy := cmd.flagString("sort")
+
+    // Keep supporting the positional limit, ex: gator browse 10
+    if len(cmd.args) > 0 {
+        l, err := strconv.Atoi(cmd.args[0])
+        if err != nil {
+            return usageError{ err: ErrorParsingInt, reason: err.Error() }
+        }
+        limit = l
+    }
+    if limit < 1 || offset < 0 {
+        return usageError{ err: ErrorParsingFlags, reason: fmt.Sprintf("limit must be at least 1 and offset not negative, got %v and %v", limit, offset) }
+    }
+
+    if sortBy != "published" && sortBy != "fetched" {
+        return usageError{ err: ErrorParsingFlags, reason: fmt.Sprintf("sort must be published or fetched, got %q", sortBy) }
     }
 
-    user, err := s.dbState.GetUser(context.Background(), s.cfgState.CurrentUserName)
+    sinceTime, err := parseTimeArg(cmd.flagString("since"))
     if err != nil {
-        return fmt.Errorf("%v | Reason: %v", ErrorGettingUser, err)
+        return fmt.Errorf("%w | Reason: %w", ErrorParsingTime, err)
+    }
+    untilTime, err := parseTimeArg(cmd.flagString("until"))
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorParsingTime, err)
+    }
+
+    folderID, err := folderIDArg(s, user, cmd.flagString("folder"))
+    if err != nil {
+        return err
     }
 
-    feed, err := s.dbState.GetFeedUrl(context.Background(), cmd.args[0])
+    posts, err := s.dbState.GetPostsForUserFiltered(context.Background(), database.GetPostsForUserFilteredParams{ UserID:       user.ID,                               Feed:       nullString(cmd.flagString("feed")),
+                                                                                                                  Since:        sinceTime,                             Until:      untilTime,
+                                                                                                                  Match:        nullString(cmd.flagString("match")),   SortBy:     sortBy,
+                                                                                                                  UnreadOnly:   cmd.flagBool("unread"),                FolderID:   folderID,
+                                                                                                                  IncludeMuted: cmd.flagBool("include-muted"),         PageSize:   int32(limit),
+                                                                                                                  PageOffset:   int32(offset), })
     if err != nil {
-        return fmt.Errorf("%v | Reason: %v", ErrorGettingFeed, err)
+        return fmt.Errorf("%w | Reason: %w", ErrorGettingPosts, err)
     }
 
-    err = s.dbState.DeleteFeedFollowsForUserUrl(context.Background(), database.DeleteFeedFollowsForUserUrlParams{ UserID: user.ID, FeedID: feed.ID })
+    list := newListing("id", "title", "feed_name", "url", "created_at", "updated_at", "published_at", "description")
+    for _, post := range posts {
+        list.add(post.ID, post.Title, post.FeedName, post.Url, post.CreatedAt, post.UpdatedAt, nullTimeValue(post.PublishedAt), nullStringValue(post.Description))
+    }
+
+    return printListing(s, list, func() {
+        width := terminalWidth()
+        for _, post := range posts {
+            fmt.Println("Title:        ", post.Title)
+            fmt.Println("Feed Name:    ", post.FeedName)
+            fmt.Println("Url:          ", post.Url)
+            fmt.Println("Created at:   ", post.CreatedAt)
+            fmt.Println("Updated at:   ", post.UpdatedAt)
+            if post.PublishedAt.Valid {
+                fmt.Println("Published at: ", post.PublishedAt.Time)
+            }
+            if post.Description.Valid {
+                fmt.Println("Description:  ")
+                fmt.Println(renderHTML(post.Description.String, width))
+            }
+            fmt.Println()
+        }
+
+        if len(posts) == limit {
+            fmt.Printf("More posts available, use --offset %v to see the next page\n", offset + limit)
+        }
+    })
+}
+
+// handlerSanitize cleans every stored post again from the html its publisher sent, for after the
+// allowlist changes
+func handlerSanitize(s *state, cmd command, user database.User) error {
+    updated, err := sanitizePosts(context.Background(), s.dbState, false)
     if err != nil {
-        return fmt.Errorf("%v | Reason: %v", ErrorGettingUserFeedFollows, err)
+        return err
     }
 
+    fmt.Printf("Sanitised %v posts\n", updated)
     return nil
 }
 
-func handlerBrowse(s *state, cmd command) error {
-    limit := 2
-    var err error
-    if len(cmd.args) > 0 {
-        limit, err = strconv.Atoi(cmd.args[0])
+func handlerMarkRead(s *state, cmd command, user database.User) error {
+    for _, url := range cmd.args {
+        n, err := s.dbState.MarkPostRead(context.Background(), database.MarkPostReadParams{ UserID: user.ID, Url: url })
         if err != nil {
-            return fmt.Errorf("%v | Reason: %v", ErrorParsingInt, err)
+            return fmt.Errorf("%w | Reason: %w", ErrorMarkingPostRead, err)
+        }
+        if n == 0 {
+            fmt.Printf("No unread post found with url %v\n", url)
+            continue
         }
+        fmt.Printf("Marked %v as read\n", url)
     }
 
-    user, err := s.dbState.GetUser(context.Background(), s.cfgState.CurrentUserName)
+    return nil
+}
+
+func handlerMarkAllRead(s *state, cmd command, user database.User) error {
+    folderID, err := folderIDArg(s, user, cmd.flagString("folder"))
     if err != nil {
-        return fmt.Errorf("%v | Reason: %v", ErrorGettingUser, err)
+        return err
     }
 
-    posts, err := s.dbState.GetPostsForUser(context.Background(), database.GetPostsForUserParams{ UserID: user.ID, Limit: int32(limit) })
+    n, err := s.dbState.MarkAllPostsRead(context.Background(), database.MarkAllPostsReadParams{ UserID: user.ID, Feed: nullString(cmd.flagString("feed")), FolderID: folderID })
     if err != nil {
-        return fmt.Errorf("%v | Reason: %v", ErrorGettingPosts, err)
+        return fmt.Errorf("%w | Reason: %w", ErrorMarkingPostRead, err)
     }
 
-    for _, post := range posts {
-        fmt.Println("Title:        ", post.Title)
-        fmt.Println("Feed Name:    ", post.FeedName)
-        fmt.Println("Url:          ", post.Url)
-        fmt.Println("Created at:   ", post.CreatedAt)
-        fmt.Println("Updated at:   ", post.UpdatedAt)
-        fmt.Println("Published at: ", post.PublishedAt)
-        fmt.Println("Description:  ")
-        fmt.Println(post.Description)
+    fmt.Printf("Marked %v posts as read\n", n)
+    return nil
+}
+
+func handlerSearch(s *state, cmd command, user database.User) error {
+    sinceTime, err := parseTimeArg(cmd.flagString("since"))
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorParsingTime, err)
+    }
+
+    // websearch_to_tsquery handles "quoted phrases", or, and -negation for us
+    query := strings.Join(cmd.args, " ")
+    results, err := s.dbState.SearchPostsForUser(context.Background(), database.SearchPostsForUserParams{ Query: query,     UserID:   user.ID,
+                                                                                                        Since: sinceTime, PageSize: int32(cmd.flagInt("limit")), })
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorSearchingPosts, err)
+    }
+
+    fmt.Printf("%v results for %q:\n\n", len(results), query)
+    for _, result := range results {
+        fmt.Printf("[%.3f] %v\n", result.Rank, result.Title)
+        fmt.Println("Feed Name:    ", result.FeedName)
+        fmt.Println("Url:          ", result.Url)
+        if result.PublishedAt.Valid {
+            fmt.Println("Published at: ", result.PublishedAt.Time)
+        }
+        if snippet := snippetText(result.Snippet); snippet != "" {
+            fmt.Println(snippet)
+        }
         fmt.Println()
     }
     return nil
 }
 
 // Methods
-func (c *commands) register(name string, f func(*state, command) error) {
-    c.commandList[name] = f 
+func (c *commands) register(info *commandInfo) {
+    info.path = info.name
+    setSubcommandPaths(info)
+    c.commandList[info.name] = info
+}
+
+// Subcommands get their full name, and what they need unless they say otherwise
+func setSubcommandPaths(info *commandInfo) {
+    for _, sub := range info.subcommands {
+        sub.path = info.path + " " + sub.name
+        if sub.needs == needsSchema {
+            sub.needs = info.needs
+        }
+        setSubcommandPaths(sub)
+    }
+}
+
+// parse finds the command, and subcommand, that args name, parses its flags and checks
+// its positional arguments.  The command's info is set even on error, for printing usage.
+func (c *commands) parse(args []string) (command, error) {
+    if len(args) < 1 {
+        return command{}, usageError{ err: EmptyArgList, reason: "no command given" }
+    }
+
+    info, ok := c.commandList[args[0]]
+    if !ok {
+        return command{}, usageError{ err: NoCommandExists, reason: fmt.Sprintf("unknown command %q", args[0]) }
+    }
+    args = args[1:]
+
+    for len(info.subcommands) > 0 {
+        if len(args) < 1 {
+            return command{ info: info }, usageError{ err: EmptyArgList, reason: "missing subcommand" }
+        }
+        sub := info.subcommand(args[0])
+        if sub == nil {
+            return command{ info: info }, usageError{ err: NoCommandExists, reason: fmt.Sprintf("unknown subcommand %q", args[0]) }
+        }
+        info, args = sub, args[1:]
+    }
+
+    fs := info.flagSet()
+    if info.rawArgs {
+        return command{ name: info.path, args: args, flags: fs, info: info }, nil
+    }
+    if err := fs.Parse(args); err != nil {
+        if errors.Is(err, flag.ErrHelp) {
+            return command{ info: info }, err
+        }
+        return command{ info: info }, usageError{ err: ErrorParsingFlags, reason: err.Error() }
+    }
+
+    n := fs.NArg()
+    switch {
+    case n < info.minArgs && n == 0:
+        return command{ info: info }, usageError{ err: EmptyArgList, reason: "missing arguments" }
+    case n < info.minArgs:
+        return command{ info: info }, usageError{ err: NotEnoughArgs, reason: "missing arguments" }
+    case info.maxArgs >= 0 && n > info.maxArgs:
+        return command{ info: info }, usageError{ err: TooManyArgs, reason: fmt.Sprintf("unexpected %q", fs.Arg(info.maxArgs)) }
+    }
+
+    return command{ name: info.path, args: fs.Args(), flags: fs, info: info }, nil
 }
 
 func (c *commands) run(s *state, cmd command) error {
+    err := cmd.info.handler(s, cmd)
+    var uErr usageError
+    if errors.As(err, &uErr) {
+        return err
+    }
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorRunningHandle, err)
+    }
+    return nil
+}
+
+// help lists every command, or describes one command in full
+func (c *commands) help(args []string) error {
+    if len(args) == 0 {
+        names := c.names()
 
-    if cmd.name == "help" {
-        fmt.Println("Command List: ")
-        for name, _ := range c.commandList {
-            fmt.Println("*", name)
+        fmt.Println("usage: gator [global flags] <command> [flags] [arguments]")
+        fmt.Println()
+        fmt.Println("Commands:")
+        for _, name := range names {
+            fmt.Printf("  %-16v %v\n", name, c.commandList[name].description)
+        }
+        if c.globalFlags != nil {
+            fmt.Println()
+            fmt.Println("Global flags:")
+            c.globalFlags.SetOutput(os.Stdout)
+            c.globalFlags.PrintDefaults()
         }
+        fmt.Println()
+        fmt.Println("Run gator help <command> for details on a command.")
         return nil
     }
 
-    comFunc, ok := c.commandList[cmd.name]
+    info, ok := c.commandList[args[0]]
     if !ok {
-        return NoCommandExists
+        return usageError{ err: NoCommandExists, reason: fmt.Sprintf("unknown command %q", args[0]) }
+    }
+    for _, name := range args[1:] {
+        sub := info.subcommand(name)
+        if sub == nil {
+            return usageError{ err: NoCommandExists, reason: fmt.Sprintf("unknown subcommand %q", name) }
+        }
+        info = sub
     }
 
-    err := comFunc(s, cmd)
+    info.printHelp()
+    return nil
+}
 
+func (info *commandInfo) subcommand(name string) *commandInfo {
+    for _, sub := range info.subcommands {
+        if sub.name == name {
+            return sub
+        }
+    }
+    return nil
+}
 
-    if err != nil {
-        return fmt.Errorf("%v | Reason: %v", ErrorRunningHandle, err)
+// flagSet makes a fresh flag set for each run, so values never carry over between runs
+func (info *commandInfo) flagSet() *flag.FlagSet {
+    fs := flag.NewFlagSet(info.path, flag.ContinueOnError)
+    fs.SetOutput(io.Discard)
+    if info.flags != nil {
+        info.flags(fs)
     }
+    return fs
+}
 
-    return nil
+func (info *commandInfo) usage() string {
+    line := "gator " + info.path
+    if len(info.subcommands) > 0 {
+        names := []string{}
+        for _, sub := range info.subcommands {
+            names = append(names, sub.name)
+        }
+        line += " <" + strings.Join(names, "|") + ">"
+    }
+    if info.flags != nil {
+        line += " [flags]"
+    }
+    if info.args != "" {
+        line += " " + info.args
+    }
+    return line
+}
+
+func (info *commandInfo) printHelp() {
+    fmt.Println("usage:", info.usage())
+    fmt.Println()
+    fmt.Println(info.description)
+
+    if info.flags != nil {
+        fmt.Println()
+        fmt.Println("Flags:")
+        fs := info.flagSet()
+        fs.SetOutput(os.Stdout)
+        fs.PrintDefaults()
+    }
+
+    if len(info.subcommands) > 0 {
+        fmt.Println()
+        fmt.Println("Subcommands:")
+        for _, sub := range info.subcommands {
+            fmt.Printf("  %-16v %v\n", sub.name, sub.description)
+        }
+    }
+}
+
+// usageError is a command typed wrong, as opposed to one that failed while running
+type usageError struct {
+    err    error
+    reason string
+}
+
+func (e usageError) Error() string {
+    return fmt.Sprintf("%v | Reason: %v", e.err, e.reason)
+}
+
+func (e usageError) Unwrap() error {
+    return e.err
+}
+
+// Typed access to the flags a command declared.  Asking for a flag that was not declared is a bug.
+func (cmd command) flagString(name string) string {
+    return cmd.flagValue(name).(string)
+}
+
+func (cmd command) flagBool(name string) bool {
+    return cmd.flagValue(name).(bool)
+}
+
+func (cmd command) flagInt(name string) int {
+    return cmd.flagValue(name).(int)
+}
+
+func (cmd command) flagDuration(name string) time.Duration {
+    return cmd.flagValue(name).(time.Duration)
+}
+
+func (cmd command) flagValue(name string) any {
+    f := cmd.flags.Lookup(name)
+    if f == nil {
+        panic(fmt.Sprintf("command %v has no flag %v", cmd.name, name))
+    }
+    return f.Value.(flag.Getter).Get()
 }
 
 // Helpers
@@ -386,12 +1202,61 @@ func printFeed(feed database.Feed) {
     fmt.Printf("* UserID:        %s\n", feed.UserID)
 }
 
+// followSettingsNote summarises non-default follow settings, ex: " (muted, priority 3)"
+func followSettingsNote(feedFollow database.GetFeedFollowsForUserRow) string {
+    notes := []string{}
+    if feedFollow.Muted {
+        notes = append(notes, "muted")
+    }
+    if feedFollow.Priority != 0 {
+        notes = append(notes, fmt.Sprintf("priority %v", feedFollow.Priority))
+    }
+    if !feedFollow.Notify {
+        notes = append(notes, "notifications off")
+    }
+    if len(notes) == 0 {
+        return ""
+    }
+    return " (" + strings.Join(notes, ", ") + ")"
+}
+
+// parseTimeArg accepts a date, an RFC3339 timestamp, or a duration meaning "that long ago".
+// An empty string yields an invalid NullTime so the filter is skipped.
+func parseTimeArg(arg string) (sql.NullTime, error) {
+    if arg == "" {
+        return sql.NullTime{}, nil
+    }
+    if d, err := time.ParseDuration(arg); err == nil {
+        return sql.NullTime{ Time: time.Now().Add(-d), Valid: true }, nil
+    }
+    for _, layout := range []string{ time.RFC3339, time.DateOnly } {
+        if t, err := time.Parse(layout, arg); err == nil {
+            return sql.NullTime{ Time: t, Valid: true }, nil
+        }
+    }
+    return sql.NullTime{}, fmt.Errorf("unrecognised time %q", arg)
+}
+
+func nullString(str string) sql.NullString {
+    return sql.NullString{ String: str, Valid: str != "" }
+}
+
 // Middleware (eugh)
-//func middlewareLoggedIn(handler func(s *state, cmd command, user database.User) error) func(*state, command) error {
-//    
-//    user, err := s.dbState.GetUser(context.Background(), s.cfgState.CurrentUserName)
-//    if err != nil {
-//        return fmt.Errorf("%v | Reason: %v", ErrorGettingUser, err)
-//   }
-//  
-//}
+func middlewareLoggedIn(handler func(s *state, cmd command, user database.User) error) func(*state, command) error {
+    return func(s *state, cmd command) error {
+        user, err := currentUser(s)
+        if err != nil {
+            return err
+        }
+        return handler(s, cmd, user)
+    }
+}
+
+func middlewareAdmin(handler func(s *state, cmd command, user database.User) error) func(*state, command) error {
+    return middlewareLoggedIn(func(s *state, cmd command, user database.User) error {
+        if user.Role != roleAdmin {
+            return fmt.Errorf("%w | Reason: %v is not an admin", ErrorNotAdmin, user.Name)
+        }
+        return handler(s, cmd, user)
+    })
+}
@@ -0,0 +1,804 @@
+package main
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "flag"
+    "internal/database"
+    "strings"
+    "testing"
+    "time"
+    "github.com/google/uuid"
+)
+
+func TestHandlerRegister(t *testing.T) {
+    eachStore(t, func(t *testing.T, s *state, q database.Querier) {
+        withInput(t, "password1", "password1")
+        run(t, s, "register", "alice")
+        if s.cfgState.SessionToken == "" {
+            t.Fatal("register did not log the new user in")
+        }
+        alice, err := currentUser(s)
+        if err != nil || alice.Name != "alice" {
+            t.Fatalf("current user = %v, %v; want alice", alice.Name, err)
+        }
+        if alice.Role != roleAdmin {
+            t.Errorf("first user role = %v, want %v", alice.Role, roleAdmin)
+        }
+
+        withInput(t, "password2", "password2")
+        run(t, s, "register", "bob")
+        bob, _ := q.GetUser(context.Background(), "bob")
+        if bob.Role != roleUser {
+            t.Errorf("second user role = %v, want %v", bob.Role, roleUser)
+        }
+        if checkPassword(bob.PasswordHash.String, "password2") != nil {
+            t.Error("password was not stored as a hash of what was typed")
+        }
+
+        withInput(t, "password3", "password3")
+        assertErr(t, runErr(s, "register", "bob"), ErrorRegisterUser)
+
+        withInput(t, "short", "short")
+        assertErr(t, runErr(s, "register", "carol"), ErrorSettingPassword)
+
+        withInput(t, "password4", "password5")
+        assertErr(t, runErr(s, "register", "carol"), ErrorSettingPassword)
+
+        assertErr(t, runErr(s, "register"), EmptyArgList)
+    })
+}
+
+func TestHandlerLogin(t *testing.T) {
+    eachStore(t, func(t *testing.T, s *state, q database.Querier) {
+        addUser(t, q, "alice", roleUser)
+
+        withInput(t, "password1")
+        out := run(t, s, "login", "alice")
+        assertContains(t, out, "User alice has been set")
+        if user, err := currentUser(s); err != nil || user.Name != "alice" {
+            t.Fatalf("current user = %v, %v; want alice", user.Name, err)
+        }
+
+        withInput(t, "wrong-password")
+        assertErr(t, runErr(s, "login", "alice"), ErrorWrongPassword)
+
+        // An unknown name gets the same answer as a wrong password
+        withInput(t, "password1")
+        assertErr(t, runErr(s, "login", "nobody"), ErrorWrongPassword)
+
+        assertErr(t, runErr(s, "login"), EmptyArgList)
+    })
+}
+
+func TestHandlerLoginWithoutPassword(t *testing.T) {
+    eachStore(t, func(t *testing.T, s *state, q database.Querier) {
+        user := addUser(t, q, "old-timer", roleUser)
+        q.SetUserPassword(context.Background(), database.SetUserPasswordParams{ ID: user.ID })
+
+        // Logging in must not let whoever gets there first choose the password
+        withInput(t, "new-password", "new-password")
+        assertErr(t, runErr(s, "login", "old-timer"), ErrorNoPassword)
+        user, _ = q.GetUser(context.Background(), "old-timer")
+        if user.PasswordHash.Valid || s.cfgState.SessionToken != "" {
+            t.Fatal("login set a password or started a session for a user without one")
+        }
+
+        // Until an admin sets one
+        loginAs(t, s, q, "root", roleAdmin)
+        withInput(t, "new-password", "new-password")
+        out := run(t, s, "user", "set-password", "old-timer")
+        assertContains(t, out, "Password set for old-timer")
+
+        withInput(t, "new-password")
+        run(t, s, "login", "old-timer")
+        if current, err := currentUser(s); err != nil || current.Name != "old-timer" {
+            t.Fatalf("current user = %v, %v; want old-timer", current.Name, err)
+        }
+    })
+}
+
+func TestUserSetPassword(t *testing.T) {
+    eachStore(t, func(t *testing.T, s *state, q database.Querier) {
+        ctx := context.Background()
+        addUser(t, q, "bob", roleUser)
+        loginAs(t, s, q, "alice", roleUser)
+        elsewhere := s.cfgState.SessionToken
+        withInput(t, "password1")
+        run(t, s, "login", "alice")
+
+        // Your own takes the current password first
+        withInput(t, "wrong-password", "password9", "password9")
+        assertErr(t, runErr(s, "user", "set-password", "alice"), ErrorWrongPassword)
+        withInput(t, "password1", "password9", "password9")
+        out := run(t, s, "user", "set-password", "alice")
+        assertContains(t, out, "Password set for alice, 1 other sessions logged out")
+        alice, _ := q.GetUser(ctx, "alice")
+        if checkPassword(alice.PasswordHash.String, "password9") != nil {
+            t.Error("own password was not changed")
+        }
+
+        // The other session ends, the one that changed it carries on
+        if _, err := q.GetUserBySession(ctx, hashToken(elsewhere)); err == nil {
+            t.Error("a session from before the password change still works")
+        }
+        if _, err := currentUser(s); err != nil {
+            t.Errorf("the session that changed the password ended: %v", err)
+        }
+        aliceSession := s.cfgState.SessionToken
+
+        withInput(t, "password9", "password9")
+        assertErr(t, runErr(s, "user", "set-password", "bob"), ErrorNotAdmin)
+
+        // An admin setting it ends all of the user's sessions
+        loginAs(t, s, q, "root", roleAdmin)
+        withInput(t, "password8", "password8")
+        run(t, s, "user", "set-password", "alice")
+        if _, err := q.GetUserBySession(ctx, hashToken(aliceSession)); err == nil {
+            t.Error("alice's session survived an admin setting her password")
+        }
+
+        withInput(t, "password9", "password9")
+        assertErr(t, runErr(s, "user", "set-password", "nobody"), ErrorGettingUser)
+        withInput(t, "short", "short")
+        assertErr(t, runErr(s, "user", "set-password", "bob"), ErrorSettingPassword)
+    })
+}
+
+func TestHandlerBootstrap(t *testing.T) {
+    eachStore(t, func(t *testing.T, s *state, q database.Querier) {
+        old := addUser(t, q, "old-timer", roleUser)
+        q.SetUserPassword(context.Background(), database.SetUserPasswordParams{ ID: old.ID })
+        bob := addUser(t, q, "bob", roleAdmin)
+        q.SetUserPassword(context.Background(), database.SetUserPasswordParams{ ID: bob.ID })
+        if err := checkAdmin(s); err == nil {
+            t.Error("doctor found a usable admin on a database without one")
+        }
+
+        withInput(t, "password9", "password9")
+        out := run(t, s, "bootstrap", "old-timer")
+        assertContains(t, out, "User old-timer is now an admin")
+        user, err := currentUser(s)
+        if err != nil || user.Name != "old-timer" || user.Role != roleAdmin || checkPassword(user.PasswordHash.String, "password9") != nil {
+            t.Fatalf("current user = %+v, %v; want old-timer as an admin with the new password", user, err)
+        }
+        if err := checkAdmin(s); err != nil {
+            t.Error(err)
+        }
+
+        // Only the first time
+        withInput(t, "password9", "password9")
+        assertErr(t, runErr(s, "bootstrap", "bob"), ErrorNotAdmin)
+    })
+}
+
+func TestHandlerBootstrapClaimsOnlyUsersWithoutPassword(t *testing.T) {
+    eachStore(t, func(t *testing.T, s *state, q database.Querier) {
+        addUser(t, q, "alice", roleUser)
+
+        // Alice can log in already, so bootstrap must not reset her password
+        withInput(t, "password9", "password9")
+        assertErr(t, runErr(s, "bootstrap", "alice"), ErrorNotAdmin)
+        alice, _ := q.GetUser(context.Background(), "alice")
+        if alice.Role != roleUser || checkPassword(alice.PasswordHash.String, "password1") != nil {
+            t.Fatalf("bootstrap took over alice: %+v", alice)
+        }
+
+        // A new name is made the admin instead
+        withInput(t, "password9", "password9")
+        run(t, s, "bootstrap", "root")
+        root, err := currentUser(s)
+        if err != nil || root.Name != "root" || root.Role != roleAdmin {
+            t.Fatalf("current user = %+v, %v; want root as an admin", root, err)
+        }
+    })
+}
+
+func TestHandlerLogout(t *testing.T) {
+    s, fake := newTestState(t)
+    assertErr(t, runErr(s, "logout"), ErrorNotLoggedIn)
+
+    loginAs(t, s, fake, "alice", roleUser)
+    run(t, s, "logout")
+    if s.cfgState.SessionToken != "" {
+        t.Error("logout left the session token in the config")
+    }
+    if len(fake.sessions) != 0 {
+        t.Error("logout left the session in the database")
+    }
+}
+
+func TestMiddleware(t *testing.T) {
+    eachStore(t, func(t *testing.T, s *state, q database.Querier) {
+        called := false
+        handler := func(s *state, cmd command, user database.User) error {
+            called = true
+            return nil
+        }
+
+        assertErr(t, middlewareLoggedIn(handler)(s, command{}), ErrorNotLoggedIn)
+
+        // A token the database does not know is as good as none
+        s.cfgState.SessionToken = "stale"
+        assertErr(t, middlewareLoggedIn(handler)(s, command{}), ErrorNotLoggedIn)
+
+        loginAs(t, s, q, "alice", roleUser)
+        if err := middlewareLoggedIn(handler)(s, command{}); err != nil {
+            t.Fatal(err)
+        }
+        if !called {
+            t.Error("logged in handler was not called")
+        }
+
+        called = false
+        assertErr(t, middlewareAdmin(handler)(s, command{}), ErrorNotAdmin)
+        if called {
+            t.Error("admin handler was called for a normal user")
+        }
+    })
+}
+
+// resetFixture has an admin, a user with a feed and a post each, and a feed nobody follows
+func resetFixture(t *testing.T) (*state, *fakeStore) {
+    s, fake := newTestState(t)
+    admin := loginAs(t, s, fake, "admin", roleAdmin)
+    bob   := addUser(t, fake, "bob", roleUser)
+
+    adminFeed := addFeed(t, fake, admin, "Admin Feed", "https://admin.example.com/rss")
+    bobFeed   := addFeed(t, fake, bob,   "Bob Feed",   "https://bob.example.com/rss")
+    addPost(t, fake, adminFeed, "Admin post", "https://admin.example.com/1", "", time.Hour)
+    addPost(t, fake, bobFeed,   "Bob post",   "https://bob.example.com/1",   "", time.Hour)
+
+    orphan := addFeed(t, fake, admin, "Orphan", "https://orphan.example.com/rss")
+    fake.DeleteFeedFollowsForUserUrl(context.Background(), database.DeleteFeedFollowsForUserUrlParams{ UserID: admin.ID, FeedID: orphan.ID })
+    return s, fake
+}
+
+func TestHandlerReset(t *testing.T) {
+
+    t.Run("needs confirmation", func(t *testing.T) {
+        s, fake := resetFixture(t)
+        assertErr(t, runErr(s, "reset"), ErrorResetNotConfirmed)
+        if len(fake.users) != 2 {
+            t.Error("unconfirmed reset deleted users")
+        }
+    })
+
+    t.Run("posts", func(t *testing.T) {
+        s, fake := resetFixture(t)
+        out := run(t, s, "reset", "--yes", "--posts")
+        assertContains(t, out, "posts")
+        if len(fake.posts) != 0 || len(fake.feeds) != 3 {
+            t.Errorf("posts reset left %v posts and %v feeds, want 0 and 3", len(fake.posts), len(fake.feeds))
+        }
+    })
+
+    t.Run("user", func(t *testing.T) {
+        s, fake := resetFixture(t)
+        run(t, s, "reset", "--yes", "--user", "bob")
+        if _, err := fake.GetUser(context.Background(), "bob"); err == nil {
+            t.Error("bob was not deleted")
+        }
+        if _, err := fake.GetFeedUrl(context.Background(), "https://bob.example.com/rss"); err == nil {
+            t.Error("bob's feed was not deleted")
+        }
+        if len(fake.posts) != 1 {
+            t.Errorf("%v posts left, want 1", len(fake.posts))
+        }
+    })
+
+    t.Run("user with followed feeds", func(t *testing.T) {
+        s, fake := resetFixture(t)
+        admin, _ := fake.GetUser(context.Background(), "admin")
+        bobFeed, _ := fake.GetFeedUrl(context.Background(), "https://bob.example.com/rss")
+        follow(t, fake, admin, bobFeed)
+
+        out := run(t, s, "reset", "--yes", "--user", "bob")
+        assertContains(t, out, "feeds handed over")
+        feed, err := fake.GetFeedUrl(context.Background(), "https://bob.example.com/rss")
+        if err != nil || feed.UserID != admin.ID {
+            t.Fatalf("bob's feed = %+v, %v; want it handed to admin", feed, err)
+        }
+        if _, following := fake.follow(admin.ID, feed.ID); !following || len(fake.posts) != 2 {
+            t.Errorf("admin lost their follow or posts of bob's feed")
+        }
+    })
+
+    t.Run("feeds without followers", func(t *testing.T) {
+        s, fake := resetFixture(t)
+        run(t, s, "reset", "--yes", "--feeds-without-followers")
+        if len(fake.feeds) != 2 {
+            t.Errorf("%v feeds left, want 2", len(fake.feeds))
+        }
+    })
+
+    t.Run("everything", func(t *testing.T) {
+        s, fake := resetFixture(t)
+        run(t, s, "reset", "--yes")
+        if len(fake.users) + len(fake.feeds) + len(fake.follows) + len(fake.posts) != 0 {
+            t.Error("full reset left rows behind")
+        }
+    })
+
+    t.Run("rolls back on failure", func(t *testing.T) {
+        s, fake := resetFixture(t)
+        assertErr(t, runErr(s, "reset", "--yes", "--posts", "--user", "nobody"), ErrorGettingUser)
+        if len(fake.posts) != 2 {
+            t.Error("failed reset still deleted posts")
+        }
+    })
+
+    t.Run("admins only", func(t *testing.T) {
+        s, fake := resetFixture(t)
+        bob, _ := fake.GetUser(context.Background(), "bob")
+        newSession(s, bob)
+        assertErr(t, runErr(s, "reset", "--yes"), ErrorNotAdmin)
+    })
+}
+
+func TestHandlerSetRole(t *testing.T) {
+    eachStore(t, func(t *testing.T, s *state, q database.Querier) {
+        loginAs(t, s, q, "admin", roleAdmin)
+        addUser(t, q, "bob", roleUser)
+
+        run(t, s, "set-role", "bob", roleAdmin)
+        bob, _ := q.GetUser(context.Background(), "bob")
+        if bob.Role != roleAdmin {
+            t.Errorf("bob's role = %v, want %v", bob.Role, roleAdmin)
+        }
+
+        assertErr(t, runErr(s, "set-role", "bob", "superuser"), ErrorSettingRole)
+        assertErr(t, runErr(s, "set-role", "nobody", roleUser), ErrorGettingUser)
+        assertErr(t, runErr(s, "set-role", "bob"), NotEnoughArgs)
+    })
+}
+
+// Demoting or deleting the last admin who can log in would leave nobody to manage users
+// and bootstrap open to anyone, so it is refused
+func TestLastAdminStays(t *testing.T) {
+    eachStore(t, func(t *testing.T, s *state, q database.Querier) {
+        loginAs(t, s, q, "admin", roleAdmin)
+        addUser(t, q, "bob", roleUser)
+
+        assertErr(t, runErr(s, "set-role", "admin", roleUser), ErrorLastAdmin)
+        assertErr(t, runErr(s, "user", "delete", "--yes", "admin"), ErrorLastAdmin)
+        assertErr(t, runErr(s, "reset", "--yes", "--user", "admin"), ErrorLastAdmin)
+        if admin, _ := q.GetUser(context.Background(), "admin"); admin.Role != roleAdmin {
+            t.Fatal("the last admin was demoted")
+        }
+
+        // With another admin around it is fine
+        run(t, s, "set-role", "bob", roleAdmin)
+        run(t, s, "set-role", "admin", roleUser)
+        if admin, _ := q.GetUser(context.Background(), "admin"); admin.Role != roleUser {
+            t.Error("admin was not demoted with bob still an admin")
+        }
+    })
+}
+
+func TestHandlerUsers(t *testing.T) {
+    eachStore(t, func(t *testing.T, s *state, q database.Querier) {
+        addUser(t, q, "bob", roleUser)
+
+        // Works logged out, with nobody marked
+        out := run(t, s, "users")
+        assertContains(t, out, "* bob\n")
+
+        loginAs(t, s, q, "alice", roleUser)
+        out = run(t, s, "users")
+        assertContains(t, out, "* bob\n", "* alice (current)\n")
+    })
+}
+
+func TestHandlerAgg(t *testing.T) {
+    s, _ := newTestState(t)
+    assertErr(t, runErr(s, "agg"), EmptyArgList)
+    assertErr(t, runErr(s, "agg", "soon"), ErrorParsingTime)
+    assertErr(t, runErr(s, "agg", "0s"), ErrorParsingTime)
+}
+
+func TestHandlerAddFeed(t *testing.T) {
+    s, fake := newTestState(t)
+    alice := loginAs(t, s, fake, "alice", roleUser)
+
+    // Stored as typed, the server may not serve any other spelling
+    run(t, s, "addfeed", "Blog", "https://Example.com/feed/")
+    feed, err := fake.GetFeedUrl(context.Background(), "https://Example.com/feed/")
+    if err != nil {
+        t.Fatalf("feed url was not kept as given: %v", err)
+    }
+    if feed.UserID != alice.ID {
+        t.Error("feed does not belong to its creator")
+    }
+    if _, ok := fake.follow(alice.ID, feed.ID); !ok {
+        t.Error("creator does not follow the new feed")
+    }
+
+    // Other spellings of it are the same feed
+    for _, url := range []string{ "example.com/feed", "http://example.com/feed", "HTTPS://example.com:443/feed" } {
+        assertErr(t, runErr(s, "addfeed", "Again", url), ErrorCreatingFeed)
+    }
+    if len(fake.feeds) != 1 {
+        t.Errorf("%v feeds, want the one", len(fake.feeds))
+    }
+
+    run(t, s, "addfeed", "Other", "example.com/other")
+    if _, err := fake.GetFeedUrl(context.Background(), "https://example.com/other"); err != nil {
+        t.Errorf("a url without a scheme was not given https: %v", err)
+    }
+    assertErr(t, runErr(s, "addfeed", "Blog"), NotEnoughArgs)
+}
+
+func TestHandlerFeedsWithName(t *testing.T) {
+    eachStore(t, func(t *testing.T, s *state, q database.Querier) {
+        alice := addUser(t, q, "alice", roleUser)
+        feed  := addFeed(t, q, alice, "Blog", "https://example.com/feed")
+
+        out := run(t, s, "feeds")
+        assertContains(t, out, "ID: " + shortID(feed), "Name: Blog", "URL: https://example.com/feed", "Username: alice")
+    })
+}
+
+func TestHandlerFollowAndUnfollow(t *testing.T) {
+    s, fake := newTestState(t)
+    alice := addUser(t, fake, "alice", roleUser)
+    feed  := addFeed(t, fake, alice, "Blog", "https://example.com/feed")
+    bob   := loginAs(t, s, fake, "bob", roleUser)
+
+    out := run(t, s, "follow", "Blog")
+    assertContains(t, out, "Feed Name: Blog", "User: bob")
+    if _, ok := fake.follow(bob.ID, feed.ID); !ok {
+        t.Fatal("follow by name did not follow the feed")
+    }
+    assertErr(t, runErr(s, "follow", "Blog"), ErrorCreatingFeedFollows)
+
+    // Unfollowing accepts other spellings of the url
+    run(t, s, "unfollow", "http://EXAMPLE.com/feed/")
+    if _, ok := fake.follow(bob.ID, feed.ID); ok {
+        t.Fatal("unfollow by url did not unfollow the feed")
+    }
+    assertErr(t, runErr(s, "unfollow", "Blog"), ErrorNotFollowing)
+
+    // Following by the short id the feeds command prints
+    run(t, s, "follow", shortID(feed))
+    if _, ok := fake.follow(bob.ID, feed.ID); !ok {
+        t.Error("follow by id prefix did not follow the feed")
+    }
+
+    assertErr(t, runErr(s, "follow", "nothing-like-it"), ErrorGettingFeed)
+    assertErr(t, runErr(s, "follow"), EmptyArgList)
+    assertErr(t, runErr(s, "unfollow"), EmptyArgList)
+}
+
+func TestHandlerFollowing(t *testing.T) {
+    eachStore(t, func(t *testing.T, s *state, q database.Querier) {
+        alice := loginAs(t, s, q, "alice", roleUser)
+        addFeed(t, q, alice, "Unfiled", "https://unfiled.example.com/rss")
+        news := addFeed(t, q, alice, "News", "https://news.example.com/rss")
+        tech := addFeed(t, q, alice, "Tech", "https://tech.example.com/rss")
+
+        withInput(t)
+        run(t, s, "folder", "create", "Reading")
+        run(t, s, "folder", "create", "--parent", "Reading", "Geek")
+        run(t, s, "folder", "move", news.Url, "Reading")
+        run(t, s, "folder", "move", tech.Url, "Geek")
+        q.UpdateFeedFollowSettings(context.Background(), database.UpdateFeedFollowSettingsParams{ UserID: alice.ID, FeedID: tech.ID,
+                                                                                                     Muted:  sql.NullBool{ Bool: true, Valid: true } })
+
+        out := run(t, s, "following")
+        assertContains(t, out, "Name: Unfiled\n", "Reading/\n    Name: News\n", "Reading/Geek/\n    Name: Tech (muted)\n")
+        if strings.Index(out, "Unfiled") > strings.Index(out, "Reading/") {
+            t.Error("unfiled feeds should be listed before folders")
+        }
+
+        out = run(t, s, "following", "--folder", "Geek")
+        if strings.Contains(out, "News") || !strings.Contains(out, "Tech") {
+            t.Errorf("--folder Geek listed the wrong feeds:\n%v", out)
+        }
+
+        out = run(t, s, "following", "--folder", "Reading")
+        assertContains(t, out, "News", "Tech")
+
+        assertErr(t, runErr(s, "following", "--folder", "Nope"), ErrorGettingFolder)
+    })
+}
+
+func TestHandlerFollowSettings(t *testing.T) {
+    s, fake := newTestState(t)
+    alice := loginAs(t, s, fake, "alice", roleUser)
+    feed  := addFeed(t, fake, alice, "Blog", "https://example.com/feed")
+
+    run(t, s, "follow-settings", "--title", "My Blog", "--priority", "3", "Blog")
+    follow, _ := fake.follow(alice.ID, feed.ID)
+    if follow.Title.String != "My Blog" || follow.Priority != 3 || follow.Muted || !follow.Notify {
+        t.Fatalf("settings after first update = %+v", follow)
+    }
+
+    // Flags not given are left alone
+    run(t, s, "follow-settings", "--muted", "Blog")
+    follow, _ = fake.follow(alice.ID, feed.ID)
+    if follow.Title.String != "My Blog" || follow.Priority != 3 || !follow.Muted {
+        t.Fatalf("settings after second update = %+v", follow)
+    }
+
+    // An empty title goes back to the feed's name
+    run(t, s, "follow-settings", "--title", "", "Blog")
+    follow, _ = fake.follow(alice.ID, feed.ID)
+    if follow.Title.Valid {
+        t.Errorf("title = %q, want none", follow.Title.String)
+    }
+
+    assertErr(t, runErr(s, "follow-settings", "--muted"), EmptyArgList)
+    assertErr(t, runErr(s, "follow-settings", "--volume", "11", "Blog"), ErrorParsingFlags)
+}
+
+// browseFixture has alice following two feeds with three posts between them, an hour apart
+func browseFixture(t *testing.T, s *state, q database.Querier) database.User {
+    alice := loginAs(t, s, q, "alice", roleUser)
+    blog  := addFeed(t, q, alice, "Blog", "https://blog.example.com/rss")
+    news  := addFeed(t, q, alice, "News", "https://news.example.com/rss")
+    addPost(t, q, blog, "Go generics",    "https://blog.example.com/1", "<p>Type parameters</p>", 1 * time.Hour)
+    addPost(t, q, news, "Election night", "https://news.example.com/1", "Results are in",         2 * time.Hour)
+    addPost(t, q, blog, "Go modules",     "https://blog.example.com/2", "Versioning",             3 * time.Hour)
+
+    // Someone else's feed never shows up
+    bob  := addUser(t, q, "bob", roleUser)
+    other := addFeed(t, q, bob, "Other", "https://other.example.com/rss")
+    addPost(t, q, other, "Not for alice", "https://other.example.com/1", "", time.Minute)
+    return alice
+}
+
+func TestHandlerBrowse(t *testing.T) {
+    eachStore(t, func(t *testing.T, s *state, q database.Querier) {
+        alice := browseFixture(t, s, q)
+
+        out := run(t, s, "browse")
+        assertContains(t, out, "Title:         Go generics", "Title:         Election night", "--offset 2", "Description:  \nType parameters\n")
+        if strings.Contains(out, "Go modules") || strings.Contains(out, "Not for alice") || strings.Contains(out, "<p>") {
+            t.Errorf("default browse showed the wrong posts:\n%v", out)
+        }
+
+        out = run(t, s, "browse", "--offset", "2")
+        assertContains(t, out, "Go modules")
+        if strings.Contains(out, "More posts available") {
+            t.Error("last page should not offer another one")
+        }
+
+        out = run(t, s, "browse", "--feed", "News", "10")
+        if !strings.Contains(out, "Election night") || strings.Contains(out, "Go generics") {
+            t.Errorf("--feed News showed the wrong posts:\n%v", out)
+        }
+
+        out = run(t, s, "browse", "--match", "MODULES", "10")
+        if !strings.Contains(out, "Go modules") || strings.Contains(out, "Go generics") {
+            t.Errorf("--match showed the wrong posts:\n%v", out)
+        }
+
+        out = run(t, s, "browse", "--since", "150m", "10")
+        if strings.Contains(out, "Go modules") || !strings.Contains(out, "Election night") {
+            t.Errorf("--since showed the wrong posts:\n%v", out)
+        }
+
+        run(t, s, "mark-read", "https://blog.example.com/1")
+        out = run(t, s, "browse", "--unread", "10")
+        if strings.Contains(out, "Go generics") {
+            t.Errorf("--unread showed a read post:\n%v", out)
+        }
+
+        news, _ := q.GetFeedUrl(context.Background(), "https://news.example.com/rss")
+        q.UpdateFeedFollowSettings(context.Background(), database.UpdateFeedFollowSettingsParams{ UserID: alice.ID, FeedID: news.ID,
+                                                                                                     Muted:  sql.NullBool{ Bool: true, Valid: true } })
+        out = run(t, s, "browse", "10")
+        if strings.Contains(out, "Election night") {
+            t.Errorf("browse showed a muted feed:\n%v", out)
+        }
+        out = run(t, s, "browse", "--include-muted", "10")
+        assertContains(t, out, "Election night")
+
+        assertErr(t, runErr(s, "browse", "--since", "last tuesday"), ErrorParsingTime)
+        assertErr(t, runErr(s, "browse", "--folder", "Nope"), ErrorGettingFolder)
+
+        // Bad paging and sorting are usage errors, with the usage exit code
+        for _, args := range [][]string{ { "--sort", "random" }, { "--offset", "-2" }, { "-1" }, { "0" }, { "--limit", "0" } } {
+            err := runErr(s, append([]string{ "browse" }, args...)...)
+            assertErr(t, err, ErrorParsingFlags)
+            if exitCode(err) != exitUsage {
+                t.Errorf("browse %v exits with %v, want %v", args, exitCode(err), exitUsage)
+            }
+        }
+        assertErr(t, runErr(s, "browse", "many"), ErrorParsingInt)
+    })
+}
+
+func TestHandlerMarkRead(t *testing.T) {
+    s, fake := newTestState(t)
+    alice := browseFixture(t, s, fake)
+
+    out := run(t, s, "mark-read", "https://blog.example.com/1", "https://nowhere.example.com/")
+    assertContains(t, out, "Marked https://blog.example.com/1 as read", "No unread post found with url https://nowhere.example.com/")
+
+    out = run(t, s, "mark-read", "https://blog.example.com/1")
+    assertContains(t, out, "No unread post found")
+
+    // Posts in feeds alice does not follow are not hers to mark
+    out = run(t, s, "mark-read", "https://other.example.com/1")
+    assertContains(t, out, "No unread post found")
+
+    if len(fake.reads) != 1 || fake.reads[0].UserID != alice.ID {
+        t.Errorf("reads = %+v, want one for alice", fake.reads)
+    }
+    assertErr(t, runErr(s, "mark-read"), EmptyArgList)
+}
+
+func TestHandlerMarkAllRead(t *testing.T) {
+    s, fake := newTestState(t)
+    browseFixture(t, s, fake)
+
+    out := run(t, s, "mark-all-read", "--feed", "Blog")
+    assertContains(t, out, "Marked 2 posts as read")
+
+    out = run(t, s, "mark-all-read")
+    assertContains(t, out, "Marked 1 posts as read")
+    if len(fake.reads) != 3 {
+        t.Errorf("%v reads, want 3", len(fake.reads))
+    }
+
+    assertErr(t, runErr(s, "mark-all-read", "--folder", "Nope"), ErrorGettingFolder)
+}
+
+func TestHandlerSearch(t *testing.T) {
+    eachStore(t, func(t *testing.T, s *state, q database.Querier) {
+        browseFixture(t, s, q)
+
+        out := run(t, s, "search", "go")
+        assertContains(t, out, `2 results for "go"`, "Go generics", "Go modules")
+        if strings.Contains(out, "Election") {
+            t.Errorf("search matched an unrelated post:\n%v", out)
+        }
+
+        out = run(t, s, "search", "--limit", "1", "go")
+        assertContains(t, out, `1 results for "go"`)
+
+        out = run(t, s, "search", "alice")
+        assertContains(t, out, `0 results`)
+
+        // Snippets are text, and the published time is shown like browse shows it
+        out = run(t, s, "search", "parameters")
+        assertContains(t, out, "Published at:  20", "Type")
+        if strings.Contains(out, "<p>") || strings.Contains(out, "true}") {
+            t.Errorf("search printed raw html or a raw NullTime:\n%v", out)
+        }
+
+        assertErr(t, runErr(s, "search"), EmptyArgList)
+        assertErr(t, runErr(s, "search", "--since", "whenever", "go"), ErrorParsingTime)
+    })
+}
+
+// Feed content and fetched articles are searched too, below titles and descriptions
+func TestHandlerSearchContent(t *testing.T) {
+    eachStore(t, func(t *testing.T, s *state, q database.Querier) {
+        alice := loginAs(t, s, q, "alice", roleUser)
+        blog  := addFeed(t, q, alice, "Blog", "https://blog.example.com/rss")
+        addPost(t, q, blog, "Channels", "https://blog.example.com/2", "<p>Goroutines talk over channels</p>", 2 * time.Hour)
+        _, err := q.CreatePost(context.Background(), database.CreatePostParams{ ID: uuid.New(), CreatedAt: time.Now(), UpdatedAt: time.Now(), Title: "Concurrency", Url: "https://blog.example.com/1",
+                                                                              Description: sql.NullString{ String: "<p>A short summary</p>", Valid: true },
+                                                                              Content:     sql.NullString{ String: "<p>The whole article, about goroutines at length</p>", Valid: true },
+                                                                              PublishedAt: sql.NullTime{ Time: time.Now().Add(-time.Hour), Valid: true }, FeedID: blog.ID })
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        out := run(t, s, "search", "goroutines")
+        assertContains(t, out, `2 results for "goroutines"`, "Concurrency", "about")
+        if strings.Index(out, "Channels") > strings.Index(out, "Concurrency") {
+            t.Errorf("a match in content ranked above one in a description:\n%v", out)
+        }
+    })
+}
+
+func TestCommandsParse(t *testing.T) {
+    coms := newCommands(flag.NewFlagSet("gator", flag.ContinueOnError))
+
+    cmd, err := coms.parse([]string{ "folder", "create", "--parent", "News", "Tech" })
+    if err != nil {
+        t.Fatal(err)
+    }
+    if cmd.name != "folder create" || len(cmd.args) != 1 || cmd.args[0] != "Tech" || cmd.flagString("parent") != "News" {
+        t.Errorf("parsed %v %v with parent %q", cmd.name, cmd.args, cmd.flagString("parent"))
+    }
+
+    // Defaults come from the registry, and a fresh flag set means nothing carries over between runs
+    cmd, err = coms.parse([]string{ "browse" })
+    if err != nil || cmd.flagInt("limit") != 2 || cmd.flagString("sort") != "published" {
+        t.Errorf("browse defaults: limit %v, sort %q, err %v", cmd.flagInt("limit"), cmd.flagString("sort"), err)
+    }
+
+    tests := []struct {
+        args []string
+        want error
+    }{
+        { []string{},                                  EmptyArgList },
+        { []string{ "nope" },                          NoCommandExists },
+        { []string{ "folder" },                        EmptyArgList },
+        { []string{ "folder", "delete" },              NoCommandExists },
+        { []string{ "login" },                         EmptyArgList },
+        { []string{ "addfeed", "Blog" },               NotEnoughArgs },
+        { []string{ "login", "alice", "bob" },         TooManyArgs },
+        { []string{ "browse", "--volume", "11" },      ErrorParsingFlags },
+    }
+    for _, tt := range tests {
+        _, err := coms.parse(tt.args)
+        var uErr usageError
+        if !errors.As(err, &uErr) {
+            t.Errorf("parse(%q) = %v, want a usage error", tt.args, err)
+            continue
+        }
+        assertErr(t, err, tt.want)
+    }
+
+    _, err = coms.parse([]string{ "browse", "-h" })
+    if !errors.Is(err, flag.ErrHelp) {
+        t.Errorf("parse with -h = %v, want flag.ErrHelp", err)
+    }
+}
+
+func TestHelp(t *testing.T) {
+    s, _ := newTestState(t)
+
+    out := run(t, s, "help")
+    assertContains(t, out, "Commands:", "  browse ", "Show posts from the feeds you follow", "Run gator help <command>")
+
+    out = run(t, s, "help", "browse")
+    assertContains(t, out, "usage: gator browse [flags] [limit]", "-include-muted", "number of posts to show (default 2)")
+
+    out = run(t, s, "help", "folder")
+    assertContains(t, out, "usage: gator folder <create|rename|move|list>", "Subcommands:", "  move ")
+
+    out = run(t, s, "help", "folder", "move")
+    assertContains(t, out, "usage: gator folder move <url|name|id> <folder|->")
+
+    assertErr(t, runErr(s, "help", "nope"), NoCommandExists)
+    assertErr(t, runErr(s, "help", "folder", "nope"), NoCommandExists)
+}
+
+func TestRequirements(t *testing.T) {
+    coms := newCommands(flag.NewFlagSet("gator", flag.ContinueOnError))
+    tests := []struct {
+        args []string
+        want requirement
+    }{
+        { []string{ "browse" },                needsSchema },
+        { []string{ "migrate", "up" },         needsDatabase },
+        { []string{ "profile", "use", "x" },   needsLittle },
+        { []string{ "doctor" },                needsLittle },
+        { []string{ "help" },                  needsNothing },
+    }
+    for _, tt := range tests {
+        cmd, err := coms.parse(tt.args)
+        if err != nil || cmd.info.needs != tt.want {
+            t.Errorf("%q needs %v, %v; want %v", tt.args, cmd.info.needs, err, tt.want)
+        }
+    }
+}
+
+func TestParseTimeArg(t *testing.T) {
+    empty, err := parseTimeArg("")
+    if err != nil || empty.Valid {
+        t.Errorf("empty = %v, %v; want no filter", empty, err)
+    }
+
+    date, err := parseTimeArg("2024-03-01")
+    if err != nil || !date.Time.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) {
+        t.Errorf("date = %v, %v", date, err)
+    }
+
+    ago, err := parseTimeArg("24h")
+    if err != nil || time.Since(ago.Time) < 24 * time.Hour || time.Since(ago.Time) > 25 * time.Hour {
+        t.Errorf("24h ago = %v, %v", ago, err)
+    }
+
+    if _, err := parseTimeArg("yesterday"); err == nil {
+        t.Error("expected an error for yesterday")
+    }
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+    "context"
+    "flag"
+    "fmt"
+    "internal/database"
+    "sort"
+    "strings"
+    "time"
+    "github.com/google/uuid"
+)
+
+// The scripts only hand the words typed so far to gator __complete, which works out the candidates
+// from the registry and the database.  New commands and flags complete without regenerating them.
+const bashCompletion = `# bash completion for gator, load with: source <(gator completion bash)
+_gator() {
+    local cur words cword
+    if declare -F _get_comp_words_by_ref >/dev/null; then
+        _get_comp_words_by_ref -n =: cur words cword
+    else
+        cur="${COMP_WORDS[COMP_CWORD]}"
+        words=("${COMP_WORDS[@]}")
+        cword=$COMP_CWORD
+    fi
+
+    local IFS=$'\n'
+    COMPREPLY=( $(gator __complete "${words[@]:1:cword-1}" "$cur" 2>/dev/null) )
+
+    # Urls hold colons, which bash splits words on
+    if declare -F __ltrim_colon_completions >/dev/null; then
+        __ltrim_colon_completions "$cur"
+    fi
+}
+complete -o default -F _gator gator
+`
+
+const zshCompletion = `#compdef gator
+# zsh completion for gator, load with: source <(gator completion zsh)
+_gator() {
+    local -a candidates
+    candidates=("${(@f)$(gator __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    if [[ -n "${candidates[*]}" ]]; then
+        compadd -a candidates
+    fi
+}
+compdef _gator gator
+`
+
+const fishCompletion = `# fish completion for gator, load with: gator completion fish | source
+function __gator_complete
+    set -l words (commandline -opc)
+    set -e words[1]
+    gator __complete $words (commandline -ct) 2>/dev/null
+end
+complete -c gator -f -a '(__gator_complete)'
+`
+
+var completionScripts = map[string]string{
+    "bash": bashCompletion,
+    "zsh":  zshCompletion,
+    "fish": fishCompletion,
+}
+
+func handlerCompletion(s *state, cmd command) error {
+    script, ok := completionScripts[cmd.args[0]]
+    if !ok {
+        return usageError{ err: ErrorCompletion, reason: fmt.Sprintf("unknown shell %q, use bash, zsh or fish", cmd.args[0]) }
+    }
+    fmt.Print(script)
+    return nil
+}
+
+// complete lists what the last word could be, given the words before it
+func (c *commands) complete(s *state, words []string) []string {
+    if len(words) == 0 {
+        words = []string{ "" }
+    }
+    current, words := words[len(words) - 1], words[:len(words) - 1]
+
+    // Global flags, then the command
+    words, expectValue := skipFlags(c.globalFlags, words)
+    if expectValue != nil {
+        return matching(flagValues(expectValue.Name), current)
+    }
+    if len(words) == 0 {
+        if strings.HasPrefix(current, "-") {
+            return matching(flagNames(c.globalFlags), current)
+        }
+        return matching(c.names(), current)
+    }
+
+    info, ok := c.commandList[words[0]]
+    if !ok {
+        return nil
+    }
+    words = words[1:]
+
+    for len(info.subcommands) > 0 {
+        if len(words) == 0 {
+            names := []string{}
+            for _, sub := range info.subcommands {
+                names = append(names, sub.name)
+            }
+            return matching(names, current)
+        }
+        info = info.subcommand(words[0])
+        if info == nil {
+            return nil
+        }
+        words = words[1:]
+    }
+
+    fs := info.flagSet()
+    positional, expectValue := skipFlags(fs, words)
+    if expectValue != nil {
+        return matching(flagValues(expectValue.Name), current)
+    }
+    if strings.HasPrefix(current, "-") {
+        return matching(flagNames(fs), current)
+    }
+    if len(positional) >= len(info.complete) {
+        return nil
+    }
+    return matching(info.complete[len(positional)](s), current)
+}
+
+// names lists the commands to offer, in order, leaving out hidden ones
+func (c *commands) names() []string {
+    names := []string{}
+    for name, info := range c.commandList {
+        if !info.hidden {
+            names = append(names, name)
+        }
+    }
+    sort.Strings(names)
+    return names
+}
+
+// skipFlags drops the flags, and the values of flags that take one, from the front of words, the
+// way the flag package parses them.  If the last word is a flag still waiting for its value, that
+// flag is returned.
+func skipFlags(fs *flag.FlagSet, words []string) ([]string, *flag.Flag) {
+    for i := 0; i < len(words); i++ {
+        word := words[i]
+        if word == "--" {
+            return words[i + 1:], nil
+        }
+        if !strings.HasPrefix(word, "-") || word == "-" {
+            return words[i:], nil
+        }
+
+        name := strings.TrimLeft(word, "-")
+        if strings.Contains(name, "=") {
+            continue
+        }
+        f := fs.Lookup(name)
+        if f == nil || isBoolFlag(f) {
+            continue
+        }
+        if i == len(words) - 1 {
+            return nil, f
+        }
+        i++
+    }
+    return nil, nil
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+    b, ok := f.Value.(interface{ IsBoolFlag() bool })
+    return ok && b.IsBoolFlag()
+}
+
+func flagNames(fs *flag.FlagSet) []string {
+    names := []string{}
+    fs.VisitAll(func(f *flag.Flag) {
+        names = append(names, "--" + f.Name)
+    })
+    return names
+}
+
+// flagValues knows the choices for flags that only take a few
+func flagValues(name string) []string {
+    switch name {
+    case "output":
+        return []string{ outputTable, outputJSON, outputCSV }
+    case "log-level":
+        return []string{ "debug", "info", "warn", "error" }
+    case "log-format":
+        return []string{ logFormatText, logFormatJSON }
+    case "sort":
+        return []string{ "published", "fetched" }
+    }
+    return nil
+}
+
+func matching(candidates []string, prefix string) []string {
+    matches := []string{}
+    for _, candidate := range candidates {
+        if strings.HasPrefix(candidate, prefix) {
+            matches = append(matches, candidate)
+        }
+    }
+    return matches
+}
+
+// Completers for positional arguments, filled from the database.  Completion has to stay quick
+// and quiet, so a missing config or database just means no candidates.
+const completionTimeout = 2 * time.Second
+
+func completeRoles(s *state) []string {
+    return []string{ roleUser, roleAdmin }
+}
+
+func completeUsers(s *state) []string {
+    if !completionReady(s) {
+        return nil
+    }
+    ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+    defer cancel()
+
+    users, err := s.dbState.GetUsers(ctx)
+    if err != nil {
+        return nil
+    }
+    return users
+}
+
+func completeFeeds(s *state) []string {
+    if !completionReady(s) {
+        return nil
+    }
+    ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+    defer cancel()
+
+    feeds, err := s.dbState.GetFeeds(ctx)
+    if err != nil {
+        return nil
+    }
+    candidates := []string{}
+    for _, feed := range feeds {
+        candidates = append(candidates, feed.Name, feed.Url)
+    }
+    return candidates
+}
+
+// completeFollowedFeeds offers the feeds the current user follows, or every feed when nobody is logged in
+func completeFollowedFeeds(s *state) []string {
+    if !completionReady(s) {
+        return nil
+    }
+    ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+    defer cancel()
+
+    // Looking the session up does not count as using it, so pressing TAB writes nothing
+    user, err := s.dbState.GetSessionUser(ctx, hashToken(s.cfgState.SessionToken))
+    if s.cfgState.SessionToken == "" || err != nil {
+        return completeFeeds(s)
+    }
+    follows, err := s.dbState.GetFeedFollowsForUser(ctx, database.GetFeedFollowsForUserParams{ Name: user.Name, FolderID: uuid.NullUUID{} })
+    if err != nil {
+        return nil
+    }
+    candidates := []string{}
+    for _, follow := range follows {
+        candidates = append(candidates, follow.FeedName, follow.FeedUrl)
+    }
+    return candidates
+}
+
+func completionReady(s *state) bool {
+    return s.cfgErr == nil && s.dbState != nil
+}
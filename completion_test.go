@@ -0,0 +1,98 @@
+package main
+
+import (
+    "flag"
+    "internal/database"
+    "reflect"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestComplete(t *testing.T) {
+    eachStore(t, func(t *testing.T, s *state, q database.Querier) {
+        alice := loginAs(t, s, q, "alice", roleUser)
+        bob   := addUser(t, q, "bob", roleUser)
+        addFeed(t, q, alice, "Blog", "https://blog.example.com/rss")
+        addFeed(t, q, bob,   "News", "https://news.example.com/rss")
+        coms := newCommands(flag.NewFlagSet("gator", flag.ContinueOnError))
+        coms.globalFlags.String("output", outputTable, "")
+
+        tests := []struct {
+            words []string
+            want  []string
+        }{
+            { []string{ "fo" },                              []string{ "folder", "follow", "follow-settings", "following" } },
+            { []string{ "--output", "" },                    []string{ outputTable, outputJSON, outputCSV } },
+            { []string{ "--output", "json", "us" },          []string{ "user", "users" } },
+            { []string{ "folder", "" },                      []string{ "create", "rename", "move", "list" } },
+            { []string{ "browse", "--inc" },                 []string{ "--include-muted" } },
+            { []string{ "browse", "--sort", "" },            []string{ "published", "fetched" } },
+            { []string{ "login", "" },                       []string{ "alice", "bob" } },
+            { []string{ "login", "alice", "" },              []string{} },
+            { []string{ "follow", "" },                      []string{ "Blog", "https://blog.example.com/rss", "News", "https://news.example.com/rss" } },
+            { []string{ "follow", "https://n" },             []string{ "https://news.example.com/rss" } },
+            { []string{ "set-role", "bob", "" },             []string{ roleUser, roleAdmin } },
+            { []string{ "feed", "transfer", "Blog", "b" },   []string{ "bob" } },
+            { []string{ "completion", "" },                  []string{ "bash", "zsh", "fish" } },
+            { []string{ "nope", "" },                        []string{} },
+
+            // Only feeds alice follows, flags before the feed do not count as arguments
+            { []string{ "unfollow", "" },                    []string{ "Blog", "https://blog.example.com/rss" } },
+            { []string{ "follow-settings", "--title", "x", "--muted", "" }, []string{ "Blog", "https://blog.example.com/rss" } },
+        }
+        for _, tt := range tests {
+            got := coms.complete(s, tt.words)
+            if len(got) == 0 && len(tt.want) == 0 {
+                continue
+            }
+            if !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("complete(%q) = %q, want %q", tt.words, got, tt.want)
+            }
+        }
+
+        // Hidden commands are not offered, and are not in help
+        for _, name := range coms.complete(s, []string{ "" }) {
+            if strings.HasPrefix(name, "__") {
+                t.Errorf("hidden command %v offered", name)
+            }
+        }
+        assertContains(t, run(t, s, "__complete", "login", "a"), "alice\n")
+        if out := run(t, s, "help"); strings.Contains(out, "__complete") {
+            t.Error("help lists the hidden command")
+        }
+    })
+}
+
+func TestCompleteLeavesSessionAlone(t *testing.T) {
+    s, fake := newTestState(t)
+    alice := loginAs(t, s, fake, "alice", roleUser)
+    addFeed(t, fake, alice, "Blog", "https://blog.example.com/rss")
+    lastUsed := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+    fake.sessions[0].LastUsedAt = lastUsed
+
+    coms := newCommands(flag.NewFlagSet("gator", flag.ContinueOnError))
+    if got := coms.complete(s, []string{ "unfollow", "" }); len(got) != 2 {
+        t.Fatalf("complete(unfollow) = %q, want alice's feed", got)
+    }
+    if !fake.sessions[0].LastUsedAt.Equal(lastUsed) {
+        t.Errorf("completing moved last_used_at to %v", fake.sessions[0].LastUsedAt)
+    }
+}
+
+func TestCompleteWithoutDatabase(t *testing.T) {
+    s, _ := newTestState(t)
+    s.dbState = nil
+    coms := newCommands(flag.NewFlagSet("gator", flag.ContinueOnError))
+    if got := coms.complete(s, []string{ "login", "" }); len(got) != 0 {
+        t.Errorf("complete without a database = %q, want nothing", got)
+    }
+}
+
+func TestHandlerCompletion(t *testing.T) {
+    s, _ := newTestState(t)
+    for _, shell := range []string{ "bash", "zsh", "fish" } {
+        assertContains(t, run(t, s, "completion", shell), "gator __complete")
+    }
+    assertErr(t, runErr(s, "completion", "powershell"), ErrorCompletion)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+    "internal/config"
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+// An address nothing listens on, so connecting fails fast
+const unreachableDBUrl = "postgres://gator@127.0.0.1:1/gator?sslmode=disable"
+
+func TestHandlerInit(t *testing.T) {
+    s, _ := newTestState(t)
+
+    assertErr(t, runErr(s, "init", "--db-url", unreachableDBUrl), ErrorConnectingDB)
+    if path, _ := config.Path(); fileExists(path) {
+        t.Error("init wrote a config for a database it could not reach")
+    }
+
+    out := run(t, s, "init", "--db-url", unreachableDBUrl, "--profile", "work", "--force")
+    assertContains(t, out, "work")
+    cfg, err := config.Read("")
+    if err != nil || cfg.ProfileName != "work" || cfg.DBUrl != unreachableDBUrl {
+        t.Errorf("config after init = %+v, %v", cfg, err)
+    }
+}
+
+func TestHandlerDoctor(t *testing.T) {
+    s, _ := newTestState(t)
+    path, _ := config.Path()
+    _, s.cfgErr = os.Stat(path)
+
+    out, err := capture(func() error { return handlerDoctor(s, command{}) })
+    assertErr(t, err, ErrorDoctor)
+    assertContains(t, out, "[FAIL] config file", "gator init")
+
+    run(t, s, "profile", "add", "default", unreachableDBUrl)
+    s.cfgErr = nil
+    s.cfgState.DBUrl = unreachableDBUrl
+
+    out, err = capture(func() error { return handlerDoctor(s, command{}) })
+    assertErr(t, err, ErrorDoctor)
+    assertContains(t, out, "[ok]   config file", "[FAIL] database reachable", "fix:")
+}
+
+func fileExists(path string) bool {
+    _, err := os.Stat(filepath.Clean(path))
+    return err == nil
+}
@@ -0,0 +1,1044 @@
+package main
+
+import (
+    "context"
+    "database/sql"
+    "internal/database"
+    "maps"
+    "slices"
+    "sort"
+    "strings"
+    "time"
+    "github.com/google/uuid"
+    "github.com/lib/pq"
+)
+
+// fakeStore is an in-memory store for tests.  It keeps enough of the schema's rules
+// (unique names and urls, cascades, feed owners restricting user deletes) for handlers
+// to hit the same errors they would against Postgres.
+type fakeStore struct {
+    users    []database.User
+    feeds    []database.Feed
+    follows  []database.FeedFollow
+    folders  []database.Folder
+    posts    []database.Post
+    reads    []database.PostRead
+    stars    []database.PostStar
+    sessions []database.Session
+    archives []database.Archive
+    archived []database.ArchiveResource
+    blobs    map[string][]byte
+}
+
+var _ store = (*fakeStore)(nil)
+
+// Errors as Postgres returns them, so code checking for them by code works on the fake too
+var errFakeDuplicate  = &pq.Error{ Code: pqUniqueViolation, Message: "duplicate key value violates unique constraint" }
+var errFakeForeignKey = &pq.Error{ Code: "23503",           Message: "update or delete on table violates foreign key constraint" }
+
+func newFakeStore() *fakeStore {
+    return &fakeStore{}
+}
+
+// Transactions snapshot the whole store, and a rollback before commit puts the snapshot back
+type fakeTx struct {
+    store    *fakeStore
+    snapshot fakeStore
+    done     bool
+}
+
+func (f *fakeStore) begin(ctx context.Context) (database.Querier, txn, error) {
+    return f, &fakeTx{ store: f, snapshot: f.clone() }, nil
+}
+
+func (tx *fakeTx) Commit() error {
+    tx.done = true
+    return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+    if !tx.done {
+        *tx.store = tx.snapshot
+        tx.done   = true
+    }
+    return nil
+}
+
+func (f *fakeStore) clone() fakeStore {
+    return fakeStore{ users:   slices.Clone(f.users),   feeds: slices.Clone(f.feeds), follows:  slices.Clone(f.follows),
+                      folders: slices.Clone(f.folders), posts: slices.Clone(f.posts), reads:    slices.Clone(f.reads),
+                      stars:   slices.Clone(f.stars),   sessions: slices.Clone(f.sessions), archives: slices.Clone(f.archives),
+                      archived: slices.Clone(f.archived), blobs: maps.Clone(f.blobs), }
+}
+
+// Lookups
+
+func (f *fakeStore) user(id uuid.UUID) (database.User, bool) {
+    for _, user := range f.users {
+        if user.ID == id {
+            return user, true
+        }
+    }
+    return database.User{}, false
+}
+
+func (f *fakeStore) feed(id uuid.UUID) (database.Feed, bool) {
+    for _, feed := range f.feeds {
+        if feed.ID == id {
+            return feed, true
+        }
+    }
+    return database.Feed{}, false
+}
+
+func (f *fakeStore) post(id uuid.UUID) (database.Post, bool) {
+    for _, post := range f.posts {
+        if post.ID == id {
+            return post, true
+        }
+    }
+    return database.Post{}, false
+}
+
+func (f *fakeStore) follow(userID, feedID uuid.UUID) (database.FeedFollow, bool) {
+    for _, follow := range f.follows {
+        if follow.UserID == userID && follow.FeedID == feedID {
+            return follow, true
+        }
+    }
+    return database.FeedFollow{}, false
+}
+
+func (f *fakeStore) isRead(userID, postID uuid.UUID) bool {
+    for _, read := range f.reads {
+        if read.UserID == userID && read.PostID == postID {
+            return true
+        }
+    }
+    return false
+}
+
+func (f *fakeStore) isStarred(userID, postID uuid.UUID) bool {
+    for _, star := range f.stars {
+        if star.UserID == userID && star.PostID == postID {
+            return true
+        }
+    }
+    return false
+}
+
+// inFolder is the fake's version of the recursive subtree query
+func (f *fakeStore) inFolder(folderID uuid.NullUUID, root uuid.NullUUID) bool {
+    if !root.Valid {
+        return true
+    }
+    for cur := folderID; cur.Valid; {
+        if cur.UUID == root.UUID {
+            return true
+        }
+        next := uuid.NullUUID{}
+        for _, folder := range f.folders {
+            if folder.ID == cur.UUID {
+                next = folder.ParentID
+            }
+        }
+        cur = next
+    }
+    return false
+}
+
+// followName is COALESCE(feed_follows.title, feeds.name)
+func followName(follow database.FeedFollow, feed database.Feed) string {
+    if follow.Title.Valid {
+        return follow.Title.String
+    }
+    return feed.Name
+}
+
+func feedMatches(arg sql.NullString, follow database.FeedFollow, feed database.Feed) bool {
+    return !arg.Valid || feed.Name == arg.String || feed.Url == arg.String || (follow.Title.Valid && follow.Title.String == arg.String)
+}
+
+// Deletes, with the schema's cascades
+
+func (f *fakeStore) deletePostsWhere(match func(database.Post) bool) int64 {
+    n := int64(0)
+    f.posts = slices.DeleteFunc(f.posts, func(post database.Post) bool {
+        if !match(post) {
+            return false
+        }
+        f.reads = slices.DeleteFunc(f.reads, func(read database.PostRead) bool { return read.PostID == post.ID })
+        f.stars = slices.DeleteFunc(f.stars, func(star database.PostStar) bool { return star.PostID == post.ID })
+        f.deleteArchive(post.ID)
+        n++
+        return true
+    })
+    return n
+}
+
+func (f *fakeStore) deleteFeedsWhere(match func(database.Feed) bool) int64 {
+    n := int64(0)
+    f.feeds = slices.DeleteFunc(f.feeds, func(feed database.Feed) bool {
+        if !match(feed) {
+            return false
+        }
+        f.follows = slices.DeleteFunc(f.follows, func(follow database.FeedFollow) bool { return follow.FeedID == feed.ID })
+        f.deletePostsWhere(func(post database.Post) bool { return post.FeedID == feed.ID })
+        n++
+        return true
+    })
+    return n
+}
+
+func (f *fakeStore) deleteUsersWhere(match func(database.User) bool) (int64, error) {
+    for _, user := range f.users {
+        if !match(user) {
+            continue
+        }
+        for _, feed := range f.feeds {
+            if feed.UserID == user.ID {
+                return 0, errFakeForeignKey
+            }
+        }
+    }
+
+    n := int64(0)
+    f.users = slices.DeleteFunc(f.users, func(user database.User) bool {
+        if !match(user) {
+            return false
+        }
+        f.follows  = slices.DeleteFunc(f.follows,  func(follow database.FeedFollow) bool { return follow.UserID == user.ID })
+        f.folders  = slices.DeleteFunc(f.folders,  func(folder database.Folder) bool { return folder.UserID == user.ID })
+        f.reads    = slices.DeleteFunc(f.reads,    func(read database.PostRead) bool { return read.UserID == user.ID })
+        f.stars    = slices.DeleteFunc(f.stars,    func(star database.PostStar) bool { return star.UserID == user.ID })
+        f.sessions = slices.DeleteFunc(f.sessions, func(session database.Session) bool { return session.UserID == user.ID })
+        n++
+        return true
+    })
+    return n, nil
+}
+
+// Querier
+
+func (f *fakeStore) CountUsableAdmins(ctx context.Context) (int64, error) {
+    n := int64(0)
+    for _, user := range f.users {
+        if user.Role == roleAdmin && user.PasswordHash.Valid {
+            n++
+        }
+    }
+    return n, nil
+}
+
+func (f *fakeStore) CountUsers(ctx context.Context) (int64, error) {
+    return int64(len(f.users)), nil
+}
+
+func (f *fakeStore) CreateFeed(ctx context.Context, arg database.CreateFeedParams) (database.Feed, error) {
+    for _, feed := range f.feeds {
+        if feed.Url == arg.Url {
+            return database.Feed{}, errFakeDuplicate
+        }
+    }
+    if _, ok := f.user(arg.UserID); !ok {
+        return database.Feed{}, errFakeForeignKey
+    }
+
+    feed := database.Feed{ ID: arg.ID, CreatedAt: arg.CreatedAt, UpdatedAt: arg.UpdatedAt, Name: arg.Name, Url: arg.Url, UserID: arg.UserID }
+    f.feeds = append(f.feeds, feed)
+    return feed, nil
+}
+
+func (f *fakeStore) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+    if _, ok := f.follow(arg.UserID, arg.FeedID); ok {
+        return database.CreateFeedFollowRow{}, errFakeDuplicate
+    }
+    user, ok := f.user(arg.UserID)
+    feed, ok2 := f.feed(arg.FeedID)
+    if !ok || !ok2 {
+        return database.CreateFeedFollowRow{}, errFakeForeignKey
+    }
+
+    follow := database.FeedFollow{ ID: arg.ID, CreatedAt: arg.CreatedAt, UpdatedAt: arg.UpdatedAt, UserID: arg.UserID, FeedID: arg.FeedID, Notify: true }
+    f.follows = append(f.follows, follow)
+    return database.CreateFeedFollowRow{ ID:       follow.ID,       CreatedAt: follow.CreatedAt, UpdatedAt: follow.UpdatedAt, UserID: follow.UserID,
+                                         FeedID:   follow.FeedID,   Notify:    follow.Notify,
+                                         FeedName: feed.Name,       UserName:  user.Name, }, nil
+}
+
+func (f *fakeStore) CreateFolder(ctx context.Context, arg database.CreateFolderParams) (database.Folder, error) {
+    for _, folder := range f.folders {
+        if folder.UserID == arg.UserID && folder.Name == arg.Name {
+            return database.Folder{}, errFakeDuplicate
+        }
+    }
+
+    folder := database.Folder{ ID: arg.ID, CreatedAt: arg.CreatedAt, UpdatedAt: arg.UpdatedAt, UserID: arg.UserID, Name: arg.Name, ParentID: arg.ParentID }
+    f.folders = append(f.folders, folder)
+    return folder, nil
+}
+
+func (f *fakeStore) CreatePost(ctx context.Context, arg database.CreatePostParams) (database.Post, error) {
+    for _, post := range f.posts {
+        if post.Url == arg.Url {
+            return database.Post{}, errFakeDuplicate
+        }
+    }
+
+    post := database.Post{ ID:          arg.ID,          CreatedAt:   arg.CreatedAt,   UpdatedAt: arg.UpdatedAt, Title: arg.Title, Url: arg.Url,
+                           Description: arg.Description, PublishedAt: arg.PublishedAt, FeedID:    arg.FeedID,
+                           DescriptionRaw: arg.DescriptionRaw, Content: arg.Content,   ContentRaw: arg.ContentRaw, }
+    f.posts = append(f.posts, post)
+    return post, nil
+}
+
+func (f *fakeStore) CreateSession(ctx context.Context, arg database.CreateSessionParams) error {
+    f.sessions = append(f.sessions, database.Session{ TokenHash: arg.TokenHash, UserID: arg.UserID, CreatedAt: arg.CreatedAt, LastUsedAt: arg.LastUsedAt })
+    return nil
+}
+
+func (f *fakeStore) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+    for _, user := range f.users {
+        if user.Name == arg.Name {
+            return database.User{}, errFakeDuplicate
+        }
+    }
+
+    user := database.User{ ID: arg.ID, CreatedAt: arg.CreatedAt, UpdatedAt: arg.UpdatedAt, Name: arg.Name, PasswordHash: arg.PasswordHash, Role: arg.Role }
+    f.users = append(f.users, user)
+    return user, nil
+}
+
+func (f *fakeStore) Delete(ctx context.Context) error {
+    f.deleteFeedsWhere(func(database.Feed) bool { return true })
+    return nil
+}
+
+func (f *fakeStore) DeleteFeed(ctx context.Context, iD uuid.UUID) (int64, error) {
+    return f.deleteFeedsWhere(func(feed database.Feed) bool { return feed.ID == iD }), nil
+}
+
+func (f *fakeStore) DeleteFeedFollows(ctx context.Context) (int64, error) {
+    n := int64(len(f.follows))
+    f.follows = nil
+    return n, nil
+}
+
+func (f *fakeStore) DeleteFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+    before := len(f.follows)
+    f.follows = slices.DeleteFunc(f.follows, func(follow database.FeedFollow) bool { return follow.UserID == userID })
+    return int64(before - len(f.follows)), nil
+}
+
+func (f *fakeStore) DeleteFeedFollowsForUserUrl(ctx context.Context, arg database.DeleteFeedFollowsForUserUrlParams) (int64, error) {
+    before := len(f.follows)
+    f.follows = slices.DeleteFunc(f.follows, func(follow database.FeedFollow) bool { return follow.UserID == arg.UserID && follow.FeedID == arg.FeedID })
+    return int64(before - len(f.follows)), nil
+}
+
+func (f *fakeStore) DeleteFeeds(ctx context.Context) (int64, error) {
+    return f.deleteFeedsWhere(func(database.Feed) bool { return true }), nil
+}
+
+func (f *fakeStore) DeleteFeedsWithoutFollowers(ctx context.Context) (int64, error) {
+    return f.deleteFeedsWhere(func(feed database.Feed) bool {
+        for _, follow := range f.follows {
+            if follow.FeedID == feed.ID {
+                return false
+            }
+        }
+        return true
+    }), nil
+}
+
+func (f *fakeStore) DeletePosts(ctx context.Context) (int64, error) {
+    return f.deletePostsWhere(func(database.Post) bool { return true }), nil
+}
+
+func (f *fakeStore) DeleteSession(ctx context.Context, tokenHash string) error {
+    f.sessions = slices.DeleteFunc(f.sessions, func(session database.Session) bool { return session.TokenHash == tokenHash })
+    return nil
+}
+
+func (f *fakeStore) DeleteSessionsForUser(ctx context.Context, arg database.DeleteSessionsForUserParams) (int64, error) {
+    n := len(f.sessions)
+    f.sessions = slices.DeleteFunc(f.sessions, func(session database.Session) bool {
+        return session.UserID == arg.UserID && session.TokenHash != arg.KeepTokenHash
+    })
+    return int64(n - len(f.sessions)), nil
+}
+
+func (f *fakeStore) DeleteUserByName(ctx context.Context, name string) (int64, error) {
+    return f.deleteUsersWhere(func(user database.User) bool { return user.Name == name })
+}
+
+func (f *fakeStore) DeleteUsers(ctx context.Context) (int64, error) {
+    return f.deleteUsersWhere(func(database.User) bool { return true })
+}
+
+func (f *fakeStore) GetFeedFollowsForUser(ctx context.Context, arg database.GetFeedFollowsForUserParams) ([]database.GetFeedFollowsForUserRow, error) {
+    rows := []database.GetFeedFollowsForUserRow{}
+    for _, follow := range f.follows {
+        user, _ := f.user(follow.UserID)
+        feed, _ := f.feed(follow.FeedID)
+        if user.Name != arg.Name || !f.inFolder(follow.FolderID, arg.FolderID) {
+            continue
+        }
+        rows = append(rows, database.GetFeedFollowsForUserRow{ ID:       follow.ID,       CreatedAt: follow.CreatedAt, UpdatedAt: follow.UpdatedAt,
+                                                                UserID:   follow.UserID,   FeedID:    follow.FeedID,    FolderID:  follow.FolderID,
+                                                                Title:    follow.Title,    Muted:     follow.Muted,     Priority:  follow.Priority,
+                                                                Notify:   follow.Notify,   UserName:  user.Name,
+                                                                FeedName: followName(follow, feed), FeedUrl: feed.Url, })
+    }
+    sort.SliceStable(rows, func(i, j int) bool {
+        if rows[i].Priority != rows[j].Priority {
+            return rows[i].Priority > rows[j].Priority
+        }
+        return rows[i].FeedName < rows[j].FeedName
+    })
+    return rows, nil
+}
+
+func (f *fakeStore) GetFeedUrl(ctx context.Context, url string) (database.Feed, error) {
+    for _, feed := range f.feeds {
+        if feed.Url == url {
+            return feed, nil
+        }
+    }
+    return database.Feed{}, sql.ErrNoRows
+}
+
+func (f *fakeStore) GetFeeds(ctx context.Context) ([]database.GetFeedsRow, error) {
+    rows := []database.GetFeedsRow{}
+    for _, feed := range f.feeds {
+        rows = append(rows, database.GetFeedsRow{ Name: feed.Name, Url: feed.Url, UserID: feed.UserID })
+    }
+    return rows, nil
+}
+
+func (f *fakeStore) feedsWhere(match func(database.Feed) bool) []database.Feed {
+    feeds := []database.Feed{}
+    for _, feed := range f.feeds {
+        if match(feed) {
+            feeds = append(feeds, feed)
+        }
+    }
+    sort.SliceStable(feeds, func(i, j int) bool { return feeds[i].CreatedAt.Before(feeds[j].CreatedAt) })
+    return feeds
+}
+
+func (f *fakeStore) GetFeedsByIDPrefix(ctx context.Context, prefix string) ([]database.Feed, error) {
+    return f.feedsWhere(func(feed database.Feed) bool { return strings.HasPrefix(feed.ID.String(), prefix) }), nil
+}
+
+func (f *fakeStore) GetFeedsByName(ctx context.Context, name string) ([]database.Feed, error) {
+    return f.feedsWhere(func(feed database.Feed) bool { return feed.Name == name }), nil
+}
+
+func (f *fakeStore) GetFeedsForUser(ctx context.Context, userID uuid.UUID) ([]database.Feed, error) {
+    return f.feedsWhere(func(feed database.Feed) bool { return feed.UserID == userID }), nil
+}
+
+func (f *fakeStore) GetFeedsWithName(ctx context.Context) ([]database.GetFeedsWithNameRow, error) {
+    rows := []database.GetFeedsWithNameRow{}
+    for _, feed := range f.feeds {
+        user, _ := f.user(feed.UserID)
+        rows = append(rows, database.GetFeedsWithNameRow{ ID: feed.ID, Name: feed.Name, Url: feed.Url, Username: user.Name })
+    }
+    return rows, nil
+}
+
+func (f *fakeStore) GetFolderByName(ctx context.Context, arg database.GetFolderByNameParams) (database.Folder, error) {
+    for _, folder := range f.folders {
+        if folder.UserID == arg.UserID && folder.Name == arg.Name {
+            return folder, nil
+        }
+    }
+    return database.Folder{}, sql.ErrNoRows
+}
+
+func (f *fakeStore) GetFoldersForUser(ctx context.Context, userID uuid.UUID) ([]database.Folder, error) {
+    folders := []database.Folder{}
+    for _, folder := range f.folders {
+        if folder.UserID == userID {
+            folders = append(folders, folder)
+        }
+    }
+    sort.Slice(folders, func(i, j int) bool { return folders[i].Name < folders[j].Name })
+    return folders, nil
+}
+
+func (f *fakeStore) GetNextFeedOwner(ctx context.Context, arg database.GetNextFeedOwnerParams) (uuid.UUID, error) {
+    next := database.FeedFollow{}
+    for _, follow := range f.follows {
+        if follow.FeedID != arg.FeedID || follow.UserID == arg.UserID {
+            continue
+        }
+        if next.ID == uuid.Nil || follow.CreatedAt.Before(next.CreatedAt) {
+            next = follow
+        }
+    }
+    if next.ID == uuid.Nil {
+        return uuid.Nil, sql.ErrNoRows
+    }
+    return next.UserID, nil
+}
+
+func (f *fakeStore) GetNextFeedToFetch(ctx context.Context) (database.Feed, error) {
+    if len(f.feeds) == 0 {
+        return database.Feed{}, sql.ErrNoRows
+    }
+
+    // ORDER BY last_fetched_at ASC NULLS FIRST
+    next := f.feeds[0]
+    for _, feed := range f.feeds[1:] {
+        if !next.LastFetchedAt.Valid {
+            break
+        }
+        if !feed.LastFetchedAt.Valid || feed.LastFetchedAt.Time.Before(next.LastFetchedAt.Time) {
+            next = feed
+        }
+    }
+    return next, nil
+}
+
+// postsForUser joins posts to the user's follows, the way the browse queries do
+func (f *fakeStore) postsForUser(userID uuid.UUID, match func(database.Post, database.FeedFollow, database.Feed) bool) []database.GetPostsForUserFilteredRow {
+    rows := []database.GetPostsForUserFilteredRow{}
+    for _, post := range f.posts {
+        follow, ok := f.follow(userID, post.FeedID)
+        if !ok {
+            continue
+        }
+        feed, _ := f.feed(post.FeedID)
+        if !match(post, follow, feed) {
+            continue
+        }
+        rows = append(rows, database.GetPostsForUserFilteredRow{ ID:          post.ID,          CreatedAt:   post.CreatedAt,   UpdatedAt: post.UpdatedAt,
+                                                                  Title:       post.Title,       Url:         post.Url,         Description: post.Description,
+                                                                  PublishedAt: post.PublishedAt, FeedID:      post.FeedID,      FeedName:  followName(follow, feed),
+                                                                  DescriptionRaw: post.DescriptionRaw, Content: post.Content,  ContentRaw: post.ContentRaw,
+                                                                  Article:        post.Article, })
+    }
+    return rows
+}
+
+func (f *fakeStore) GetPostsForUser(ctx context.Context, arg database.GetPostsForUserParams) ([]database.GetPostsForUserRow, error) {
+    posts := f.postsForUser(arg.UserID, func(database.Post, database.FeedFollow, database.Feed) bool { return true })
+    sort.SliceStable(posts, func(i, j int) bool { return posts[i].PublishedAt.Time.After(posts[j].PublishedAt.Time) })
+
+    rows := []database.GetPostsForUserRow{}
+    for _, post := range posts {
+        if len(rows) == int(arg.Limit) {
+            break
+        }
+        rows = append(rows, database.GetPostsForUserRow(post))
+    }
+    return rows, nil
+}
+
+func (f *fakeStore) GetPostsForUserFiltered(ctx context.Context, arg database.GetPostsForUserFilteredParams) ([]database.GetPostsForUserFilteredRow, error) {
+    sortTime := func(post database.Post) sql.NullTime {
+        if arg.SortBy == "fetched" {
+            return sql.NullTime{ Time: post.CreatedAt, Valid: true }
+        }
+        return post.PublishedAt
+    }
+    contains := func(s, sub string) bool { return strings.Contains(strings.ToLower(s), strings.ToLower(sub)) }
+
+    posts := f.postsForUser(arg.UserID, func(post database.Post, follow database.FeedFollow, feed database.Feed) bool {
+        t := sortTime(post)
+        switch {
+        case !feedMatches(arg.Feed, follow, feed):
+            return false
+        case arg.Since.Valid && (!t.Valid || t.Time.Before(arg.Since.Time)):
+            return false
+        case arg.Until.Valid && (!t.Valid || !t.Time.Before(arg.Until.Time)):
+            return false
+        case arg.Match.Valid && !contains(post.Title, arg.Match.String) && !contains(post.Description.String, arg.Match.String):
+            return false
+        case arg.UnreadOnly && f.isRead(arg.UserID, post.ID):
+            return false
+        case !f.inFolder(follow.FolderID, arg.FolderID):
+            return false
+        case !arg.IncludeMuted && follow.Muted:
+            return false
+        }
+        return true
+    })
+
+    // DESC NULLS LAST
+    byID := map[uuid.UUID]database.Post{}
+    for _, post := range f.posts {
+        byID[post.ID] = post
+    }
+    sort.SliceStable(posts, func(i, j int) bool {
+        a, b := sortTime(byID[posts[i].ID]), sortTime(byID[posts[j].ID])
+        if a.Valid != b.Valid {
+            return a.Valid
+        }
+        return a.Time.After(b.Time)
+    })
+
+    start := min(int(arg.PageOffset), len(posts))
+    end   := min(start + int(arg.PageSize), len(posts))
+    return posts[start:end], nil
+}
+
+func (f *fakeStore) GetPostsForReader(ctx context.Context, arg database.GetPostsForReaderParams) ([]database.GetPostsForReaderRow, error) {
+    byID := map[uuid.UUID]database.Post{}
+    for _, post := range f.posts {
+        byID[post.ID] = post
+    }
+    sortTime := func(id uuid.UUID) time.Time {
+        post := byID[id]
+        if post.PublishedAt.Valid {
+            return post.PublishedAt.Time
+        }
+        return post.CreatedAt
+    }
+
+    posts := f.postsForUser(arg.UserID, func(post database.Post, follow database.FeedFollow, feed database.Feed) bool { return !follow.Muted })
+    sort.SliceStable(posts, func(i, j int) bool { return sortTime(posts[i].ID).After(sortTime(posts[j].ID)) })
+
+    rows := []database.GetPostsForReaderRow{}
+    for _, post := range posts {
+        if len(rows) == int(arg.PageSize) {
+            break
+        }
+        follow, _ := f.follow(arg.UserID, post.FeedID)
+        rows = append(rows, database.GetPostsForReaderRow{ ID:          post.ID,          Title:     post.Title,     Url:         post.Url,
+                                                            Description: post.Description, CreatedAt: post.CreatedAt, PublishedAt: post.PublishedAt,
+                                                            Content:     post.Content,     Article:   post.Article,
+                                                            FeedID:      post.FeedID,      FeedName:  post.FeedName,  FolderID:    follow.FolderID,
+                                                            IsRead:      f.isRead(arg.UserID, post.ID),
+                                                            IsStarred:   f.isStarred(arg.UserID, post.ID), })
+    }
+    return rows, nil
+}
+
+func (f *fakeStore) GetPostsRaw(ctx context.Context, unsanitizedOnly bool) ([]database.GetPostsRawRow, error) {
+    rows := []database.GetPostsRawRow{}
+    for _, post := range f.posts {
+        if unsanitizedOnly && (post.Description.Valid || !post.DescriptionRaw.Valid) {
+            continue
+        }
+        feed, _ := f.feed(post.FeedID)
+        rows = append(rows, database.GetPostsRawRow{ ID: post.ID, Url: post.Url, DescriptionRaw: post.DescriptionRaw, ContentRaw: post.ContentRaw, FeedUrl: feed.Url })
+    }
+    return rows, nil
+}
+
+func (f *fakeStore) GetUser(ctx context.Context, name string) (database.User, error) {
+    for _, user := range f.users {
+        if user.Name == name {
+            return user, nil
+        }
+    }
+    return database.User{}, sql.ErrNoRows
+}
+
+func (f *fakeStore) GetSessionUser(ctx context.Context, tokenHash string) (database.User, error) {
+    for _, session := range f.sessions {
+        if session.TokenHash != tokenHash {
+            continue
+        }
+        if user, ok := f.user(session.UserID); ok {
+            return user, nil
+        }
+        break
+    }
+    return database.User{}, sql.ErrNoRows
+}
+
+func (f *fakeStore) GetUserBySession(ctx context.Context, tokenHash string) (database.User, error) {
+    for i, session := range f.sessions {
+        if session.TokenHash != tokenHash {
+            continue
+        }
+        user, ok := f.user(session.UserID)
+        if !ok {
+            break
+        }
+        f.sessions[i].LastUsedAt = time.Now()
+        return user, nil
+    }
+    return database.User{}, sql.ErrNoRows
+}
+
+func (f *fakeStore) GetUserName(ctx context.Context, id uuid.UUID) (string, error) {
+    user, ok := f.user(id)
+    if !ok {
+        return "", sql.ErrNoRows
+    }
+    return user.Name, nil
+}
+
+func (f *fakeStore) GetUsers(ctx context.Context) ([]string, error) {
+    names := []string{}
+    for _, user := range f.users {
+        names = append(names, user.Name)
+    }
+    return names, nil
+}
+
+func (f *fakeStore) markRead(userID uuid.UUID, postIDs []uuid.UUID) int64 {
+    n := int64(0)
+    for _, id := range postIDs {
+        if f.isRead(userID, id) {
+            continue
+        }
+        f.reads = append(f.reads, database.PostRead{ UserID: userID, PostID: id, ReadAt: time.Now() })
+        n++
+    }
+    return n
+}
+
+// LockUsers has nothing to do, the fake is only ever used from one goroutine at a time
+func (f *fakeStore) LockUsers(ctx context.Context) error {
+    return nil
+}
+
+func (f *fakeStore) MarkAllPostsRead(ctx context.Context, arg database.MarkAllPostsReadParams) (int64, error) {
+    ids := []uuid.UUID{}
+    for _, post := range f.postsForUser(arg.UserID, func(post database.Post, follow database.FeedFollow, feed database.Feed) bool {
+        return feedMatches(arg.Feed, follow, feed) && f.inFolder(follow.FolderID, arg.FolderID)
+    }) {
+        ids = append(ids, post.ID)
+    }
+    return f.markRead(arg.UserID, ids), nil
+}
+
+func (f *fakeStore) MarkFeedFetched(ctx context.Context, id uuid.UUID) (database.Feed, error) {
+    for i, feed := range f.feeds {
+        if feed.ID == id {
+            f.feeds[i].LastFetchedAt = sql.NullTime{ Time: time.Now(), Valid: true }
+            f.feeds[i].UpdatedAt     = time.Now()
+            return f.feeds[i], nil
+        }
+    }
+    return database.Feed{}, sql.ErrNoRows
+}
+
+func (f *fakeStore) MarkPostRead(ctx context.Context, arg database.MarkPostReadParams) (int64, error) {
+    ids := []uuid.UUID{}
+    for _, post := range f.posts {
+        if _, following := f.follow(arg.UserID, post.FeedID); following && post.Url == arg.Url {
+            ids = append(ids, post.ID)
+        }
+    }
+    return f.markRead(arg.UserID, ids), nil
+}
+
+func (f *fakeStore) MarkPostReadByID(ctx context.Context, arg database.MarkPostReadByIDParams) (int64, error) {
+    return f.markRead(arg.UserID, []uuid.UUID{ arg.PostID }), nil
+}
+
+func (f *fakeStore) RenameFolder(ctx context.Context, arg database.RenameFolderParams) (int64, error) {
+    n := int64(0)
+    for i, folder := range f.folders {
+        if folder.UserID == arg.UserID && folder.Name == arg.OldName {
+            f.folders[i].Name      = arg.NewName
+            f.folders[i].UpdatedAt = time.Now()
+            n++
+        }
+    }
+    return n, nil
+}
+
+func (f *fakeStore) RenameUser(ctx context.Context, arg database.RenameUserParams) (int64, error) {
+    if _, err := f.GetUser(ctx, arg.NewName); err == nil {
+        return 0, errFakeDuplicate
+    }
+
+    n := int64(0)
+    for i, user := range f.users {
+        if user.Name == arg.OldName {
+            f.users[i].Name      = arg.NewName
+            f.users[i].UpdatedAt = time.Now()
+            n++
+        }
+    }
+    return n, nil
+}
+
+// SearchPostsForUser matches posts containing every word of the query, ranked by how often they appear
+func (f *fakeStore) SearchPostsForUser(ctx context.Context, arg database.SearchPostsForUserParams) ([]database.SearchPostsForUserRow, error) {
+    words := strings.Fields(strings.ToLower(arg.Query))
+
+    rows := []database.SearchPostsForUserRow{}
+    for _, post := range f.postsForUser(arg.UserID, func(post database.Post, _ database.FeedFollow, _ database.Feed) bool {
+        return !arg.Since.Valid || (post.PublishedAt.Valid && !post.PublishedAt.Time.Before(arg.Since.Time))
+    }) {
+        // Weighted like the real ranking, titles over descriptions over content
+        fields  := []string{ post.Title, post.Description.String, post.Content.String }
+        weights := []float32{ 1.0, 0.4, 0.2 }
+        rank, snippet := float32(0), ""
+        for _, word := range words {
+            matched := float32(0)
+            for i, field := range fields {
+                count := strings.Count(strings.ToLower(field), word)
+                matched += float32(count) * weights[i]
+                if count > 0 && i > 0 && snippet == "" {
+                    snippet = field
+                }
+            }
+            if matched == 0 {
+                rank = 0
+                break
+            }
+            rank += matched
+        }
+        if rank == 0 {
+            continue
+        }
+        if snippet == "" {
+            snippet = post.Description.String
+        }
+        rows = append(rows, database.SearchPostsForUserRow{ ID:       post.ID,       Title: post.Title, Url:     post.Url, PublishedAt: post.PublishedAt,
+                                                             FeedName: post.FeedName, Rank:  rank,       Snippet: snippet, })
+    }
+    sort.SliceStable(rows, func(i, j int) bool { return rows[i].Rank > rows[j].Rank })
+    if len(rows) > int(arg.PageSize) {
+        rows = rows[:arg.PageSize]
+    }
+    return rows, nil
+}
+
+func (f *fakeStore) SetFeedFollowFolder(ctx context.Context, arg database.SetFeedFollowFolderParams) (int64, error) {
+    for i, follow := range f.follows {
+        if follow.UserID == arg.UserID && follow.FeedID == arg.FeedID {
+            f.follows[i].FolderID  = arg.FolderID
+            f.follows[i].UpdatedAt = time.Now()
+            return 1, nil
+        }
+    }
+    return 0, nil
+}
+
+func (f *fakeStore) SetFeedOwner(ctx context.Context, arg database.SetFeedOwnerParams) error {
+    for i, feed := range f.feeds {
+        if feed.ID == arg.ID {
+            f.feeds[i].UserID    = arg.UserID
+            f.feeds[i].UpdatedAt = time.Now()
+        }
+    }
+    return nil
+}
+
+func (f *fakeStore) SetUserPassword(ctx context.Context, arg database.SetUserPasswordParams) error {
+    for i, user := range f.users {
+        if user.ID == arg.ID {
+            f.users[i].PasswordHash = arg.PasswordHash
+            f.users[i].UpdatedAt    = time.Now()
+        }
+    }
+    return nil
+}
+
+func (f *fakeStore) SetUserRole(ctx context.Context, arg database.SetUserRoleParams) (int64, error) {
+    n := int64(0)
+    for i, user := range f.users {
+        if user.Name == arg.Name {
+            f.users[i].Role      = arg.Role
+            f.users[i].UpdatedAt = time.Now()
+            n++
+        }
+    }
+    return n, nil
+}
+
+func (f *fakeStore) StarPost(ctx context.Context, arg database.StarPostParams) (int64, error) {
+    if f.isStarred(arg.UserID, arg.PostID) {
+        return 0, nil
+    }
+    f.stars = append(f.stars, database.PostStar{ UserID: arg.UserID, PostID: arg.PostID, StarredAt: time.Now() })
+    return 1, nil
+}
+
+func (f *fakeStore) UnstarPost(ctx context.Context, arg database.UnstarPostParams) (int64, error) {
+    n := int64(len(f.stars))
+    f.stars = slices.DeleteFunc(f.stars, func(star database.PostStar) bool { return star.UserID == arg.UserID && star.PostID == arg.PostID })
+    return n - int64(len(f.stars)), nil
+}
+
+func (f *fakeStore) UpdateFeedSettings(ctx context.Context, arg database.UpdateFeedSettingsParams) (database.Feed, error) {
+    for i := range f.feeds {
+        if f.feeds[i].ID == arg.ID {
+            if arg.FullText.Valid {
+                f.feeds[i].FullText = arg.FullText.Bool
+            }
+            if arg.Archive.Valid {
+                f.feeds[i].Archive = arg.Archive.Bool
+            }
+            f.feeds[i].UpdatedAt = time.Now()
+            return f.feeds[i], nil
+        }
+    }
+    return database.Feed{}, sql.ErrNoRows
+}
+
+func (f *fakeStore) SetPostArticle(ctx context.Context, arg database.SetPostArticleParams) error {
+    for i := range f.posts {
+        if f.posts[i].ID == arg.ID {
+            f.posts[i].Article, f.posts[i].UpdatedAt = arg.Article, time.Now()
+        }
+    }
+    return nil
+}
+
+func (f *fakeStore) UpdatePostSanitized(ctx context.Context, arg database.UpdatePostSanitizedParams) (int64, error) {
+    for i := range f.posts {
+        if f.posts[i].ID == arg.ID {
+            f.posts[i].Description, f.posts[i].Content, f.posts[i].UpdatedAt = arg.Description, arg.Content, time.Now()
+            return 1, nil
+        }
+    }
+    return 0, nil
+}
+
+func (f *fakeStore) UpdateFeedFollowSettings(ctx context.Context, arg database.UpdateFeedFollowSettingsParams) (database.FeedFollow, error) {
+    for i, follow := range f.follows {
+        if follow.UserID != arg.UserID || follow.FeedID != arg.FeedID {
+            continue
+        }
+        if arg.Title.Valid {
+            f.follows[i].Title = sql.NullString{ String: arg.Title.String, Valid: arg.Title.String != "" }
+        }
+        if arg.Muted.Valid {
+            f.follows[i].Muted = arg.Muted.Bool
+        }
+        if arg.Priority.Valid {
+            f.follows[i].Priority = arg.Priority.Int32
+        }
+        if arg.Notify.Valid {
+            f.follows[i].Notify = arg.Notify.Bool
+        }
+        f.follows[i].UpdatedAt = time.Now()
+        return f.follows[i], nil
+    }
+    return database.FeedFollow{}, sql.ErrNoRows
+}
+
+// Archives
+
+func (f *fakeStore) CreateArchiveBlob(ctx context.Context, arg database.CreateArchiveBlobParams) error {
+    if f.blobs == nil {
+        f.blobs = map[string][]byte{}
+    }
+    if _, ok := f.blobs[arg.Sha256]; !ok {
+        f.blobs[arg.Sha256] = arg.Data
+    }
+    return nil
+}
+
+func (f *fakeStore) CreateArchive(ctx context.Context, arg database.CreateArchiveParams) (database.Archive, error) {
+    for _, archive := range f.archives {
+        if archive.PostID == arg.PostID {
+            return database.Archive{}, errFakeDuplicate
+        }
+    }
+    archive := database.Archive{ ID: arg.ID, PostID: arg.PostID, Url: arg.Url, ArchivedAt: arg.ArchivedAt, Error: arg.Error }
+    f.archives = append(f.archives, archive)
+    return archive, nil
+}
+
+func (f *fakeStore) CreateArchiveResource(ctx context.Context, arg database.CreateArchiveResourceParams) error {
+    if _, ok := f.blobs[arg.Sha256]; !ok {
+        return errFakeForeignKey
+    }
+    for _, resource := range f.archived {
+        if resource.ArchiveID == arg.ArchiveID && resource.Url == arg.Url {
+            return nil
+        }
+    }
+    f.archived = append(f.archived, database.ArchiveResource{ ArchiveID: arg.ArchiveID, Url: arg.Url, Status: arg.Status, ContentType: arg.ContentType,
+                                                              Sha256: arg.Sha256, FetchedAt: arg.FetchedAt })
+    return nil
+}
+
+func (f *fakeStore) DeleteArchiveForPost(ctx context.Context, postID uuid.UUID) error {
+    f.deleteArchive(postID)
+    return nil
+}
+
+func (f *fakeStore) DeleteUnusedArchiveBlobs(ctx context.Context) (int64, error) {
+    n := int64(0)
+    for sum := range f.blobs {
+        if !slices.ContainsFunc(f.archived, func(resource database.ArchiveResource) bool { return resource.Sha256 == sum }) {
+            delete(f.blobs, sum)
+            n++
+        }
+    }
+    return n, nil
+}
+
+func (f *fakeStore) deleteArchive(postID uuid.UUID) {
+    for _, archive := range f.archives {
+        if archive.PostID == postID {
+            f.archived = slices.DeleteFunc(f.archived, func(resource database.ArchiveResource) bool { return resource.ArchiveID == archive.ID })
+        }
+    }
+    f.archives = slices.DeleteFunc(f.archives, func(archive database.Archive) bool { return archive.PostID == postID })
+}
+
+func (f *fakeStore) GetPostsToArchive(ctx context.Context, limit int32) ([]database.GetPostsToArchiveRow, error) {
+    rows := []database.GetPostsToArchiveRow{}
+    for _, post := range f.posts {
+        feed, _ := f.feed(post.FeedID)
+        starred := slices.ContainsFunc(f.stars, func(star database.PostStar) bool { return star.PostID == post.ID })
+        done    := slices.ContainsFunc(f.archives, func(archive database.Archive) bool { return archive.PostID == post.ID })
+        if (feed.Archive || starred) && !done && len(rows) < int(limit) {
+            rows = append(rows, database.GetPostsToArchiveRow{ ID: post.ID, Url: post.Url, FeedUrl: feed.Url })
+        }
+    }
+    return rows, nil
+}
+
+func (f *fakeStore) GetPostToArchiveByUrl(ctx context.Context, arg database.GetPostToArchiveByUrlParams) (database.GetPostToArchiveByUrlRow, error) {
+    for _, post := range f.posts {
+        _, following := f.follow(arg.UserID, post.FeedID)
+        if post.Url == arg.Url && (following || f.isStarred(arg.UserID, post.ID)) {
+            feed, _ := f.feed(post.FeedID)
+            return database.GetPostToArchiveByUrlRow{ ID: post.ID, Url: post.Url, FeedUrl: feed.Url }, nil
+        }
+    }
+    return database.GetPostToArchiveByUrlRow{}, sql.ErrNoRows
+}
+
+func (f *fakeStore) GetArchivesForUser(ctx context.Context, userID uuid.UUID) ([]database.GetArchivesForUserRow, error) {
+    rows := []database.GetArchivesForUserRow{}
+    for _, archive := range f.archives {
+        post, _ := f.post(archive.PostID)
+        _, following := f.follow(userID, post.FeedID)
+        if following || f.isStarred(userID, post.ID) {
+            rows = append(rows, database.GetArchivesForUserRow{ ID: archive.ID, PostID: archive.PostID, Url: archive.Url, ArchivedAt: archive.ArchivedAt,
+                                                                Error: archive.Error, Title: post.Title })
+        }
+    }
+    sort.SliceStable(rows, func(i, j int) bool { return rows[i].ArchivedAt.After(rows[j].ArchivedAt) })
+    return rows, nil
+}
+
+func (f *fakeStore) GetArchiveForPostUrl(ctx context.Context, arg database.GetArchiveForPostUrlParams) (database.GetArchiveForPostUrlRow, error) {
+    for _, archive := range f.archives {
+        post, _ := f.post(archive.PostID)
+        _, following := f.follow(arg.UserID, post.FeedID)
+        if post.Url == arg.Url && (following || f.isStarred(arg.UserID, post.ID)) {
+            return database.GetArchiveForPostUrlRow{ ID: archive.ID, PostID: archive.PostID, Url: archive.Url, ArchivedAt: archive.ArchivedAt,
+                                                     Error: archive.Error, Title: post.Title }, nil
+        }
+    }
+    return database.GetArchiveForPostUrlRow{}, sql.ErrNoRows
+}
+
+func (f *fakeStore) GetArchiveResources(ctx context.Context, archiveID uuid.UUID) ([]database.GetArchiveResourcesRow, error) {
+    rows := []database.GetArchiveResourcesRow{}
+    for _, resource := range f.archived {
+        if resource.ArchiveID == archiveID {
+            rows = append(rows, database.GetArchiveResourcesRow{ Url: resource.Url, Status: resource.Status, ContentType: resource.ContentType,
+                                                                 Sha256: resource.Sha256, FetchedAt: resource.FetchedAt, Data: f.blobs[resource.Sha256] })
+        }
+    }
+    return rows, nil
+}
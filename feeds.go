@@ -0,0 +1,347 @@
+package main
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "flag"
+    "fmt"
+    "internal/database"
+    "net/url"
+    "os"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+func feedTransfer(s *state, cmd command, current database.User) error {
+    feed, err := resolveFeed(s, cmd.args[0])
+    if err != nil {
+        return err
+    }
+    if feed.UserID != current.ID && current.Role != roleAdmin {
+        return fmt.Errorf("%w | Reason: only the feed's owner or an admin can transfer it", ErrorNotFeedOwner)
+    }
+
+    newOwner, err := s.dbState.GetUser(context.Background(), cmd.args[1])
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorGettingUser, err)
+    }
+
+    err = s.dbState.SetFeedOwner(context.Background(), database.SetFeedOwnerParams{ ID: feed.ID, UserID: newOwner.ID })
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorTransferringFeed, err)
+    }
+
+    fmt.Printf("Feed %v now belongs to %v\n", feed.Name, newOwner.Name)
+    return nil
+}
+
+// feedSettings changes settings that apply to a feed for everyone, only the flags given are changed
+func feedSettings(s *state, cmd command, current database.User) error {
+    feed, err := resolveFeed(s, cmd.args[0])
+    if err != nil {
+        return err
+    }
+
+    params := database.UpdateFeedSettingsParams{ ID: feed.ID }
+    cmd.flags.Visit(func(f *flag.Flag) {
+        switch f.Name {
+        case "full-text":
+            params.FullText = sql.NullBool{ Bool: cmd.flagBool("full-text"), Valid: true }
+        case "archive":
+            params.Archive  = sql.NullBool{ Bool: cmd.flagBool("archive"),   Valid: true }
+        }
+    })
+
+    if params.FullText.Valid || params.Archive.Valid {
+        if feed.UserID != current.ID && current.Role != roleAdmin {
+            return fmt.Errorf("%w | Reason: only the feed's owner or an admin can change its settings", ErrorNotFeedOwner)
+        }
+        feed, err = s.dbState.UpdateFeedSettings(context.Background(), params)
+        if err != nil {
+            return fmt.Errorf("%w | Reason: %w", ErrorUpdatingFeed, err)
+        }
+    }
+
+    fmt.Println("Feed Name:    ", feed.Name)
+    fmt.Println("Full Text:    ", feed.FullText)
+    fmt.Println("Archive:      ", feed.Archive)
+    return nil
+}
+
+// feedDelete deletes a feed nobody else follows.  If others do follow it, the owner stops
+// following it and ownership passes to the longest standing follower instead.
+func feedDelete(s *state, cmd command, current database.User) error {
+    force := cmd.flagBool("force")
+
+    feed, err := resolveFeed(s, cmd.args[0])
+    if err != nil {
+        return err
+    }
+    if feed.UserID != current.ID && current.Role != roleAdmin {
+        return fmt.Errorf("%w | Reason: only the feed's owner or an admin can delete it", ErrorNotFeedOwner)
+    }
+    if force && current.Role != roleAdmin {
+        return fmt.Errorf("%w | Reason: only admins can use --force", ErrorNotAdmin)
+    }
+
+    ctx := context.Background()
+    qtx, tx, err := s.dbState.begin(ctx)
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorDeletingFeeds, err)
+    }
+    defer tx.Rollback()
+
+    if !force {
+        handedOver, err := handOverFeed(ctx, qtx, feed)
+        if err != nil {
+            return err
+        }
+        if handedOver {
+            _, err = qtx.DeleteFeedFollowsForUserUrl(ctx, database.DeleteFeedFollowsForUserUrlParams{ UserID: feed.UserID, FeedID: feed.ID })
+            if err != nil {
+                return fmt.Errorf("%w | Reason: %w", ErrorDeletingFeedFollows, err)
+            }
+            if err = tx.Commit(); err != nil {
+                return fmt.Errorf("%w | Reason: %w", ErrorTransferringFeed, err)
+            }
+            fmt.Printf("Feed %v has other followers, so it was handed to one of them and its owner unfollowed it\n", feed.Name)
+            return nil
+        }
+    }
+
+    _, err = qtx.DeleteFeed(ctx, feed.ID)
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorDeletingFeeds, err)
+    }
+    if err = tx.Commit(); err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorDeletingFeeds, err)
+    }
+
+    fmt.Printf("Feed %v deleted\n", feed.Name)
+    return nil
+}
+
+// resolveFeed finds a feed from whatever the user typed: a url (any spelling of it), a feed name,
+// a short id prefix as shown by the feeds command, or failing all that a fuzzy pick.
+func resolveFeed(s *state, arg string) (database.Feed, error) {
+    ctx := context.Background()
+
+    if strings.Contains(arg, "://") || strings.Contains(arg, ".") {
+        feed, err := feedByURL(ctx, s.dbState, arg)
+        if err == nil {
+            return feed, nil
+        }
+        if !errors.Is(err, sql.ErrNoRows) {
+            return database.Feed{}, fmt.Errorf("%w | Reason: %w", ErrorGettingFeed, err)
+        }
+    }
+
+    feeds, err := s.dbState.GetFeedsByName(ctx, arg)
+    if err != nil {
+        return database.Feed{}, fmt.Errorf("%w | Reason: %w", ErrorGettingFeed, err)
+    }
+    if len(feeds) == 1 {
+        return feeds[0], nil
+    }
+    if len(feeds) > 1 {
+        return pickFeed(feeds, arg)
+    }
+
+    if isIDPrefix(arg) {
+        feeds, err = s.dbState.GetFeedsByIDPrefix(ctx, strings.ToLower(arg))
+        if err != nil {
+            return database.Feed{}, fmt.Errorf("%w | Reason: %w", ErrorGettingFeed, err)
+        }
+        if len(feeds) == 1 {
+            return feeds[0], nil
+        }
+        if len(feeds) > 1 {
+            return pickFeed(feeds, arg)
+        }
+    }
+
+    // Nothing exact, fall back to fuzzy matching names and urls
+    all, err := s.dbState.GetFeedsWithName(ctx)
+    if err != nil {
+        return database.Feed{}, fmt.Errorf("%w | Reason: %w", ErrorGettingFeeds, err)
+    }
+
+    type match struct {
+        feed  database.GetFeedsWithNameRow
+        score int
+    }
+    matches := []match{}
+    for _, feed := range all {
+        score := max(fuzzyScore(arg, feed.Name), fuzzyScore(arg, feed.Url))
+        if score > 0 {
+            matches = append(matches, match{ feed: feed, score: score })
+        }
+    }
+    if len(matches) == 0 {
+        return database.Feed{}, fmt.Errorf("%w | Reason: no feed matches %q", ErrorGettingFeed, arg)
+    }
+    sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+
+    feeds = []database.Feed{}
+    for _, m := range matches {
+        feeds = append(feeds, database.Feed{ ID: m.feed.ID, Name: m.feed.Name, Url: m.feed.Url })
+    }
+    picked := feeds[0]
+    if len(feeds) > 1 {
+        picked, err = pickFeed(feeds, arg)
+        if err != nil {
+            return database.Feed{}, err
+        }
+    }
+
+    feed, err := s.dbState.GetFeedUrl(ctx, picked.Url)
+    if err != nil {
+        return database.Feed{}, fmt.Errorf("%w | Reason: %w", ErrorGettingFeed, err)
+    }
+    return feed, nil
+}
+
+// pickFeed asks the user to choose between several feeds.  Without a terminal to ask on,
+// the choices are listed in the error instead.
+func pickFeed(feeds []database.Feed, arg string) (database.Feed, error) {
+    choices := []string{}
+    for i, feed := range feeds {
+        choices = append(choices, fmt.Sprintf("%3d) %v | %v | %v", i + 1, shortID(feed), feed.Name, feed.Url))
+    }
+
+    if !stdinIsTerminal() {
+        return database.Feed{}, fmt.Errorf("%w | Reason: %q matches more than one feed, be more specific:\n%v", ErrorGettingFeed, arg, strings.Join(choices, "\n"))
+    }
+
+    fmt.Printf("Feeds matching %q:\n", arg)
+    for _, choice := range choices {
+        fmt.Println(choice)
+    }
+    fmt.Print("Pick a feed (number, empty to cancel): ")
+
+    line, err := stdinReader.ReadString('\n')
+    if err != nil && line == "" {
+        return database.Feed{}, fmt.Errorf("%w | Reason: %w", ErrorGettingFeed, err)
+    }
+    line = strings.TrimSpace(line)
+    if line == "" {
+        return database.Feed{}, fmt.Errorf("%w | Reason: no feed picked", ErrorGettingFeed)
+    }
+
+    n, err := strconv.Atoi(line)
+    if err != nil || n < 1 || n > len(feeds) {
+        return database.Feed{}, fmt.Errorf("%w | Reason: %q is not one of the choices", ErrorGettingFeed, line)
+    }
+    return feeds[n - 1], nil
+}
+
+// normalizeFeedURL gives one spelling for equivalent urls: lowercase scheme and host,
+// no default port, no fragment and no trailing slash.  A missing scheme means https.
+// It is only for comparing urls, feeds are stored and fetched at the url as given.
+func normalizeFeedURL(raw string) (string, error) {
+    raw = strings.TrimSpace(raw)
+    if !strings.Contains(raw, "://") {
+        raw = "https://" + raw
+    }
+
+    u, err := url.Parse(raw)
+    if err != nil {
+        return "", err
+    }
+    if u.Host == "" {
+        return "", fmt.Errorf("url %q has no host", raw)
+    }
+
+    u.Scheme   = strings.ToLower(u.Scheme)
+    u.Host     = strings.ToLower(u.Host)
+    u.Fragment = ""
+    if (u.Scheme == "http" && u.Port() == "80") || (u.Scheme == "https" && u.Port() == "443") {
+        u.Host = u.Hostname()
+    }
+    u.Path    = strings.TrimRight(u.Path, "/")
+    u.RawPath = ""
+
+    return u.String(), nil
+}
+
+// feedURLKey is what two urls for the same feed have in common: the normalised url without its
+// scheme, as feeds are often served over both http and https
+func feedURLKey(raw string) (string, error) {
+    normalized, err := normalizeFeedURL(raw)
+    if err != nil {
+        return "", err
+    }
+    _, key, _ := strings.Cut(normalized, "://")
+    return key, nil
+}
+
+// feedByURL finds the feed stored under any spelling of a url, sql.ErrNoRows when there is none
+func feedByURL(ctx context.Context, q database.Querier, raw string) (database.Feed, error) {
+    feed, err := q.GetFeedUrl(ctx, strings.TrimSpace(raw))
+    if !errors.Is(err, sql.ErrNoRows) {
+        return feed, err
+    }
+
+    key, err := feedURLKey(raw)
+    if err != nil {
+        return database.Feed{}, sql.ErrNoRows
+    }
+    feeds, err := q.GetFeeds(ctx)
+    if err != nil {
+        return database.Feed{}, err
+    }
+    for _, feed := range feeds {
+        if feedKey, err := feedURLKey(feed.Url); err == nil && feedKey == key {
+            return q.GetFeedUrl(ctx, feed.Url)
+        }
+    }
+    return database.Feed{}, sql.ErrNoRows
+}
+
+// fuzzyScore is 0 for no match, higher for better: substring matches beat in-order letter matches
+func fuzzyScore(pattern, target string) int {
+    pattern = strings.ToLower(pattern)
+    target  = strings.ToLower(target)
+
+    if strings.Contains(target, pattern) {
+        return 2
+    }
+
+    runes := []rune(pattern)
+    i := 0
+    for _, r := range target {
+        if i < len(runes) && r == runes[i] {
+            i++
+        }
+    }
+    if i == len(runes) {
+        return 1
+    }
+    return 0
+}
+
+func isIDPrefix(arg string) bool {
+    if len(arg) < 4 {
+        return false
+    }
+    for _, r := range strings.ToLower(arg) {
+        if !strings.ContainsRune("0123456789abcdef-", r) {
+            return false
+        }
+    }
+    return true
+}
+
+func shortID(feed database.Feed) string {
+    return feed.ID.String()[:8]
+}
+
+func stdinIsTerminal() bool {
+    stat, err := os.Stdin.Stat()
+    if err != nil {
+        return false
+    }
+    return stat.Mode() & os.ModeCharDevice != 0
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+    "context"
+    "internal/database"
+    "testing"
+)
+
+func TestFeedTransfer(t *testing.T) {
+    eachStore(t, func(t *testing.T, s *state, q database.Querier) {
+        alice := loginAs(t, s, q, "alice", roleUser)
+        bob   := addUser(t, q, "bob", roleUser)
+        feed  := addFeed(t, q, alice, "Blog", "https://example.com/feed")
+
+        out := run(t, s, "feed", "transfer", "Blog", "bob")
+        assertContains(t, out, "Feed Blog now belongs to bob")
+        feed, _ = q.GetFeedUrl(context.Background(), feed.Url)
+        if feed.UserID != bob.ID {
+            t.Fatal("feed was not transferred")
+        }
+
+        // No longer the owner, so no transferring it back
+        assertErr(t, runErr(s, "feed", "transfer", "Blog", "alice"), ErrorNotFeedOwner)
+
+        // Admins can transfer anyone's feed
+        loginAs(t, s, q, "admin", roleAdmin)
+        run(t, s, "feed", "transfer", "Blog", "alice")
+        feed, _ = q.GetFeedUrl(context.Background(), feed.Url)
+        if feed.UserID != alice.ID {
+            t.Error("admin transfer did not happen")
+        }
+
+        assertErr(t, runErr(s, "feed", "transfer", "Blog", "nobody"), ErrorGettingUser)
+        assertErr(t, runErr(s, "feed", "transfer", "Blog"), NotEnoughArgs)
+        assertErr(t, runErr(s, "feed", "rename"), NoCommandExists)
+        assertErr(t, runErr(s, "feed"), EmptyArgList)
+    })
+}
+
+func TestFeedDelete(t *testing.T) {
+    t.Run("nobody else follows", func(t *testing.T) {
+        s, fake := newTestState(t)
+        alice := loginAs(t, s, fake, "alice", roleUser)
+        feed  := addFeed(t, fake, alice, "Blog", "https://example.com/feed")
+        addPost(t, fake, feed, "Post", "https://example.com/1", "", 0)
+
+        out := run(t, s, "feed", "delete", "Blog")
+        assertContains(t, out, "Feed Blog deleted")
+        if len(fake.feeds) != 0 || len(fake.posts) != 0 {
+            t.Error("feed or its posts were not deleted")
+        }
+    })
+
+    t.Run("hands over to a follower", func(t *testing.T) {
+        s, fake := newTestState(t)
+        alice := loginAs(t, s, fake, "alice", roleUser)
+        bob   := addUser(t, fake, "bob", roleUser)
+        feed  := addFeed(t, fake, alice, "Blog", "https://example.com/feed")
+        follow(t, fake, bob, feed)
+
+        out := run(t, s, "feed", "delete", "Blog")
+        assertContains(t, out, "handed to one of them")
+        feed, err := fake.GetFeedUrl(context.Background(), feed.Url)
+        if err != nil || feed.UserID != bob.ID {
+            t.Fatalf("feed owner = %v, %v; want bob", feed.UserID, err)
+        }
+        if _, ok := fake.follow(alice.ID, feed.ID); ok {
+            t.Error("old owner still follows the feed")
+        }
+    })
+
+    t.Run("only owners and admins", func(t *testing.T) {
+        s, fake := newTestState(t)
+        bob := addUser(t, fake, "bob", roleUser)
+        addFeed(t, fake, bob, "Blog", "https://example.com/feed")
+        loginAs(t, s, fake, "alice", roleUser)
+
+        assertErr(t, runErr(s, "feed", "delete", "Blog"), ErrorNotFeedOwner)
+    })
+
+    t.Run("force is for admins", func(t *testing.T) {
+        s, fake := newTestState(t)
+        alice := loginAs(t, s, fake, "alice", roleUser)
+        bob   := addUser(t, fake, "bob", roleUser)
+        feed  := addFeed(t, fake, alice, "Blog", "https://example.com/feed")
+        follow(t, fake, bob, feed)
+
+        assertErr(t, runErr(s, "feed", "delete", "--force", "Blog"), ErrorNotAdmin)
+
+        loginAs(t, s, fake, "admin", roleAdmin)
+        run(t, s, "feed", "delete", "--force", "Blog")
+        if len(fake.feeds) != 0 {
+            t.Error("forced delete left the feed")
+        }
+    })
+}
+
+func TestResolveFeed(t *testing.T) {
+    eachStore(t, func(t *testing.T, s *state, q database.Querier) {
+        alice := addUser(t, q, "alice", roleUser)
+        blog  := addFeed(t, q, alice, "Blog",    "https://blog.example.com/rss")
+        addFeed(t, q, alice, "Tech News",  "https://tech.example.com/rss")
+        addFeed(t, q, alice, "World News", "https://world.example.com/rss")
+
+        for _, arg := range []string{ "Blog", "https://blog.example.com/rss", "http://BLOG.example.com/rss/", "blog.example.com/rss", shortID(blog), "blg" } {
+            feed, err := resolveFeed(s, arg)
+            if err != nil || feed.ID != blog.ID {
+                t.Errorf("resolveFeed(%q) = %v, %v; want Blog", arg, feed.Name, err)
+            }
+        }
+
+        // A fuzzy match is taken when it is the only one, several are listed with no terminal to pick on
+        if feed, err := resolveFeed(s, "tech"); err != nil || feed.Name != "Tech News" {
+            t.Errorf("resolveFeed(tech) = %v, %v; want Tech News", feed.Name, err)
+        }
+
+        _, err := resolveFeed(s, "news")
+        assertErr(t, err, ErrorGettingFeed)
+        assertContains(t, err.Error(), "Tech News", "World News")
+
+        _, err = resolveFeed(s, "zzz")
+        assertErr(t, err, ErrorGettingFeed)
+    })
+}
+
+func TestNormalizeFeedURL(t *testing.T) {
+    tests := []struct {
+        in   string
+        want string
+    }{
+        { "https://example.com/feed",          "https://example.com/feed" },
+        { "HTTPS://Example.COM/feed/",         "https://example.com/feed" },
+        { "example.com/feed",                  "https://example.com/feed" },
+        { "http://example.com:80/feed",        "http://example.com/feed" },
+        { "https://example.com:8443/feed",     "https://example.com:8443/feed" },
+        { "https://example.com/feed#comments", "https://example.com/feed" },
+        { "https://example.com/feed?format=rss", "https://example.com/feed?format=rss" },
+    }
+    for _, tt := range tests {
+        got, err := normalizeFeedURL(tt.in)
+        if err != nil || got != tt.want {
+            t.Errorf("normalizeFeedURL(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+        }
+    }
+
+    if _, err := normalizeFeedURL("https:///feed"); err == nil {
+        t.Error("expected an error for a url without a host")
+    }
+}
+
+func TestFuzzyScore(t *testing.T) {
+    tests := []struct {
+        pattern string
+        target  string
+        want    int
+    }{
+        { "news",  "Tech News",  2 },
+        { "tcnws", "Tech News",  1 },
+        { "swen",  "Tech News",  0 },
+    }
+    for _, tt := range tests {
+        if got := fuzzyScore(tt.pattern, tt.target); got != tt.want {
+            t.Errorf("fuzzyScore(%q, %q) = %v, want %v", tt.pattern, tt.target, got, tt.want)
+        }
+    }
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "internal/database"
+    "sort"
+    "strings"
+    "time"
+    "github.com/google/uuid"
+)
+
+func folderCreate(s *state, cmd command, user database.User) error {
+    parentID, err := folderIDArg(s, user, cmd.flagString("parent"))
+    if err != nil {
+        return err
+    }
+
+    folder, err := s.dbState.CreateFolder(context.Background(), database.CreateFolderParams{ ID:     uuid.New(), CreatedAt: time.Now(),  UpdatedAt: time.Now(),
+                                                                                           UserID: user.ID,    Name:      cmd.args[0], ParentID:  parentID, })
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorCreatingFolder, err)
+    }
+
+    fmt.Printf("Folder %v created\n", folder.Name)
+    return nil
+}
+
+func folderRename(s *state, cmd command, user database.User) error {
+    n, err := s.dbState.RenameFolder(context.Background(), database.RenameFolderParams{ UserID: user.ID, OldName: cmd.args[0], NewName: cmd.args[1] })
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorUpdatingFolder, err)
+    }
+    if n == 0 {
+        return fmt.Errorf("%w | Reason: no folder named %v", ErrorGettingFolder, cmd.args[0])
+    }
+
+    fmt.Printf("Folder %v renamed to %v\n", cmd.args[0], cmd.args[1])
+    return nil
+}
+
+func folderMove(s *state, cmd command, user database.User) error {
+    feed, err := resolveFeed(s, cmd.args[0])
+    if err != nil {
+        return err
+    }
+
+    folderName := cmd.args[1]
+    if folderName == "-" {
+        folderName = ""
+    }
+    folderID, err := folderIDArg(s, user, folderName)
+    if err != nil {
+        return err
+    }
+
+    n, err := s.dbState.SetFeedFollowFolder(context.Background(), database.SetFeedFollowFolderParams{ UserID: user.ID, FeedID: feed.ID, FolderID: folderID })
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorUpdatingFolder, err)
+    }
+    if n == 0 {
+        return fmt.Errorf("%w | Reason: %v is not following %v", ErrorNotFollowing, user.Name, feed.Name)
+    }
+
+    if folderName == "" {
+        fmt.Printf("Feed %v moved out of its folder\n", feed.Name)
+        return nil
+    }
+    fmt.Printf("Feed %v moved to %v\n", feed.Name, folderName)
+    return nil
+}
+
+func folderList(s *state, cmd command, user database.User) error {
+    folders, err := s.dbState.GetFoldersForUser(context.Background(), user.ID)
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorGettingFolder, err)
+    }
+
+    paths := folderPaths(folders)
+    list  := make([]string, 0, len(paths))
+    for _, path := range paths {
+        list = append(list, path)
+    }
+    sort.Strings(list)
+
+    for _, path := range list {
+        fmt.Println("*", path)
+    }
+    return nil
+}
+
+// Helpers
+
+// folderIDArg looks up a folder by name for the user.  An empty name means no folder.
+func folderIDArg(s *state, user database.User, name string) (uuid.NullUUID, error) {
+    if name == "" {
+        return uuid.NullUUID{}, nil
+    }
+
+    folder, err := s.dbState.GetFolderByName(context.Background(), database.GetFolderByNameParams{ UserID: user.ID, Name: name })
+    if err != nil {
+        return uuid.NullUUID{}, fmt.Errorf("%w | Reason: %w", ErrorGettingFolder, err)
+    }
+    return uuid.NullUUID{ UUID: folder.ID, Valid: true }, nil
+}
+
+// folderPaths maps each folder id to its full path, ex: News/Tech
+func folderPaths(folders []database.Folder) map[uuid.UUID]string {
+    byID := map[uuid.UUID]database.Folder{}
+    for _, folder := range folders {
+        byID[folder.ID] = folder
+    }
+
+    paths := map[uuid.UUID]string{}
+    for _, folder := range folders {
+        parts := []string{ folder.Name }
+        seen  := map[uuid.UUID]bool{ folder.ID: true }
+        for cur := folder; cur.ParentID.Valid && !seen[cur.ParentID.UUID]; {
+            cur = byID[cur.ParentID.UUID]
+            seen[cur.ID] = true
+            parts = append([]string{ cur.Name }, parts...)
+        }
+        paths[folder.ID] = strings.Join(parts, "/")
+    }
+    return paths
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+    "testing"
+    "github.com/google/uuid"
+    "internal/database"
+)
+
+func TestHandlerFolder(t *testing.T) {
+    s, fake := newTestState(t)
+    alice := loginAs(t, s, fake, "alice", roleUser)
+    feed  := addFeed(t, fake, alice, "Blog", "https://example.com/feed")
+
+    run(t, s, "folder", "create", "News")
+    run(t, s, "folder", "create", "--parent", "News", "Tech")
+    assertErr(t, runErr(s, "folder", "create", "News"), ErrorCreatingFolder)
+    assertErr(t, runErr(s, "folder", "create", "--parent", "Nope", "Sub"), ErrorGettingFolder)
+
+    out := run(t, s, "folder", "list")
+    assertContains(t, out, "* News\n* News/Tech\n")
+
+    run(t, s, "folder", "rename", "Tech", "Technology")
+    out = run(t, s, "folder", "list")
+    assertContains(t, out, "* News/Technology\n")
+    assertErr(t, runErr(s, "folder", "rename", "Tech", "Other"), ErrorGettingFolder)
+
+    out = run(t, s, "folder", "move", "Blog", "Technology")
+    assertContains(t, out, "Feed Blog moved to Technology")
+    follow, _ := fake.follow(alice.ID, feed.ID)
+    if !follow.FolderID.Valid {
+        t.Fatal("feed was not moved into the folder")
+    }
+
+    out = run(t, s, "folder", "move", "Blog", "-")
+    assertContains(t, out, "moved out of its folder")
+    follow, _ = fake.follow(alice.ID, feed.ID)
+    if follow.FolderID.Valid {
+        t.Error("feed is still in a folder")
+    }
+
+    // Only feeds you follow can be filed
+    bob := addUser(t, fake, "bob", roleUser)
+    addFeed(t, fake, bob, "Other", "https://other.example.com/feed")
+    assertErr(t, runErr(s, "folder", "move", "Other", "News"), ErrorNotFollowing)
+
+    assertErr(t, runErr(s, "folder", "move", "Blog"), NotEnoughArgs)
+    assertErr(t, runErr(s, "folder", "delete", "News"), NoCommandExists)
+    assertErr(t, runErr(s, "folder"), EmptyArgList)
+}
+
+func TestFolderPaths(t *testing.T) {
+    root  := database.Folder{ ID: uuid.New(), Name: "A" }
+    child := database.Folder{ ID: uuid.New(), Name: "B", ParentID: uuid.NullUUID{ UUID: root.ID,  Valid: true } }
+    leaf  := database.Folder{ ID: uuid.New(), Name: "C", ParentID: uuid.NullUUID{ UUID: child.ID, Valid: true } }
+
+    // A parent cycle must not loop forever
+    loopA := database.Folder{ ID: uuid.New(), Name: "X" }
+    loopB := database.Folder{ ID: uuid.New(), Name: "Y", ParentID: uuid.NullUUID{ UUID: loopA.ID, Valid: true } }
+    loopA.ParentID = uuid.NullUUID{ UUID: loopB.ID, Valid: true }
+
+    paths := folderPaths([]database.Folder{ leaf, child, root, loopA, loopB })
+    if paths[leaf.ID] != "A/B/C" || paths[child.ID] != "A/B" || paths[root.ID] != "A" {
+        t.Errorf("paths = %v", paths)
+    }
+    if paths[loopA.ID] != "Y/X" || paths[loopB.ID] != "X/Y" {
+        t.Errorf("cycle paths = %v, %v", paths[loopA.ID], paths[loopB.ID])
+    }
+}
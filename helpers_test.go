@@ -0,0 +1,200 @@
+package main
+
+import (
+    "bufio"
+    "context"
+    "database/sql"
+    "errors"
+    "flag"
+    "internal/config"
+    "internal/database"
+    "io"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+    "github.com/google/uuid"
+)
+
+func TestMain(m *testing.M) {
+    // Handlers ask for passwords and confirmations on a terminal.  A closed pipe on stdin makes them
+    // take the non-interactive paths, and tests feed answers through stdinReader instead.
+    r, w, err := os.Pipe()
+    if err != nil {
+        panic(err)
+    }
+    w.Close()
+    os.Stdin = r
+
+    os.Exit(m.Run())
+}
+
+// newTestState gives a state on an empty fake store, with its config file in a temp dir
+func newTestState(t *testing.T) (*state, *fakeStore) {
+    t.Helper()
+    config.SetPath(filepath.Join(t.TempDir(), "config.json"))
+    t.Cleanup(func() { config.SetPath("") })
+
+    cfg   := config.Config{ ProfileName: "default" }
+    fake  := newFakeStore()
+    return &state{ cfgState: &cfg, dbState: fake }, fake
+}
+
+// newSQLiteState gives a state on a new SQLite database, migrated up, with its config file in a temp dir
+func newSQLiteState(t *testing.T) (*state, store) {
+    t.Helper()
+    s, _ := newTestState(t)
+    st, db, err := openStore("sqlite://" + filepath.Join(t.TempDir(), "gator.db"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { db.Close() })
+
+    s.dbState, s.dbConn = st, db
+    run(t, s, "migrate", "up")
+    return s, st
+}
+
+// eachStore runs a test on the fake store and again on SQLite, for tests that only go through
+// commands and queries, so both are held to the same behaviour
+func eachStore(t *testing.T, test func(t *testing.T, s *state, q database.Querier)) {
+    t.Run("fake", func(t *testing.T) {
+        s, fake := newTestState(t)
+        test(t, s, fake)
+    })
+    t.Run("sqlite", func(t *testing.T) {
+        s, st := newSQLiteState(t)
+        test(t, s, st)
+    })
+}
+
+// withInput makes the next prompts read these lines
+func withInput(t *testing.T, lines ...string) {
+    t.Helper()
+    old := stdinReader
+    stdinReader = bufio.NewReader(strings.NewReader(strings.Join(lines, "\n") + "\n"))
+    t.Cleanup(func() { stdinReader = old })
+}
+
+// capture runs fn and returns what it printed to stdout
+func capture(fn func() error) (string, error) {
+    r, w, err := os.Pipe()
+    if err != nil {
+        return "", err
+    }
+
+    old := os.Stdout
+    os.Stdout = w
+    out := make(chan string)
+    go func() {
+        data, _ := io.ReadAll(r)
+        out <- string(data)
+    }()
+
+    err = fn()
+    w.Close()
+    os.Stdout = old
+    return <-out, err
+}
+
+// run runs a command line through the registry, failing the test on error, and returns its output
+func run(t *testing.T, s *state, args ...string) string {
+    t.Helper()
+    out, err := capture(func() error { return runArgs(s, args) })
+    if err != nil {
+        t.Fatalf("unexpected error: %v\noutput:\n%v", err, out)
+    }
+    return out
+}
+
+// runErr runs a command line quietly and returns its error
+func runErr(s *state, args ...string) error {
+    _, err := capture(func() error { return runArgs(s, args) })
+    return err
+}
+
+func runArgs(s *state, args []string) error {
+    coms := newCommands(flag.NewFlagSet("gator", flag.ContinueOnError))
+    cmd, err := coms.parse(args)
+    if err != nil {
+        return err
+    }
+    return coms.run(s, cmd)
+}
+
+// assertErr checks err wraps the sentinel want
+func assertErr(t *testing.T, err, want error) {
+    t.Helper()
+    if err == nil {
+        t.Fatalf("expected error %q, got nil", want)
+    }
+    if !errors.Is(err, want) {
+        t.Fatalf("expected error %q, got %q", want, err)
+    }
+}
+
+func assertContains(t *testing.T, out string, wants ...string) {
+    t.Helper()
+    for _, want := range wants {
+        if !strings.Contains(out, want) {
+            t.Fatalf("expected output to contain %q, got:\n%v", want, out)
+        }
+    }
+}
+
+// addUser creates a user with the password "password1"
+func addUser(t *testing.T, q database.Querier, name, role string) database.User {
+    t.Helper()
+    hash, err := hashPassword("password1")
+    if err != nil {
+        t.Fatal(err)
+    }
+    user, err := q.CreateUser(context.Background(), database.CreateUserParams{ ID: uuid.New(), CreatedAt: time.Now(), UpdatedAt: time.Now(), Name: name,
+                                                                               PasswordHash: sql.NullString{ String: hash, Valid: true }, Role: role })
+    if err != nil {
+        t.Fatal(err)
+    }
+    return user
+}
+
+// loginAs creates a user and logs the state in as them
+func loginAs(t *testing.T, s *state, q database.Querier, name, role string) database.User {
+    t.Helper()
+    user := addUser(t, q, name, role)
+    if err := newSession(s, user); err != nil {
+        t.Fatal(err)
+    }
+    return user
+}
+
+// addFeed creates a feed owned and followed by the user
+func addFeed(t *testing.T, q database.Querier, user database.User, name, url string) database.Feed {
+    t.Helper()
+    feed, err := q.CreateFeed(context.Background(), database.CreateFeedParams{ ID: uuid.New(), CreatedAt: time.Now(), UpdatedAt: time.Now(), Name: name, Url: url, UserID: user.ID })
+    if err != nil {
+        t.Fatal(err)
+    }
+    follow(t, q, user, feed)
+    return feed
+}
+
+func follow(t *testing.T, q database.Querier, user database.User, feed database.Feed) {
+    t.Helper()
+    _, err := q.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{ ID: uuid.New(), CreatedAt: time.Now(), UpdatedAt: time.Now(), UserID: user.ID, FeedID: feed.ID })
+    if err != nil {
+        t.Fatal(err)
+    }
+}
+
+// addPost creates a post published the given time ago
+func addPost(t *testing.T, q database.Querier, feed database.Feed, title, url, description string, age time.Duration) database.Post {
+    t.Helper()
+    post, err := q.CreatePost(context.Background(), database.CreatePostParams{ ID: uuid.New(), CreatedAt: time.Now(), UpdatedAt: time.Now(), Title: title, Url: url,
+                                                                               Description: sql.NullString{ String: description, Valid: true },
+                                                                               PublishedAt: sql.NullTime{ Time: time.Now().Add(-age), Valid: true }, FeedID: feed.ID })
+    if err != nil {
+        t.Fatal(err)
+    }
+    return post
+}
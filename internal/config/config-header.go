@@ -1,10 +1,35 @@
 package config
 
 const configFileName = ".gatorconfig.json"
+const xdgDirName     = "gator"
+const xdgFileName    = "config.json"
 
+const envConfigPath   = "GATOR_CONFIG"
+const envDBUrl        = "GATOR_DB_URL"
+const envSessionToken = "GATOR_SESSION_TOKEN"
+
+const defaultProfile = "default"
+
+// Profile is one database and the session used with it
+type Profile struct {
+    DBUrl        string `json:"db_url"`
+    SessionToken string `json:"session_token"`
+}
+
+// Config is the active profile.  Use SetSession to change it so it gets written back.
 type Config struct {
-    DBUrl           string `json:"db_url"`
-    CurrentUserName string `json:"current_user_name"`
+    Profile
+    ProfileName string
 }
 
+// configPathOverride is set from the --config flag
+var configPathOverride string
 
+// configFile is what is stored on disk.  db_url and session_token at the top level are
+// from before profiles existed, they are read as the default profile.
+type configFile struct {
+    DBUrl          string             `json:"db_url,omitempty"`
+    SessionToken   string             `json:"session_token,omitempty"`
+    CurrentProfile string             `json:"current_profile"`
+    Profiles       map[string]Profile `json:"profiles"`
+}
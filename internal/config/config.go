@@ -2,22 +2,92 @@ package config
 
 import (
     "encoding/json"
+    "errors"
+    "fmt"
     "os"
+    "path/filepath"
+    "sort"
 )
 
-func (cfg *Config) SetUser(username string) error {
-    cfg.CurrentUserName = username
-    err := write(*cfg)
-    return err
+// SetSession stores the session token handed out at login.  An empty token logs out.
+// Only the token is written back, the rest of the profile is kept as it is in the file, so
+// values that came from GATOR_DB_URL never end up stored in it.
+func (cfg *Config) SetSession(token string) error {
+    cfg.SessionToken = token
+
+    file, err := readFile()
+    if errors.Is(err, os.ErrNotExist) {
+        file, err = configFile{ CurrentProfile: cfg.ProfileName, Profiles: map[string]Profile{} }, nil
+    }
+    if err != nil {
+        return err
+    }
+    profile := file.Profiles[cfg.ProfileName]
+    profile.SessionToken = token
+    file.Profiles[cfg.ProfileName] = profile
+    return write(file)
+}
+
+// Profiles lists profile names in the config file along with the current one
+func Profiles() ([]string, string, error) {
+    file, err := readFile()
+    if err != nil {
+        return nil, "", err
+    }
+
+    names := []string{}
+    for name := range file.Profiles {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return names, file.CurrentProfile, nil
 }
 
-func write(cfg Config) error {
+// AddProfile adds a profile or changes its database url.  The config file is created if it does not exist yet.
+func AddProfile(name, dbURL string) error {
+    file, err := readFile()
+    if errors.Is(err, os.ErrNotExist) {
+        file, err = configFile{ CurrentProfile: name, Profiles: map[string]Profile{} }, nil
+    }
+    if err != nil {
+        return err
+    }
+
+    // A session only makes sense for the database it was made on
+    profile := file.Profiles[name]
+    if profile.DBUrl != dbURL {
+        profile = Profile{ DBUrl: dbURL }
+    }
+    file.Profiles[name] = profile
+    return write(file)
+}
+
+// UseProfile makes a profile the one used when no other is asked for
+func UseProfile(name string) error {
+    file, err := readFile()
+    if err != nil {
+        return err
+    }
+
+    if _, ok := file.Profiles[name]; !ok {
+        return fmt.Errorf("profile %q not found in config", name)
+    }
+    file.CurrentProfile = name
+    return write(file)
+}
+
+func write(file configFile) error {
     configPath, err := getConfigFilePath()
     if err != nil {
         return err
     }
 
-    data, err := json.Marshal(cfg)
+    data, err := json.MarshalIndent(file, "", "    ")
+    if err != nil {
+        return err
+    }
+
+    err = os.MkdirAll(filepath.Dir(configPath), 0700)
     if err != nil {
         return err
     }
@@ -0,0 +1,107 @@
+package config
+
+import (
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+// isolate points HOME at an empty temp dir and clears every variable that picks the config file
+func isolate(t *testing.T) string {
+    t.Helper()
+    home := t.TempDir()
+    t.Setenv("HOME", home)
+    for _, name := range []string{ "XDG_CONFIG_HOME", envConfigPath, envDBUrl, envSessionToken, "GATOR_PROFILE" } {
+        t.Setenv(name, "")
+    }
+    SetPath("")
+    t.Cleanup(func() { SetPath("") })
+    return home
+}
+
+func touch(t *testing.T, path string) {
+    t.Helper()
+    if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+        t.Fatal(err)
+    }
+    if err := os.WriteFile(path, []byte(`{"current_profile":"default","profiles":{"default":{"db_url":"postgres://file"}}}`), 0600); err != nil {
+        t.Fatal(err)
+    }
+}
+
+func TestConfigPathOrder(t *testing.T) {
+    home   := isolate(t)
+    legacy := filepath.Join(home, configFileName)
+    xdg    := filepath.Join(home, ".config", xdgDirName, xdgFileName)
+
+    steps := []struct {
+        name  string
+        setup func()
+        want  string
+    }{
+        { "nothing yet, no XDG_CONFIG_HOME", func() {},                                               legacy },
+        { "XDG_CONFIG_HOME set",             func() { t.Setenv("XDG_CONFIG_HOME", home + "/xdg") },   filepath.Join(home, "xdg", xdgDirName, xdgFileName) },
+        { "legacy file exists",              func() { touch(t, legacy) },                             legacy },
+        { "XDG file wins over legacy",       func() { t.Setenv("XDG_CONFIG_HOME", ""); touch(t, xdg) }, xdg },
+        { "GATOR_CONFIG wins over XDG",      func() { t.Setenv(envConfigPath, home + "/env.json") },  home + "/env.json" },
+        { "--config wins over GATOR_CONFIG", func() { SetPath(home + "/flag.json") },                 home + "/flag.json" },
+    }
+    for _, step := range steps {
+        step.setup()
+        if got, err := Path(); err != nil || got != step.want {
+            t.Errorf("%v: Path() = %v, %v; want %v", step.name, got, err, step.want)
+        }
+    }
+}
+
+func TestReadEnvOverrides(t *testing.T) {
+    home := isolate(t)
+    path := filepath.Join(home, "config.json")
+    SetPath(path)
+
+    // No file and no GATOR_DB_URL is an error, GATOR_DB_URL alone is enough
+    if _, err := Read(""); err == nil {
+        t.Error("expected an error without a config file")
+    }
+    t.Setenv(envDBUrl, "postgres://env")
+    t.Setenv(envSessionToken, "env-token")
+    cfg, err := Read("")
+    if err != nil || cfg.DBUrl != "postgres://env" || cfg.SessionToken != "env-token" || cfg.ProfileName != defaultProfile {
+        t.Fatalf("Read() = %+v, %v", cfg, err)
+    }
+
+    touch(t, path)
+    cfg, err = Read("")
+    if err != nil || cfg.DBUrl != "postgres://env" || cfg.SessionToken != "env-token" {
+        t.Errorf("env did not override the file: %+v, %v", cfg, err)
+    }
+
+    t.Setenv(envDBUrl, "")
+    t.Setenv(envSessionToken, "")
+    cfg, err = Read("")
+    if err != nil || cfg.DBUrl != "postgres://file" || cfg.SessionToken != "" {
+        t.Errorf("Read() = %+v, %v; want the file's profile", cfg, err)
+    }
+}
+
+func TestSetSessionKeepsFileProfile(t *testing.T) {
+    home := isolate(t)
+    path := filepath.Join(home, "config.json")
+    SetPath(path)
+    touch(t, path)
+
+    t.Setenv(envDBUrl, "postgres://env")
+    cfg, err := Read("")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := cfg.SetSession("new-token"); err != nil {
+        t.Fatal(err)
+    }
+
+    t.Setenv(envDBUrl, "")
+    cfg, err = Read("")
+    if err != nil || cfg.DBUrl != "postgres://file" || cfg.SessionToken != "new-token" {
+        t.Errorf("after SetSession the file has %+v, %v; want its own db_url and the new token", cfg, err)
+    }
+}
@@ -4,34 +4,122 @@ import (
     "os"
     "errors"
     "encoding/json"
-//    "fmt"
+    "fmt"
+    "path/filepath"
 )
 
-func Read() (Config, error) {
+// Read loads a profile from the config file.  The profile is picked from the argument,
+// then the GATOR_PROFILE environment variable, then the file's current profile.
+// GATOR_DB_URL and GATOR_SESSION_TOKEN override the profile's fields, and are enough
+// on their own when there is no config file at all (containers, CI).
+func Read(profile string) (Config, error) {
 
-    configPath, err := getConfigFilePath()
+    file, err := readFile()
+    if errors.Is(err, os.ErrNotExist) && os.Getenv(envDBUrl) != "" {
+        file, err = configFile{ CurrentProfile: defaultProfile, Profiles: map[string]Profile{ defaultProfile: {} } }, nil
+    }
     if err != nil {
         return Config{}, err
     }
 
+    if profile == "" {
+        profile = os.Getenv("GATOR_PROFILE")
+    }
+    if profile == "" {
+        profile = file.CurrentProfile
+    }
+
+    p, ok := file.Profiles[profile]
+    if !ok {
+        return Config{}, fmt.Errorf("profile %q not found in config", profile)
+    }
+
+    if dbURL := os.Getenv(envDBUrl); dbURL != "" {
+        p.DBUrl = dbURL
+    }
+    if token := os.Getenv(envSessionToken); token != "" {
+        p.SessionToken = token
+    }
+
+    return Config{ Profile: p, ProfileName: profile }, nil
+}
+
+// SetPath makes every read and write use this config file, as with the --config flag
+func SetPath(path string) {
+    configPathOverride = path
+}
+
+// Path reports which config file is used
+func Path() (string, error) {
+    return getConfigFilePath()
+}
+
+func readFile() (configFile, error) {
+    configPath, err := getConfigFilePath()
+    if err != nil {
+        return configFile{}, err
+    }
+
     data, err := os.ReadFile(configPath)
     if errors.Is(err, os.ErrNotExist) {
-        return Config{}, err
+        return configFile{}, err
     }
 
-    config := Config{}
-    err = json.Unmarshal(data, &config)
+    file := configFile{}
+    err = json.Unmarshal(data, &file)
     if err != nil {
-        return Config{}, err
+        return configFile{}, err
+    }
+
+    // Config files from before profiles become the default profile
+    if file.Profiles == nil {
+        file.Profiles = map[string]Profile{}
+    }
+    if file.DBUrl != "" || file.SessionToken != "" {
+        if _, ok := file.Profiles[defaultProfile]; !ok {
+            file.Profiles[defaultProfile] = Profile{ DBUrl: file.DBUrl, SessionToken: file.SessionToken }
+        }
+        file.DBUrl        = ""
+        file.SessionToken = ""
+    }
+    if file.CurrentProfile == "" {
+        file.CurrentProfile = defaultProfile
     }
 
-    return config, nil
+    return file, nil
 }
 
+// getConfigFilePath picks the config file, first match wins:
+//   1. the path given to SetPath (--config)
+//   2. $GATOR_CONFIG
+//   3. $XDG_CONFIG_HOME/gator/config.json (~/.config/gator/config.json), if it exists
+//   4. ~/.gatorconfig.json
+// When neither 3 nor 4 exist yet, a new file goes to 3 if XDG_CONFIG_HOME is set, else to 4.
 func getConfigFilePath() (string, error) {
+    if configPathOverride != "" {
+        return configPathOverride, nil
+    }
+    if path := os.Getenv(envConfigPath); path != "" {
+        return path, nil
+    }
+
     homePath, err := os.UserHomeDir()
     if err != nil {
         return "", err
     }
-    return homePath + "/" + configFileName, nil
+
+    xdgHome := os.Getenv("XDG_CONFIG_HOME")
+    xdgPath := filepath.Join(homePath, ".config", xdgDirName, xdgFileName)
+    if xdgHome != "" {
+        xdgPath = filepath.Join(xdgHome, xdgDirName, xdgFileName)
+    }
+    if _, err := os.Stat(xdgPath); err == nil {
+        return xdgPath, nil
+    }
+
+    legacyPath := filepath.Join(homePath, configFileName)
+    if _, err := os.Stat(legacyPath); err == nil || xdgHome == "" {
+        return legacyPath, nil
+    }
+    return xdgPath, nil
 }
@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,9 +17,9 @@ const createFeedFollow = `-- name: CreateFeedFollow :one
 WITH feed_follow_insert AS (
     INSERT INTO feed_follows ( id, created_at, updated_at, user_id, feed_id )
                       VALUES ( $1, $2,         $3,         $4,      $5      )
-    RETURNING id, created_at, updated_at, user_id, feed_id )
+    RETURNING id, created_at, updated_at, user_id, feed_id, folder_id, title, muted, priority, notify )
 
-SELECT feed_follow_insert.id, feed_follow_insert.created_at, feed_follow_insert.updated_at, feed_follow_insert.user_id, feed_follow_insert.feed_id, feeds.name AS feed_name, users.name AS user_name
+SELECT feed_follow_insert.id, feed_follow_insert.created_at, feed_follow_insert.updated_at, feed_follow_insert.user_id, feed_follow_insert.feed_id, feed_follow_insert.folder_id, feed_follow_insert.title, feed_follow_insert.muted, feed_follow_insert.priority, feed_follow_insert.notify, feeds.name AS feed_name, users.name AS user_name
 FROM   feed_follow_insert
 INNER JOIN users ON users.id = feed_follow_insert.user_id
 INNER JOIN feeds ON feeds.id = feed_follow_insert.feed_id
@@ -38,6 +39,11 @@ type CreateFeedFollowRow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	FolderID  uuid.NullUUID
+	Title     sql.NullString
+	Muted     bool
+	Priority  int32
+	Notify    bool
 	FeedName  string
 	UserName  string
 }
@@ -57,22 +63,43 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.FeedID,
+		&i.FolderID,
+		&i.Title,
+		&i.Muted,
+		&i.Priority,
+		&i.Notify,
 		&i.FeedName,
 		&i.UserName,
 	)
 	return i, err
 }
 
-const deleteFeedFollows = `-- name: DeleteFeedFollows :exec
+const deleteFeedFollows = `-- name: DeleteFeedFollows :execrows
 DELETE FROM feed_follows
 `
 
-func (q *Queries) DeleteFeedFollows(ctx context.Context) error {
-	_, err := q.db.ExecContext(ctx, deleteFeedFollows)
-	return err
+func (q *Queries) DeleteFeedFollows(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFeedFollows)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteFeedFollowsForUser = `-- name: DeleteFeedFollowsForUser :execrows
+DELETE FROM feed_follows
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFeedFollowsForUser, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
-const deleteFeedFollowsForUserUrl = `-- name: DeleteFeedFollowsForUserUrl :exec
+const deleteFeedFollowsForUserUrl = `-- name: DeleteFeedFollowsForUserUrl :execrows
 DELETE FROM feed_follows
 WHERE feed_follows.user_id = $1 AND feed_follows.feed_id = $2
 `
@@ -82,31 +109,52 @@ type DeleteFeedFollowsForUserUrlParams struct {
 	FeedID uuid.UUID
 }
 
-func (q *Queries) DeleteFeedFollowsForUserUrl(ctx context.Context, arg DeleteFeedFollowsForUserUrlParams) error {
-	_, err := q.db.ExecContext(ctx, deleteFeedFollowsForUserUrl, arg.UserID, arg.FeedID)
-	return err
+func (q *Queries) DeleteFeedFollowsForUserUrl(ctx context.Context, arg DeleteFeedFollowsForUserUrlParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFeedFollowsForUserUrl, arg.UserID, arg.FeedID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
-SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id, users.name AS user_name, feeds.name AS feed_name
+SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id, feed_follows.folder_id, feed_follows.title, feed_follows.muted, feed_follows.priority, feed_follows.notify, users.name AS user_name, COALESCE(feed_follows.title, feeds.name) AS feed_name, feeds.url AS feed_url
 FROM feed_follows
 INNER JOIN feeds ON feed_follows.feed_id = feeds.id
 INNER JOIN users ON feed_follows.user_id = users.id
 WHERE users.name = $1
+  AND ($2::uuid IS NULL OR feed_follows.folder_id IN (
+        WITH RECURSIVE subtree AS (
+            SELECT folders.id FROM folders WHERE folders.id = $2
+            UNION ALL
+            SELECT folders.id FROM folders JOIN subtree ON folders.parent_id = subtree.id )
+        SELECT subtree.id FROM subtree ))
+ORDER BY feed_follows.priority DESC, feed_name
 `
 
+type GetFeedFollowsForUserParams struct {
+	Name     string
+	FolderID uuid.NullUUID
+}
+
 type GetFeedFollowsForUserRow struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	FolderID  uuid.NullUUID
+	Title     sql.NullString
+	Muted     bool
+	Priority  int32
+	Notify    bool
 	UserName  string
 	FeedName  string
+	FeedUrl   string
 }
 
-func (q *Queries) GetFeedFollowsForUser(ctx context.Context, name string) ([]GetFeedFollowsForUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, getFeedFollowsForUser, name)
+func (q *Queries) GetFeedFollowsForUser(ctx context.Context, arg GetFeedFollowsForUserParams) ([]GetFeedFollowsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedFollowsForUser, arg.Name, arg.FolderID)
 	if err != nil {
 		return nil, err
 	}
@@ -120,8 +168,14 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, name string) ([]Get
 			&i.UpdatedAt,
 			&i.UserID,
 			&i.FeedID,
+			&i.FolderID,
+			&i.Title,
+			&i.Muted,
+			&i.Priority,
+			&i.Notify,
 			&i.UserName,
 			&i.FeedName,
+			&i.FeedUrl,
 		); err != nil {
 			return nil, err
 		}
@@ -135,3 +189,67 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, name string) ([]Get
 	}
 	return items, nil
 }
+
+const getNextFeedOwner = `-- name: GetNextFeedOwner :one
+SELECT user_id FROM feed_follows
+WHERE feed_id = $1 AND user_id <> $2
+ORDER BY created_at
+LIMIT 1
+`
+
+type GetNextFeedOwnerParams struct {
+	FeedID uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) GetNextFeedOwner(ctx context.Context, arg GetNextFeedOwnerParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, getNextFeedOwner, arg.FeedID, arg.UserID)
+	var user_id uuid.UUID
+	err := row.Scan(&user_id)
+	return user_id, err
+}
+
+const updateFeedFollowSettings = `-- name: UpdateFeedFollowSettings :one
+UPDATE feed_follows
+SET title      = NULLIF(COALESCE($1, title), ''),
+    muted      = COALESCE($2,    muted),
+    priority   = COALESCE($3, priority),
+    notify     = COALESCE($4,   notify),
+    updated_at = NOW()
+WHERE user_id = $5 AND feed_id = $6
+RETURNING id, created_at, updated_at, user_id, feed_id, folder_id, title, muted, priority, notify
+`
+
+type UpdateFeedFollowSettingsParams struct {
+	Title    sql.NullString
+	Muted    sql.NullBool
+	Priority sql.NullInt32
+	Notify   sql.NullBool
+	UserID   uuid.UUID
+	FeedID   uuid.UUID
+}
+
+func (q *Queries) UpdateFeedFollowSettings(ctx context.Context, arg UpdateFeedFollowSettingsParams) (FeedFollow, error) {
+	row := q.db.QueryRowContext(ctx, updateFeedFollowSettings,
+		arg.Title,
+		arg.Muted,
+		arg.Priority,
+		arg.Notify,
+		arg.UserID,
+		arg.FeedID,
+	)
+	var i FeedFollow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.FeedID,
+		&i.FolderID,
+		&i.Title,
+		&i.Muted,
+		&i.Priority,
+		&i.Notify,
+	)
+	return i, err
+}
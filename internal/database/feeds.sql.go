@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,7 +16,7 @@ import (
 const createFeed = `-- name: CreateFeed :one
 INSERT INTO feeds  ( id, created_at, updated_at, name, url, user_id )
             VALUES ( $1, $2,         $3,         $4,   $5,  $6      )
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, full_text, archive
 `
 
 type CreateFeedParams struct {
@@ -45,21 +46,52 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.FullText,
+		&i.Archive,
 	)
 	return i, err
 }
 
-const deleteFeeds = `-- name: DeleteFeeds :exec
+const deleteFeed = `-- name: DeleteFeed :execrows
 DELETE FROM feeds
+WHERE id = $1
 `
 
-func (q *Queries) DeleteFeeds(ctx context.Context) error {
-	_, err := q.db.ExecContext(ctx, deleteFeeds)
-	return err
+func (q *Queries) DeleteFeed(ctx context.Context, iD uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFeed, iD)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteFeeds = `-- name: DeleteFeeds :execrows
+DELETE FROM feeds
+`
+
+func (q *Queries) DeleteFeeds(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFeeds)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteFeedsWithoutFollowers = `-- name: DeleteFeedsWithoutFollowers :execrows
+DELETE FROM feeds
+WHERE NOT EXISTS ( SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id )
+`
+
+func (q *Queries) DeleteFeedsWithoutFollowers(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFeedsWithoutFollowers)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 const getFeedUrl = `-- name: GetFeedUrl :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, full_text, archive FROM feeds
 WHERE feeds.url = $1
 `
 
@@ -74,6 +106,8 @@ func (q *Queries) GetFeedUrl(ctx context.Context, url string) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.FullText,
+		&i.Archive,
 	)
 	return i, err
 }
@@ -111,13 +145,131 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 	return items, nil
 }
 
+const getFeedsByIDPrefix = `-- name: GetFeedsByIDPrefix :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, full_text, archive FROM feeds
+WHERE feeds.id::text LIKE $1::text || '%'
+ORDER BY created_at
+`
+
+func (q *Queries) GetFeedsByIDPrefix(ctx context.Context, prefix string) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedsByIDPrefix, prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.FullText,
+			&i.Archive,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeedsByName = `-- name: GetFeedsByName :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, full_text, archive FROM feeds
+WHERE feeds.name = $1
+ORDER BY created_at
+`
+
+func (q *Queries) GetFeedsByName(ctx context.Context, name string) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedsByName, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.FullText,
+			&i.Archive,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeedsForUser = `-- name: GetFeedsForUser :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, full_text, archive FROM feeds
+WHERE user_id = $1
+ORDER BY created_at
+`
+
+func (q *Queries) GetFeedsForUser(ctx context.Context, userID uuid.UUID) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.FullText,
+			&i.Archive,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getFeedsWithName = `-- name: GetFeedsWithName :many
-SELECT feeds.name, feeds.url, users.name AS username FROM feeds
+SELECT feeds.id, feeds.name, feeds.url, users.name AS username FROM feeds
 INNER JOIN users
 ON feeds.user_id = users.id
 `
 
 type GetFeedsWithNameRow struct {
+	ID       uuid.UUID
 	Name     string
 	Url      string
 	Username string
@@ -132,7 +284,12 @@ func (q *Queries) GetFeedsWithName(ctx context.Context) ([]GetFeedsWithNameRow,
 	var items []GetFeedsWithNameRow
 	for rows.Next() {
 		var i GetFeedsWithNameRow
-		if err := rows.Scan(&i.Name, &i.Url, &i.Username); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Url,
+			&i.Username,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -147,7 +304,7 @@ func (q *Queries) GetFeedsWithName(ctx context.Context) ([]GetFeedsWithNameRow,
 }
 
 const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, full_text, archive FROM feeds
 ORDER BY last_fetched_at ASC NULLS FIRST
 LIMIT 1
 `
@@ -163,6 +320,8 @@ func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.FullText,
+		&i.Archive,
 	)
 	return i, err
 }
@@ -171,7 +330,7 @@ const markFeedFetched = `-- name: MarkFeedFetched :one
 UPDATE feeds
 SET last_fetched_at = NOW(), updated_at = NOW()
 WHERE id = $1
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, full_text, archive
 `
 
 func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) (Feed, error) {
@@ -185,6 +344,56 @@ func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) (Feed, erro
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.FullText,
+		&i.Archive,
+	)
+	return i, err
+}
+
+const setFeedOwner = `-- name: SetFeedOwner :exec
+UPDATE feeds
+SET user_id = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type SetFeedOwnerParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) SetFeedOwner(ctx context.Context, arg SetFeedOwnerParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedOwner, arg.ID, arg.UserID)
+	return err
+}
+
+const updateFeedSettings = `-- name: UpdateFeedSettings :one
+UPDATE feeds
+SET full_text  = COALESCE($1, full_text),
+    archive    = COALESCE($2,   archive),
+    updated_at = NOW()
+WHERE id = $3
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, full_text, archive
+`
+
+type UpdateFeedSettingsParams struct {
+	FullText sql.NullBool
+	Archive  sql.NullBool
+	ID       uuid.UUID
+}
+
+func (q *Queries) UpdateFeedSettings(ctx context.Context, arg UpdateFeedSettingsParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, updateFeedSettings, arg.FullText, arg.Archive, arg.ID)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.FullText,
+		&i.Archive,
 	)
 	return i, err
 }
@@ -11,6 +11,28 @@ import (
 	"github.com/google/uuid"
 )
 
+type Archive struct {
+	ID         uuid.UUID
+	PostID     uuid.UUID
+	Url        string
+	ArchivedAt time.Time
+	Error      sql.NullString
+}
+
+type ArchiveBlob struct {
+	Sha256 string
+	Data   []byte
+}
+
+type ArchiveResource struct {
+	ArchiveID   uuid.UUID
+	Url         string
+	Status      int32
+	ContentType string
+	Sha256      string
+	FetchedAt   time.Time
+}
+
 type Feed struct {
 	ID            uuid.UUID
 	CreatedAt     time.Time
@@ -19,6 +41,8 @@ type Feed struct {
 	Url           string
 	UserID        uuid.UUID
 	LastFetchedAt sql.NullTime
+	FullText      bool
+	Archive       bool
 }
 
 type FeedFollow struct {
@@ -27,22 +51,62 @@ type FeedFollow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	FolderID  uuid.NullUUID
+	Title     sql.NullString
+	Muted     bool
+	Priority  int32
+	Notify    bool
 }
 
-type Post struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description sql.NullString
-	PublishedAt sql.NullTime
-	FeedID      uuid.UUID
-}
-
-type User struct {
+type Folder struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	UserID    uuid.UUID
 	Name      string
+	ParentID  uuid.NullUUID
+}
+
+type Post struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Title          string
+	Url            string
+	Description    sql.NullString
+	PublishedAt    sql.NullTime
+	FeedID         uuid.UUID
+	DescriptionRaw sql.NullString
+	Content        sql.NullString
+	ContentRaw     sql.NullString
+	Article        sql.NullString
+	SearchVector   interface{}
+}
+
+type PostRead struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+	ReadAt time.Time
+}
+
+type PostStar struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	StarredAt time.Time
+}
+
+type Session struct {
+	TokenHash  string
+	UserID     uuid.UUID
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+}
+
+type User struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Name         string
+	PasswordHash sql.NullString
+	Role         string
 }
@@ -14,20 +14,23 @@ import (
 )
 
 const createPost = `-- name: CreatePost :one
-INSERT INTO posts ( id, created_at, updated_at, title, url, description, published_at, feed_id )
-            VALUES ( $1, $2,         $3,         $4,    $5,  $6,          $7,           $8      )
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+INSERT INTO posts ( id, created_at, updated_at, title, url, description, published_at, feed_id, description_raw, content, content_raw )
+            VALUES ( $1, $2,         $3,         $4,    $5,  $6,          $7,           $8,      $9,              $10,     $11         )
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, description_raw, content, content_raw, article, search_vector
 `
 
 type CreatePostParams struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description sql.NullString
-	PublishedAt sql.NullTime
-	FeedID      uuid.UUID
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Title          string
+	Url            string
+	Description    sql.NullString
+	PublishedAt    sql.NullTime
+	FeedID         uuid.UUID
+	DescriptionRaw sql.NullString
+	Content        sql.NullString
+	ContentRaw     sql.NullString
 }
 
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
@@ -40,6 +43,9 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		arg.Description,
 		arg.PublishedAt,
 		arg.FeedID,
+		arg.DescriptionRaw,
+		arg.Content,
+		arg.ContentRaw,
 	)
 	var i Post
 	err := row.Scan(
@@ -51,34 +57,128 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.DescriptionRaw,
+		&i.Content,
+		&i.ContentRaw,
+		&i.Article,
+		&i.SearchVector,
 	)
 	return i, err
 }
 
-const getPostsForUser = `-- name: GetPostsForUser :many
-SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, feeds.name AS feed_name FROM posts
+const deletePosts = `-- name: DeletePosts :execrows
+DELETE FROM posts
+`
+
+func (q *Queries) DeletePosts(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deletePosts)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getPostsForReader = `-- name: GetPostsForReader :many
+SELECT posts.id, posts.title, posts.url, posts.description, posts.content, posts.article, posts.published_at, posts.created_at, posts.feed_id,
+       COALESCE(feed_follows.title, feeds.name) AS feed_name, feed_follows.folder_id,
+       EXISTS ( SELECT 1 FROM post_reads WHERE post_reads.post_id = posts.id AND post_reads.user_id = $1 ) AS is_read,
+       EXISTS ( SELECT 1 FROM post_stars WHERE post_stars.post_id = posts.id AND post_stars.user_id = $1 ) AS is_starred
+FROM posts
 JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
 JOIN feeds        ON posts.feed_id        = feeds.id
 WHERE feed_follows.user_id = $1
-ORDER BY posts.published_at DESC
+  AND NOT feed_follows.muted
+ORDER BY COALESCE(posts.published_at, posts.created_at) DESC, posts.id
 LIMIT $2
 `
 
-type GetPostsForUserParams struct {
-	UserID uuid.UUID
-	Limit  int32
+type GetPostsForReaderParams struct {
+	UserID   uuid.UUID
+	PageSize int32
 }
 
-type GetPostsForUserRow struct {
+type GetPostsForReaderRow struct {
 	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
 	Title       string
 	Url         string
 	Description sql.NullString
+	Content     sql.NullString
+	Article     sql.NullString
 	PublishedAt sql.NullTime
+	CreatedAt   time.Time
 	FeedID      uuid.UUID
 	FeedName    string
+	FolderID    uuid.NullUUID
+	IsRead      bool
+	IsStarred   bool
+}
+
+func (q *Queries) GetPostsForReader(ctx context.Context, arg GetPostsForReaderParams) ([]GetPostsForReaderRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsForReader, arg.UserID, arg.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostsForReaderRow
+	for rows.Next() {
+		var i GetPostsForReaderRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.Content,
+			&i.Article,
+			&i.PublishedAt,
+			&i.CreatedAt,
+			&i.FeedID,
+			&i.FeedName,
+			&i.FolderID,
+			&i.IsRead,
+			&i.IsStarred,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostsForUser = `-- name: GetPostsForUser :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.description_raw, posts.content, posts.content_raw, posts.article, posts.search_vector, COALESCE(feed_follows.title, feeds.name) AS feed_name FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+JOIN feeds        ON posts.feed_id        = feeds.id
+WHERE feed_follows.user_id = $1
+ORDER BY posts.published_at DESC
+LIMIT $2
+`
+
+type GetPostsForUserParams struct {
+	UserID uuid.UUID
+	Limit  int32
+}
+
+type GetPostsForUserRow struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Title          string
+	Url            string
+	Description    sql.NullString
+	PublishedAt    sql.NullTime
+	FeedID         uuid.UUID
+	DescriptionRaw sql.NullString
+	Content        sql.NullString
+	ContentRaw     sql.NullString
+	Article        sql.NullString
+	SearchVector   interface{}
+	FeedName       string
 }
 
 func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error) {
@@ -99,7 +199,297 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.DescriptionRaw,
+			&i.Content,
+			&i.ContentRaw,
+			&i.Article,
+			&i.SearchVector,
+			&i.FeedName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostsForUserFiltered = `-- name: GetPostsForUserFiltered :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.description_raw, posts.content, posts.content_raw, posts.article, posts.search_vector, COALESCE(feed_follows.title, feeds.name) AS feed_name FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+JOIN feeds        ON posts.feed_id        = feeds.id
+WHERE feed_follows.user_id = $1
+  AND ($2::text        IS NULL OR feeds.name = $2 OR feeds.url = $2 OR feed_follows.title = $2)
+  AND ($3::timestamp  IS NULL OR (CASE WHEN $4::text = 'fetched' THEN posts.created_at ELSE posts.published_at END) >= $3)
+  AND ($5::timestamp  IS NULL OR (CASE WHEN $4::text = 'fetched' THEN posts.created_at ELSE posts.published_at END) <  $5)
+  AND ($6::text       IS NULL OR posts.title ILIKE '%' || $6 || '%' OR posts.description ILIKE '%' || $6 || '%')
+  AND (NOT $7::boolean OR NOT EXISTS ( SELECT 1 FROM post_reads WHERE post_reads.post_id = posts.id AND post_reads.user_id = $1 ))
+  AND ($8::uuid IS NULL OR feed_follows.folder_id IN (
+        WITH RECURSIVE subtree AS (
+            SELECT folders.id FROM folders WHERE folders.id = $8
+            UNION ALL
+            SELECT folders.id FROM folders JOIN subtree ON folders.parent_id = subtree.id )
+        SELECT subtree.id FROM subtree ))
+  AND ($9::boolean OR NOT feed_follows.muted)
+ORDER BY (CASE WHEN $4::text = 'fetched' THEN posts.created_at ELSE posts.published_at END) DESC NULLS LAST, posts.id
+LIMIT $10 OFFSET $11
+`
+
+type GetPostsForUserFilteredParams struct {
+	UserID       uuid.UUID
+	Feed         sql.NullString
+	Since        sql.NullTime
+	SortBy       string
+	Until        sql.NullTime
+	Match        sql.NullString
+	UnreadOnly   bool
+	FolderID     uuid.NullUUID
+	IncludeMuted bool
+	PageSize     int32
+	PageOffset   int32
+}
+
+type GetPostsForUserFilteredRow struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Title          string
+	Url            string
+	Description    sql.NullString
+	PublishedAt    sql.NullTime
+	FeedID         uuid.UUID
+	DescriptionRaw sql.NullString
+	Content        sql.NullString
+	ContentRaw     sql.NullString
+	Article        sql.NullString
+	SearchVector   interface{}
+	FeedName       string
+}
+
+func (q *Queries) GetPostsForUserFiltered(ctx context.Context, arg GetPostsForUserFilteredParams) ([]GetPostsForUserFilteredRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsForUserFiltered,
+		arg.UserID,
+		arg.Feed,
+		arg.Since,
+		arg.SortBy,
+		arg.Until,
+		arg.Match,
+		arg.UnreadOnly,
+		arg.FolderID,
+		arg.IncludeMuted,
+		arg.PageSize,
+		arg.PageOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostsForUserFilteredRow
+	for rows.Next() {
+		var i GetPostsForUserFilteredRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.DescriptionRaw,
+			&i.Content,
+			&i.ContentRaw,
+			&i.Article,
+			&i.SearchVector,
+			&i.FeedName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostsRaw = `-- name: GetPostsRaw :many
+SELECT posts.id, posts.url, posts.description_raw, posts.content_raw, feeds.url AS feed_url FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+WHERE NOT $1::boolean OR (posts.description IS NULL AND posts.description_raw IS NOT NULL)
+ORDER BY posts.created_at, posts.id
+`
+
+type GetPostsRawRow struct {
+	ID             uuid.UUID
+	Url            string
+	DescriptionRaw sql.NullString
+	ContentRaw     sql.NullString
+	FeedUrl        string
+}
+
+func (q *Queries) GetPostsRaw(ctx context.Context, unsanitizedOnly bool) ([]GetPostsRawRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsRaw, unsanitizedOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostsRawRow
+	for rows.Next() {
+		var i GetPostsRawRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Url,
+			&i.DescriptionRaw,
+			&i.ContentRaw,
+			&i.FeedUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markAllPostsRead = `-- name: MarkAllPostsRead :execrows
+INSERT INTO post_reads ( user_id, post_id, read_at )
+SELECT $1, posts.id, NOW() FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+JOIN feeds        ON posts.feed_id        = feeds.id
+WHERE feed_follows.user_id = $1
+  AND ($2::text IS NULL OR feeds.name = $2 OR feeds.url = $2 OR feed_follows.title = $2)
+  AND ($3::uuid IS NULL OR feed_follows.folder_id IN (
+        WITH RECURSIVE subtree AS (
+            SELECT folders.id FROM folders WHERE folders.id = $3
+            UNION ALL
+            SELECT folders.id FROM folders JOIN subtree ON folders.parent_id = subtree.id )
+        SELECT subtree.id FROM subtree ))
+ON CONFLICT DO NOTHING
+`
+
+type MarkAllPostsReadParams struct {
+	UserID   uuid.UUID
+	Feed     sql.NullString
+	FolderID uuid.NullUUID
+}
+
+func (q *Queries) MarkAllPostsRead(ctx context.Context, arg MarkAllPostsReadParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markAllPostsRead, arg.UserID, arg.Feed, arg.FolderID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const markPostRead = `-- name: MarkPostRead :execrows
+INSERT INTO post_reads ( user_id, post_id, read_at )
+SELECT $1, posts.id, NOW() FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1
+  AND posts.url = $2
+ON CONFLICT DO NOTHING
+`
+
+type MarkPostReadParams struct {
+	UserID uuid.UUID
+	Url    string
+}
+
+func (q *Queries) MarkPostRead(ctx context.Context, arg MarkPostReadParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markPostRead, arg.UserID, arg.Url)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const markPostReadByID = `-- name: MarkPostReadByID :execrows
+INSERT INTO post_reads ( user_id, post_id, read_at )
+VALUES ( $1, $2, NOW() )
+ON CONFLICT DO NOTHING
+`
+
+type MarkPostReadByIDParams struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+}
+
+func (q *Queries) MarkPostReadByID(ctx context.Context, arg MarkPostReadByIDParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markPostReadByID, arg.UserID, arg.PostID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const searchPostsForUser = `-- name: SearchPostsForUser :many
+SELECT posts.id, posts.title, posts.url, posts.published_at, COALESCE(feed_follows.title, feeds.name) AS feed_name,
+       ts_rank(posts.search_vector, query) AS rank,
+       ts_headline('english', concat_ws(' ', posts.description, posts.content), query, 'StartSel=**, StopSel=**, MaxFragments=1, MaxWords=20, MinWords=5')::text AS snippet
+FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+JOIN feeds        ON posts.feed_id        = feeds.id
+CROSS JOIN websearch_to_tsquery('english', $1) AS query
+WHERE feed_follows.user_id = $2
+  AND posts.search_vector @@ query
+  AND ($3::timestamp IS NULL OR posts.published_at >= $3)
+ORDER BY rank DESC, posts.published_at DESC NULLS LAST
+LIMIT $4
+`
+
+type SearchPostsForUserParams struct {
+	Query    string
+	UserID   uuid.UUID
+	Since    sql.NullTime
+	PageSize int32
+}
+
+type SearchPostsForUserRow struct {
+	ID          uuid.UUID
+	Title       string
+	Url         string
+	PublishedAt sql.NullTime
+	FeedName    string
+	Rank        float32
+	Snippet     string
+}
+
+func (q *Queries) SearchPostsForUser(ctx context.Context, arg SearchPostsForUserParams) ([]SearchPostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchPostsForUser,
+		arg.Query,
+		arg.UserID,
+		arg.Since,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchPostsForUserRow
+	for rows.Next() {
+		var i SearchPostsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Url,
+			&i.PublishedAt,
 			&i.FeedName,
+			&i.Rank,
+			&i.Snippet,
 		); err != nil {
 			return nil, err
 		}
@@ -113,3 +503,76 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	}
 	return items, nil
 }
+
+const setPostArticle = `-- name: SetPostArticle :exec
+UPDATE posts
+SET article = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type SetPostArticleParams struct {
+	ID      uuid.UUID
+	Article sql.NullString
+}
+
+func (q *Queries) SetPostArticle(ctx context.Context, arg SetPostArticleParams) error {
+	_, err := q.db.ExecContext(ctx, setPostArticle, arg.ID, arg.Article)
+	return err
+}
+
+const starPost = `-- name: StarPost :execrows
+INSERT INTO post_stars ( user_id, post_id, starred_at )
+VALUES ( $1, $2, NOW() )
+ON CONFLICT DO NOTHING
+`
+
+type StarPostParams struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+}
+
+func (q *Queries) StarPost(ctx context.Context, arg StarPostParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, starPost, arg.UserID, arg.PostID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const unstarPost = `-- name: UnstarPost :execrows
+DELETE FROM post_stars
+WHERE user_id = $1 AND post_id = $2
+`
+
+type UnstarPostParams struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+}
+
+func (q *Queries) UnstarPost(ctx context.Context, arg UnstarPostParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, unstarPost, arg.UserID, arg.PostID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updatePostSanitized = `-- name: UpdatePostSanitized :execrows
+UPDATE posts
+SET description = $2, content = $3, updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdatePostSanitizedParams struct {
+	ID          uuid.UUID
+	Description sql.NullString
+	Content     sql.NullString
+}
+
+func (q *Queries) UpdatePostSanitized(ctx context.Context, arg UpdatePostSanitizedParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updatePostSanitized, arg.ID, arg.Description, arg.Content)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
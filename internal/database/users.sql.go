@@ -7,22 +7,48 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const countUsableAdmins = `-- name: CountUsableAdmins :one
+SELECT COUNT(*) FROM users
+WHERE role = 'admin' AND password_hash IS NOT NULL
+`
+
+func (q *Queries) CountUsableAdmins(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUsableAdmins)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countUsers = `-- name: CountUsers :one
+SELECT COUNT(*) FROM users
+`
+
+func (q *Queries) CountUsers(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUsers)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createUser = `-- name: CreateUser :one
-INSERT INTO users ( id, created_at, updated_at, name )
-VALUES            ( $1, $2,         $3,         $4   )
-RETURNING id, created_at, updated_at, name
+INSERT INTO users ( id, created_at, updated_at, name, password_hash, role )
+VALUES            ( $1, $2,         $3,         $4,   $5,            $6   )
+RETURNING id, created_at, updated_at, name, password_hash, role
 `
 
 type CreateUserParams struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Name      string
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Name         string
+	PasswordHash sql.NullString
+	Role         string
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
@@ -31,6 +57,8 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		arg.CreatedAt,
 		arg.UpdatedAt,
 		arg.Name,
+		arg.PasswordHash,
+		arg.Role,
 	)
 	var i User
 	err := row.Scan(
@@ -38,21 +66,39 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Name,
+		&i.PasswordHash,
+		&i.Role,
 	)
 	return i, err
 }
 
-const deleteUsers = `-- name: DeleteUsers :exec
+const deleteUserByName = `-- name: DeleteUserByName :execrows
 DELETE FROM users
+WHERE name = $1
 `
 
-func (q *Queries) DeleteUsers(ctx context.Context) error {
-	_, err := q.db.ExecContext(ctx, deleteUsers)
-	return err
+func (q *Queries) DeleteUserByName(ctx context.Context, name string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUserByName, name)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteUsers = `-- name: DeleteUsers :execrows
+DELETE FROM users
+`
+
+func (q *Queries) DeleteUsers(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUsers)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 const getUser = `-- name: GetUser :one
-SELECT id, created_at, updated_at, name FROM users
+SELECT id, created_at, updated_at, name, password_hash, role FROM users
 WHERE name = $1
 `
 
@@ -64,6 +110,8 @@ func (q *Queries) GetUser(ctx context.Context, name string) (User, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Name,
+		&i.PasswordHash,
+		&i.Role,
 	)
 	return i, err
 }
@@ -106,3 +154,66 @@ func (q *Queries) GetUsers(ctx context.Context) ([]string, error) {
 	}
 	return items, nil
 }
+
+const lockUsers = `-- name: LockUsers :exec
+LOCK TABLE users IN SHARE ROW EXCLUSIVE MODE
+`
+
+func (q *Queries) LockUsers(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, lockUsers)
+	return err
+}
+
+const renameUser = `-- name: RenameUser :execrows
+UPDATE users
+SET name = $1, updated_at = NOW()
+WHERE name = $2
+`
+
+type RenameUserParams struct {
+	NewName string
+	OldName string
+}
+
+func (q *Queries) RenameUser(ctx context.Context, arg RenameUserParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, renameUser, arg.NewName, arg.OldName)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setUserPassword = `-- name: SetUserPassword :exec
+UPDATE users
+SET password_hash = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type SetUserPasswordParams struct {
+	ID           uuid.UUID
+	PasswordHash sql.NullString
+}
+
+func (q *Queries) SetUserPassword(ctx context.Context, arg SetUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, setUserPassword, arg.ID, arg.PasswordHash)
+	return err
+}
+
+const setUserRole = `-- name: SetUserRole :execrows
+UPDATE users
+SET role = $2, updated_at = NOW()
+WHERE name = $1
+`
+
+type SetUserRoleParams struct {
+	Name string
+	Role string
+}
+
+func (q *Queries) SetUserRole(ctx context.Context, arg SetUserRoleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setUserRole, arg.Name, arg.Role)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
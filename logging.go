@@ -0,0 +1,58 @@
+package main
+
+import (
+    "fmt"
+    "io"
+    "log/slog"
+    "net/http"
+    "time"
+)
+
+// Formats for --log-format.  Logs always go to stderr, out of the way of command output.
+const (
+    logFormatText = "text"
+    logFormatJSON = "json"
+)
+
+// newLogger makes a logger for --log-level (debug, info, warn or error) and --log-format
+func newLogger(w io.Writer, level, format string) (*slog.Logger, error) {
+    var lvl slog.Level
+    if err := lvl.UnmarshalText([]byte(level)); err != nil {
+        return nil, fmt.Errorf("unknown log level %q, use debug, info, warn or error", level)
+    }
+
+    opts := &slog.HandlerOptions{ Level: lvl }
+    switch format {
+    case logFormatText:
+        return slog.New(slog.NewTextHandler(w, opts)), nil
+    case logFormatJSON:
+        return slog.New(slog.NewJSONHandler(w, opts)), nil
+    }
+    return nil, fmt.Errorf("unknown log format %q, use text or json", format)
+}
+
+// loggingTransport traces every http request at debug level
+type loggingTransport struct {
+    base http.RoundTripper
+}
+
+func (t loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+    start := time.Now()
+    slog.Debug("http request", "method", req.Method, "url", req.URL.String())
+
+    resp, err := t.base.RoundTrip(req)
+    if err != nil {
+        slog.Debug("http request failed", "method", req.Method, "url", req.URL.String(), "duration", time.Since(start), "error", err)
+        return nil, err
+    }
+
+    slog.Debug("http response", "method", req.Method, "url", req.URL.String(), "status", resp.StatusCode,
+               "content_length", resp.ContentLength, "duration", time.Since(start))
+    return resp, nil
+}
+
+// No single request, a feed, a page or one file of a snapshot, may take longer than this
+const httpTimeout = time.Minute
+
+// httpClient is what gator fetches feeds with
+var httpClient = &http.Client{ Transport: loggingTransport{ base: http.DefaultTransport }, Timeout: httpTimeout }
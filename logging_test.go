@@ -0,0 +1,125 @@
+package main
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "internal/database"
+    "log/slog"
+    "strings"
+    "testing"
+)
+
+// captureLogs sends slog output, as json, to a buffer for the rest of the test
+func captureLogs(t *testing.T, level string) *bytes.Buffer {
+    t.Helper()
+    buf := &bytes.Buffer{}
+    logger, err := newLogger(buf, level, logFormatJSON)
+    if err != nil {
+        t.Fatal(err)
+    }
+    old := slog.Default()
+    slog.SetDefault(logger)
+    t.Cleanup(func() { slog.SetDefault(old) })
+    return buf
+}
+
+// logRecords decodes json log lines, keeping only those with the given message
+func logRecords(t *testing.T, buf *bytes.Buffer, msg string) []map[string]any {
+    t.Helper()
+    records := []map[string]any{}
+    for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+        if line == "" {
+            continue
+        }
+        record := map[string]any{}
+        if err := json.Unmarshal([]byte(line), &record); err != nil {
+            t.Fatalf("log line is not json: %v\n%v", err, line)
+        }
+        if record["msg"] == msg {
+            records = append(records, record)
+        }
+    }
+    return records
+}
+
+func TestNewLogger(t *testing.T) {
+    for _, level := range []string{ "debug", "info", "WARN", "error" } {
+        if _, err := newLogger(&bytes.Buffer{}, level, logFormatText); err != nil {
+            t.Errorf("newLogger(%q) = %v", level, err)
+        }
+    }
+    if _, err := newLogger(&bytes.Buffer{}, "loud", logFormatText); err == nil {
+        t.Error("expected an error for an unknown level")
+    }
+    if _, err := newLogger(&bytes.Buffer{}, "info", "xml"); err == nil {
+        t.Error("expected an error for an unknown format")
+    }
+
+    buf := &bytes.Buffer{}
+    logger, _ := newLogger(buf, "warn", logFormatText)
+    logger.Info("quiet")
+    logger.Warn("loud")
+    if strings.Contains(buf.String(), "quiet") || !strings.Contains(buf.String(), "level=WARN msg=loud") {
+        t.Errorf("warn level logged:\n%v", buf)
+    }
+}
+
+func TestScrapeFeedsLogs(t *testing.T) {
+    eachStore(t, func(t *testing.T, s *state, q database.Querier) {
+        alice := addUser(t, q, "alice", roleUser)
+        server, _ := feedServer(t, testFeedXML)
+        feed := addFeed(t, q, alice, "Cartoons", server.URL + "/feed")
+        buf := captureLogs(t, "debug")
+
+        for range 2 {
+            if err := scrapeFeeds(context.Background(), s); err != nil {
+                t.Fatal(err)
+            }
+        }
+
+        collected := logRecords(t, buf, "feed collected")
+        if len(collected) != 2 {
+            t.Fatalf("%v feed collected records, want 2:\n%v", len(collected), buf)
+        }
+        first := collected[0]
+        if first["feed_id"] != feed.ID.String() || first["url"] != feed.Url || first["level"] != "INFO" {
+            t.Errorf("record = %v", first)
+        }
+        if _, ok := first["duration"].(float64); !ok {
+            t.Errorf("duration = %v, want a number", first["duration"])
+        }
+        if first["new_posts"] != float64(2) || collected[1]["new_posts"] != float64(0) {
+            t.Errorf("new_posts = %v then %v, want 2 then 0", first["new_posts"], collected[1]["new_posts"])
+        }
+
+        // http traces only show at debug
+        responses := logRecords(t, buf, "http response")
+        if len(responses) != 2 || responses[0]["status"] != float64(200) || responses[0]["url"] != feed.Url {
+            t.Errorf("http responses = %v", responses)
+        }
+
+        quiet := captureLogs(t, "info")
+        if _, err := fetchFeed(context.Background(), feed.Url); err != nil {
+            t.Fatal(err)
+        }
+        if quiet.Len() != 0 {
+            t.Errorf("info level logged http traces:\n%v", quiet)
+        }
+    })
+}
+
+func TestScrapeFeedsLogsFetchError(t *testing.T) {
+    eachStore(t, func(t *testing.T, s *state, q database.Querier) {
+        alice := addUser(t, q, "alice", roleUser)
+        server, _ := feedServer(t, "not a feed")
+        feed := addFeed(t, q, alice, "Broken", server.URL)
+        buf := captureLogs(t, "info")
+
+        assertErr(t, scrapeFeeds(context.Background(), s), ErrorFetchingFeed)
+        failed := logRecords(t, buf, "feed fetch failed")
+        if len(failed) != 1 || failed[0]["feed_id"] != feed.ID.String() || failed[0]["level"] != "ERROR" || failed[0]["error"] == nil {
+            t.Errorf("fetch failure records = %v", failed)
+        }
+    })
+}
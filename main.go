@@ -1,82 +1,185 @@
 package main
 
-import _ "github.com/lib/pq"
-
 import (
     "internal/config"
+    "context"
+    "database/sql"
+    "database/sql/driver"
+    "errors"
+    "log/slog"
+    "net"
     "fmt"
     "os"
-    "internal/database"
-    "database/sql"
     "time"
+    "flag"
+    "github.com/lib/pq"
+    "modernc.org/sqlite"
 )
 
+// Exit codes, so scripts and cron jobs can tell what kind of failure happened
+const (
+    exitOK       = 0
+    exitFailure  = 1 // anything not covered below
+    exitUsage    = 2 // unknown command or flag, missing or extra arguments
+    exitNotFound = 3 // no such user, feed, folder or follow
+    exitAuth     = 4 // not logged in, wrong password, or not allowed
+    exitNetwork  = 5 // a feed could not be fetched
+    exitDatabase = 6 // the database could not be reached or used
+)
 
 func main() {
+    os.Exit(runGator(os.Args[1:]))
+}
+
+func runGator(args []string) int {
 
     // time program
     start := time.Now()
-    defer tt(start)
 
-    // Make CLI prettier by separating from prompt lines
-    fmt.Println()
+    // Global flags come before the command name, ex: gator --profile work browse
+    globalFlags := flag.NewFlagSet("gator", flag.ContinueOnError)
+    profile    := globalFlags.String("profile", "", "config profile to use (default: $GATOR_PROFILE, then the profile chosen with profile use)")
+    configPath := globalFlags.String("config",  "", "config file to use (default: $GATOR_CONFIG, then $XDG_CONFIG_HOME/gator/config.json, then ~/.gatorconfig.json)")
+    output     := globalFlags.String("output",  outputTable, "output format for users, feeds, following and browse: table, json or csv")
+    logLevel   := globalFlags.String("log-level",  "info",        "least severe log messages to show: debug, info, warn or error")
+    logFormat  := globalFlags.String("log-format", logFormatText, "log format, text or json, for log pipelines")
 
-    // Read config from file containing current user and db url
-    cfg, err := config.Read()
-    if err != nil {
-        return
+    // Create instance of commands struct, with every command registered
+    coms := newCommands(globalFlags)
+
+    globalFlags.Usage = func() {
+        coms.help(nil)
+    }
+    if err := globalFlags.Parse(args); err != nil {
+        if errors.Is(err, flag.ErrHelp) {
+            return exitOK
+        }
+        return exitUsage
     }
+    if *configPath != "" {
+        config.SetPath(*configPath)
+    }
+    if !validOutput(*output) {
+        fmt.Fprintf(os.Stderr, "Unknown output format %q, use table, json or csv\n", *output)
+        return exitUsage
+    }
+    logger, logErr := newLogger(os.Stderr, *logLevel, *logFormat)
+    if logErr != nil {
+        fmt.Fprintln(os.Stderr, logErr)
+        return exitUsage
+    }
+    slog.SetDefault(logger)
 
-    // Create instance of commands struct
-    coms := commands{ commandList: map[string]func(*state, command) error {} }
-
-    // Register a handler function for each command
-    coms.register("login",     handlerLogin)
-    coms.register("register",  handlerRegister)
-    coms.register("reset",     handlerReset)
-    coms.register("users",     handlerUsers)
-    coms.register("agg",       handlerAgg)
-    coms.register("addfeed",   handlerAddFeed)
-    coms.register("feeds",     handlerFeedsWithName)
-    coms.register("follow",    handlerFollow)
-    coms.register("following", handlerFollowing)
-    coms.register("unfollow",  handlerUnfollow)
-    coms.register("browse",    handlerBrowse)
-
-    // Open connection to database
-    db, err := sql.Open("postgres", cfg.DBUrl)
+    // Find the command and check its flags and arguments before touching config or database
+    com, err := coms.parse(globalFlags.Args())
+    if errors.Is(err, flag.ErrHelp) {
+        com.info.printHelp()
+        return exitOK
+    }
     if err != nil {
-        return
+        fmt.Fprintln(os.Stderr, err)
+        if com.info != nil {
+            fmt.Fprintln(os.Stderr, "usage:", com.info.usage())
+            fmt.Fprintf(os.Stderr, "Run gator help %v for details.\n", com.info.path)
+        } else {
+            fmt.Fprintln(os.Stderr, "Run gator help for a list of commands.")
+        }
+        return exitUsage
     }
-    dbQueries := database.New(db)
-
-    // Initialize state, for storing config and queries to be used by commands
-    cState := state{ cfgState: &cfg, dbState: dbQueries }
-    
-    // Get args
-    args := os.Args
-    if len(args) < 2 {
-        fmt.Println("Not enough arguments provided.  Exiting...")
-        return
+
+    // Make CLI prettier by separating from prompt lines, and time the program
+    // json, csv and output meant for other programs are left bare for whatever they are piped into
+    if *output == outputTable && !com.info.bare {
+        fmt.Println()
+        defer tt(start)
     }
 
-    // Create command
-    cName := args[1]
-    cArgs := args[2:]
-    com   := command{ name: cName, args: cArgs}
+    cState := state{ cfgState: &config.Config{}, output: *output }
+    if com.info.needs != needsNothing {
+
+        // Read config from file containing db url and session for the profile
+        // profile, init and doctor deal with the file themselves, so they run without a usable profile
+        cfg, cfgErr := config.Read(*profile)
+        if cfgErr != nil && com.info.needs < needsLittle {
+            fmt.Fprintf(os.Stderr, "Unable to read config: %v\n", cfgErr)
+            fmt.Fprintln(os.Stderr, "Run gator init to create a config, or gator doctor to find out what is wrong.")
+            return exitFailure
+        }
+
+        // Open connection to database
+        // sql.Open only checks the url's driver, a bad url or unreachable server shows up on first use
+        // init and doctor test the url themselves, and report a bad one better than this can
+        dbStore, db, err := openStore(cfg.DBUrl)
+        if err != nil && com.info.needs < needsLittle {
+            fmt.Fprintf(os.Stderr, "Unable to open database: %v\n", err)
+            fmt.Fprintln(os.Stderr, "Check db_url in your config, or run gator doctor.")
+            return exitDatabase
+        }
+
+        // Refuse to touch a database whose schema does not match this binary
+        // Commands for setting up or fixing the database are let through
+        if com.info.needs == needsSchema {
+            if err := checkSchema(db); err != nil {
+                fmt.Fprintln(os.Stderr, err)
+                return exitDatabase
+            }
+
+            // Posts from before sanitising was added are cleaned the first time gator runs on the new schema
+            n, err := sanitizePosts(context.Background(), dbStore, true)
+            if err != nil {
+                fmt.Fprintln(os.Stderr, err)
+                return exitDatabase
+            }
+            if n > 0 {
+                slog.Info("sanitised posts saved before the upgrade", "posts", n)
+            }
+        }
+
+        // Initialize state, for storing config and queries to be used by commands
+        cState = state{ cfgState: &cfg, dbState: dbStore, dbConn: db, cfgErr: cfgErr, output: *output }
+    }
 
     // Run command
     err = coms.run(&cState, com)
 
     // Retrun with error if command fails
     if err != nil {
-        fmt.Printf("Error while running command. Reason: %v\n", err)
-        return
+        fmt.Fprintf(os.Stderr, "Error while running command. Reason: %v\n", err)
+        return exitCode(err)
     }
 
+    return exitOK
 }
 
 func tt(start time.Time) {
     e := time.Since(start)
     fmt.Printf("Program took %v\n\n", e)
 }
+
+// exitCode sorts an error into one of the exit codes by the sentinels and driver errors it wraps
+func exitCode(err error) int {
+    var uErr  usageError
+    var pqErr     *pq.Error
+    var sqliteErr *sqlite.Error
+    var nErr      net.Error
+    switch {
+    case err == nil:
+        return exitOK
+    case errors.As(err, &uErr):
+        return exitUsage
+    case errors.Is(err, ErrorFetchingFeed):
+        return exitNetwork
+
+    // A network error that is not from fetching a feed is from talking to the database.  This comes
+    // before the auth errors, a session that could not be looked up is an outage, not a logout.
+    case errors.As(err, &pqErr), errors.As(err, &sqliteErr), errors.As(err, &nErr), errors.Is(err, driver.ErrBadConn), errors.Is(err, sql.ErrConnDone),
+         errors.Is(err, ErrorConnectingDB), errors.Is(err, ErrorMigrating), errors.Is(err, ErrorSchemaMismatch):
+        return exitDatabase
+    case errors.Is(err, ErrorNotLoggedIn), errors.Is(err, ErrorNotAdmin), errors.Is(err, ErrorWrongPassword), errors.Is(err, ErrorNoPassword),
+         errors.Is(err, ErrorNotFeedOwner), errors.Is(err, ErrorLastAdmin):
+        return exitAuth
+    case errors.Is(err, sql.ErrNoRows), errors.Is(err, ErrorGettingUser), errors.Is(err, ErrorGettingFeed), errors.Is(err, ErrorGettingFolder), errors.Is(err, ErrorNotFollowing):
+        return exitNotFound
+    }
+    return exitFailure
+}
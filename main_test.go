@@ -0,0 +1,70 @@
+package main
+
+import (
+    "database/sql"
+    "errors"
+    "fmt"
+    "internal/config"
+    "net"
+    "path/filepath"
+    "testing"
+    "github.com/lib/pq"
+)
+
+func TestExitCode(t *testing.T) {
+    netErr := &net.OpError{ Op: "dial", Net: "tcp", Err: errors.New("connection refused") }
+    tests := []struct {
+        err  error
+        want int
+    }{
+        { nil,                                                                       exitOK },
+        { errors.New("something else"),                                              exitFailure },
+        { usageError{ err: EmptyArgList, reason: "missing arguments" },              exitUsage },
+        { fmt.Errorf("%w | Reason: %w", ErrorRunningHandle, ErrorNotLoggedIn),       exitAuth },
+        { fmt.Errorf("%w | Reason: nope", ErrorNotAdmin),                            exitAuth },
+        { fmt.Errorf("%w | Reason: %w", ErrorNotLoggedIn, sql.ErrNoRows),            exitAuth },
+        { fmt.Errorf("%w | Reason: %w", ErrorNotLoggedIn, netErr),                   exitDatabase },
+        { fmt.Errorf("%w | Reason: %w", ErrorNotLoggedIn, sql.ErrConnDone),          exitDatabase },
+        { fmt.Errorf("%w | Reason: %w", ErrorGettingUser, sql.ErrNoRows),            exitNotFound },
+        { fmt.Errorf("%w | Reason: no feed matches", ErrorGettingFeed),              exitNotFound },
+        { fmt.Errorf("%w | Reason: %w", ErrorFetchingFeed, netErr),                  exitNetwork },
+        { fmt.Errorf("%w | Reason: %w", ErrorGettingUser, netErr),                   exitDatabase },
+        { fmt.Errorf("%w | Reason: %w", ErrorCreatingFeed, &pq.Error{ Code: "23505" }), exitDatabase },
+        { fmt.Errorf("%w | Reason: too old", ErrorSchemaMismatch),                   exitDatabase },
+    }
+    for _, tt := range tests {
+        if got := exitCode(tt.err); got != tt.want {
+            t.Errorf("exitCode(%v) = %v, want %v", tt.err, got, tt.want)
+        }
+    }
+}
+
+func TestRunGatorExitCodes(t *testing.T) {
+    t.Setenv("GATOR_CONFIG", filepath.Join(t.TempDir(), "missing.json"))
+    t.Cleanup(func() { config.SetPath("") })
+
+    tests := []struct {
+        args []string
+        want int
+    }{
+        { []string{ "help" },                        exitOK },
+        { []string{ "help", "browse" },              exitOK },
+        { []string{ "browse", "-h" },                exitOK },
+        { []string{},                                exitUsage },
+        { []string{ "frobnicate" },                  exitUsage },
+        { []string{ "login" },                       exitUsage },
+        { []string{ "help", "frobnicate" },          exitUsage },
+        { []string{ "--output", "yaml", "users" },   exitUsage },
+        { []string{ "users" },                       exitFailure },
+    }
+    for _, tt := range tests {
+        var code int
+        capture(func() error {
+            code = runGator(tt.args)
+            return nil
+        })
+        if code != tt.want {
+            t.Errorf("gator %q exited %v, want %v", tt.args, code, tt.want)
+        }
+    }
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+    "context"
+    "database/sql"
+    "embed"
+    "fmt"
+    "path"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+// The goose migrations in sql/schema, and their SQLite equivalents in sql/sqlite/schema, built into
+// the binary so no external tool is needed.  Both have the same versions.  Versions are kept in
+// goose's goose_db_version table, so databases migrated with goose before this existed carry on
+// where they were.
+//go:embed sql/schema/*.sql sql/sqlite/schema/*.sql
+var schemaFS embed.FS
+
+type migration struct {
+    version int64
+    name    string
+    up      string
+    down    string
+}
+
+func migrateUp(s *state, cmd command) error {
+    ctx := context.Background()
+    migrations, current, err := migrateSetup(ctx, s.dbConn)
+    if err != nil {
+        return err
+    }
+
+    applied := 0
+    for _, m := range migrations {
+        if m.version <= current {
+            continue
+        }
+        err = runMigration(ctx, s.dbConn, m.up, "INSERT INTO goose_db_version ( version_id, is_applied ) VALUES ( $1, TRUE )", m.version)
+        if err != nil {
+            return fmt.Errorf("%w | Reason: %v: %w", ErrorMigrating, m.name, err)
+        }
+        fmt.Println("Applied", m.name)
+        applied++
+    }
+    if applied == 0 {
+        fmt.Println("Database is already up to date.")
+    }
+    return nil
+}
+
+func migrateDown(s *state, cmd command) error {
+    ctx := context.Background()
+    migrations, current, err := migrateSetup(ctx, s.dbConn)
+    if err != nil {
+        return err
+    }
+
+    for i := len(migrations) - 1; i >= 0; i-- {
+        m := migrations[i]
+        if m.version != current {
+            continue
+        }
+        err = runMigration(ctx, s.dbConn, m.down, "DELETE FROM goose_db_version WHERE version_id = $1", m.version)
+        if err != nil {
+            return fmt.Errorf("%w | Reason: %v: %w", ErrorMigrating, m.name, err)
+        }
+        fmt.Println("Rolled back", m.name)
+        return nil
+    }
+    fmt.Println("No migration to roll back.")
+    return nil
+}
+
+func migrateStatus(s *state, cmd command) error {
+    migrations, current, err := migrateSetup(context.Background(), s.dbConn)
+    if err != nil {
+        return err
+    }
+
+    for _, m := range migrations {
+        status := "pending"
+        if m.version <= current {
+            status = "applied"
+        }
+        fmt.Printf("%-8v %v\n", status, m.name)
+    }
+    fmt.Printf("\nDatabase version %v, gator expects %v\n", current, migrations[len(migrations) - 1].version)
+    return nil
+}
+
+// migrateSetup loads the built in migrations and the version the database is at
+func migrateSetup(ctx context.Context, db *sql.DB) ([]migration, int64, error) {
+    migrations, err := loadMigrations(schemaDir(db))
+    if err != nil {
+        return nil, 0, fmt.Errorf("%w | Reason: %w", ErrorMigrating, err)
+    }
+
+    err = ensureVersionTable(ctx, db)
+    if err != nil {
+        return nil, 0, fmt.Errorf("%w | Reason: %w", ErrorMigrating, err)
+    }
+    current, err := dbSchemaVersion(db)
+    if err != nil {
+        return nil, 0, fmt.Errorf("%w | Reason: %w", ErrorMigrating, err)
+    }
+    return migrations, current, nil
+}
+
+// checkSchema refuses to go on against a database migrated to a different version than this binary's
+func checkSchema(db *sql.DB) error {
+    migrations, err := loadMigrations(schemaDir(db))
+    if err != nil {
+        return err
+    }
+    latest := migrations[len(migrations) - 1].version
+
+    current, err := dbSchemaVersion(db)
+    if err != nil {
+        return fmt.Errorf("%w | Reason: unable to read schema version (%w), run gator migrate up on a new database or gator doctor to find out what is wrong", ErrorSchemaMismatch, err)
+    }
+    if current < latest {
+        return fmt.Errorf("%w | Reason: database schema is at version %v but this gator needs %v, run gator migrate up", ErrorSchemaMismatch, current, latest)
+    }
+    if current > latest {
+        return fmt.Errorf("%w | Reason: database schema is at version %v, newer than this gator's %v, upgrade gator", ErrorSchemaMismatch, current, latest)
+    }
+    return nil
+}
+
+// schemaDir is where the migrations for db's backend are
+func schemaDir(db *sql.DB) string {
+    if isSQLite(db) {
+        return "sql/sqlite/schema"
+    }
+    return "sql/schema"
+}
+
+func loadMigrations(dir string) ([]migration, error) {
+    files, err := schemaFS.ReadDir(dir)
+    if err != nil {
+        return nil, err
+    }
+
+    migrations := []migration{}
+    for _, file := range files {
+        // Files are named <version>_<description>.sql
+        prefix, _, ok := strings.Cut(file.Name(), "_")
+        if !ok {
+            return nil, fmt.Errorf("migration %v has no version prefix", file.Name())
+        }
+        version, err := strconv.ParseInt(prefix, 10, 64)
+        if err != nil {
+            return nil, fmt.Errorf("migration %v has no version prefix", file.Name())
+        }
+
+        data, err := schemaFS.ReadFile(path.Join(dir, file.Name()))
+        if err != nil {
+            return nil, err
+        }
+        up, down, ok := strings.Cut(string(data), "-- +goose Down")
+        if !ok {
+            return nil, fmt.Errorf("migration %v has no -- +goose Down section", file.Name())
+        }
+        up = strings.Replace(up, "-- +goose Up", "", 1)
+
+        migrations = append(migrations, migration{ version: version, name: file.Name(), up: up, down: down })
+    }
+
+    sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+    if len(migrations) == 0 {
+        return nil, fmt.Errorf("no migrations embedded")
+    }
+    return migrations, nil
+}
+
+// runMigration runs one direction of a migration and records it in one transaction
+func runMigration(ctx context.Context, db *sql.DB, script, record string, version int64) error {
+    tx, err := db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+
+    if _, err = tx.ExecContext(ctx, script); err != nil {
+        return err
+    }
+    if _, err = tx.ExecContext(ctx, record, version); err != nil {
+        return err
+    }
+    return tx.Commit()
+}
+
+// ensureVersionTable creates goose's table the way goose does, for databases that never saw goose
+func ensureVersionTable(ctx context.Context, db *sql.DB) error {
+    find   := "SELECT to_regclass('goose_db_version') IS NOT NULL"
+    create := `CREATE TABLE goose_db_version (
+        id         SERIAL    PRIMARY KEY,
+        version_id BIGINT    NOT NULL,
+        is_applied BOOLEAN   NOT NULL,
+        tstamp     TIMESTAMP DEFAULT NOW()
+    );`
+    if isSQLite(db) {
+        find   = "SELECT EXISTS ( SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'goose_db_version' )"
+        create = `CREATE TABLE goose_db_version (
+        id         INTEGER   PRIMARY KEY AUTOINCREMENT,
+        version_id INTEGER   NOT NULL,
+        is_applied INTEGER   NOT NULL,
+        tstamp     TIMESTAMP DEFAULT (datetime('now'))
+    );`
+    }
+
+    var exists bool
+    err := db.QueryRowContext(ctx, find).Scan(&exists)
+    if err != nil || exists {
+        return err
+    }
+
+    _, err = db.ExecContext(ctx, create + `
+    INSERT INTO goose_db_version ( version_id, is_applied ) VALUES ( 0, TRUE );`)
+    return err
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+    "strings"
+    "testing"
+)
+
+func TestLoadMigrations(t *testing.T) {
+    postgres, err := loadMigrations("sql/schema")
+    if err != nil {
+        t.Fatal(err)
+    }
+    sqlite, err := loadMigrations("sql/sqlite/schema")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    for _, migrations := range [][]migration{ postgres, sqlite } {
+        for i, m := range migrations {
+            if m.version != int64(i + 1) {
+                t.Errorf("migration %v has version %v, want %v (gap or duplicate)", m.name, m.version, i + 1)
+            }
+            if strings.TrimSpace(m.up) == "" || strings.TrimSpace(m.down) == "" {
+                t.Errorf("migration %v is missing its up or down section", m.name)
+            }
+            if strings.Contains(m.up, "+goose") || strings.Contains(m.down, "+goose") {
+                t.Errorf("migration %v still has goose annotations in its sql", m.name)
+            }
+        }
+    }
+
+    // Each Postgres migration has its SQLite equivalent under the same version
+    if len(sqlite) != len(postgres) {
+        t.Fatalf("%v sqlite migrations for %v postgres ones", len(sqlite), len(postgres))
+    }
+    for i := range postgres {
+        if sqlite[i].name != postgres[i].name {
+            t.Errorf("sqlite migration %v stands for postgres migration %v", sqlite[i].name, postgres[i].name)
+        }
+    }
+}
+
+func TestHandlerMigrateUsage(t *testing.T) {
+    s, _ := newTestState(t)
+    assertErr(t, runErr(s, "migrate"), EmptyArgList)
+    assertErr(t, runErr(s, "migrate", "sideways"), NoCommandExists)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+    "database/sql"
+    "encoding/csv"
+    "encoding/json"
+    "fmt"
+    "os"
+    "time"
+)
+
+// Formats for --output.  Table is for people, json and csv are for scripts and carry no decoration.
+const (
+    outputTable = "table"
+    outputJSON  = "json"
+    outputCSV   = "csv"
+)
+
+// listing is what a listing command found, as rows of named columns
+type listing struct {
+    columns []string
+    rows    [][]any
+}
+
+func newListing(columns ...string) *listing {
+    return &listing{ columns: columns, rows: [][]any{} }
+}
+
+// add appends a row, one value per column.  nil is an empty value.
+func (l *listing) add(values ...any) {
+    l.rows = append(l.rows, values)
+}
+
+// printListing writes the listing in the format asked for with --output, or calls table for the
+// human readable version
+func printListing(s *state, l *listing, table func()) error {
+    var err error
+    switch s.output {
+    case outputJSON:
+        err = writeJSON(l)
+    case outputCSV:
+        err = writeCSV(l)
+    default:
+        table()
+    }
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorWritingOutput, err)
+    }
+    return nil
+}
+
+func validOutput(format string) bool {
+    return format == outputTable || format == outputJSON || format == outputCSV
+}
+
+// writeJSON writes an array with an object per row, keyed by column
+func writeJSON(l *listing) error {
+    records := make([]map[string]any, 0, len(l.rows))
+    for _, row := range l.rows {
+        record := map[string]any{}
+        for i, column := range l.columns {
+            record[column] = row[i]
+        }
+        records = append(records, record)
+    }
+
+    enc := json.NewEncoder(os.Stdout)
+    enc.SetIndent("", "  ")
+    return enc.Encode(records)
+}
+
+// writeCSV writes a header line of column names, then a line per row
+func writeCSV(l *listing) error {
+    w := csv.NewWriter(os.Stdout)
+    if err := w.Write(l.columns); err != nil {
+        return err
+    }
+    for _, row := range l.rows {
+        fields := make([]string, len(row))
+        for i, value := range row {
+            fields[i] = csvField(value)
+        }
+        if err := w.Write(fields); err != nil {
+            return err
+        }
+    }
+    w.Flush()
+    return w.Error()
+}
+
+func csvField(value any) string {
+    switch v := value.(type) {
+    case nil:
+        return ""
+    case time.Time:
+        return v.Format(time.RFC3339)
+    default:
+        return fmt.Sprint(v)
+    }
+}
+
+// Null columns become nil, so they are null in json and empty in csv
+func nullTimeValue(t sql.NullTime) any {
+    if !t.Valid {
+        return nil
+    }
+    return t.Time
+}
+
+func nullStringValue(str sql.NullString) any {
+    if !str.Valid {
+        return nil
+    }
+    return str.String
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+    "encoding/csv"
+    "encoding/json"
+    "internal/database"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestOutputJSON(t *testing.T) {
+    eachStore(t, func(t *testing.T, s *state, q database.Querier) {
+        alice := loginAs(t, s, q, "alice", roleUser)
+        addUser(t, q, "bob", roleUser)
+        feed  := addFeed(t, q, alice, "Blog", "https://blog.example.com/rss")
+        addPost(t, q, feed, "Hello", "https://blog.example.com/1", "First post", time.Hour)
+        s.output = outputJSON
+
+        var users []map[string]any
+        decodeJSON(t, run(t, s, "users"), &users)
+        if len(users) != 2 || users[0]["name"] != "alice" || users[0]["current"] != true || users[1]["current"] != false {
+            t.Errorf("users = %v", users)
+        }
+
+        var follows []map[string]any
+        decodeJSON(t, run(t, s, "following"), &follows)
+        if len(follows) != 1 || follows[0]["name"] != "Blog" || follows[0]["title"] != nil || follows[0]["muted"] != false {
+            t.Errorf("following = %v", follows)
+        }
+
+        var posts []map[string]any
+        decodeJSON(t, run(t, s, "browse"), &posts)
+        if len(posts) != 1 || posts[0]["title"] != "Hello" || posts[0]["feed_name"] != "Blog" || posts[0]["description"] != "First post" {
+            t.Errorf("browse = %v", posts)
+        }
+
+        // No matches is an empty array, not null
+        out := run(t, s, "browse", "--match", "nothing")
+        if strings.TrimSpace(out) != "[]" {
+            t.Errorf("empty browse = %q, want []", out)
+        }
+    })
+}
+
+func TestOutputCSV(t *testing.T) {
+    eachStore(t, func(t *testing.T, s *state, q database.Querier) {
+        alice := loginAs(t, s, q, "alice", roleUser)
+        addFeed(t, q, alice, "Blog, with a comma", "https://blog.example.com/rss")
+        s.output = outputCSV
+
+        records, err := csv.NewReader(strings.NewReader(run(t, s, "feeds"))).ReadAll()
+        if err != nil {
+            t.Fatal(err)
+        }
+        if len(records) != 2 || strings.Join(records[0], ",") != "id,name,url,user" {
+            t.Fatalf("feeds csv = %v", records)
+        }
+        if records[1][1] != "Blog, with a comma" || records[1][3] != "alice" || len(records[1][0]) != 36 {
+            t.Errorf("feed row = %v", records[1])
+        }
+    })
+}
+
+func TestOutputTableUnchanged(t *testing.T) {
+    eachStore(t, func(t *testing.T, s *state, q database.Querier) {
+        loginAs(t, s, q, "alice", roleUser)
+        s.output = outputTable
+        assertContains(t, run(t, s, "users"), "* alice (current)\n")
+
+        if validOutput("yaml") || !validOutput(outputCSV) {
+            t.Error("validOutput accepts the wrong formats")
+        }
+    })
+}
+
+func decodeJSON(t *testing.T, out string, v any) {
+    t.Helper()
+    if err := json.Unmarshal([]byte(out), v); err != nil {
+        t.Fatalf("output is not json: %v\n%v", err, out)
+    }
+}
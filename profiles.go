@@ -0,0 +1,42 @@
+package main
+
+import (
+    "fmt"
+    "internal/config"
+)
+
+func profileList(s *state, cmd command) error {
+    names, current, err := config.Profiles()
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorReadingConfig, err)
+    }
+    for _, name := range names {
+        fmt.Printf("* %v", name)
+        if name == current {
+            fmt.Printf(" (current)")
+        }
+        if name == s.cfgState.ProfileName && name != current {
+            fmt.Printf(" (this run)")
+        }
+        fmt.Printf("\n")
+    }
+    return nil
+}
+
+func profileAdd(s *state, cmd command) error {
+    err := config.AddProfile(cmd.args[0], cmd.args[1])
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorWritingConfig, err)
+    }
+    fmt.Printf("Profile %v saved.  Switch to it with gator profile use %v or gator --profile %v <command>\n", cmd.args[0], cmd.args[0], cmd.args[0])
+    return nil
+}
+
+func profileUse(s *state, cmd command) error {
+    err := config.UseProfile(cmd.args[0])
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorWritingConfig, err)
+    }
+    fmt.Printf("Now using profile %v\n", cmd.args[0])
+    return nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+    "internal/config"
+    "testing"
+)
+
+func TestHandlerProfile(t *testing.T) {
+    s, _ := newTestState(t)
+
+    run(t, s, "profile", "add", "home", "postgres://localhost/home")
+    run(t, s, "profile", "add", "work", "postgres://localhost/work")
+
+    out := run(t, s, "profile", "list")
+    assertContains(t, out, "* home (current)\n", "* work\n")
+
+    run(t, s, "profile", "use", "work")
+    cfg, err := config.Read("")
+    if err != nil || cfg.ProfileName != "work" || cfg.DBUrl != "postgres://localhost/work" {
+        t.Errorf("config after use = %+v, %v", cfg, err)
+    }
+
+    assertErr(t, runErr(s, "profile", "use", "nope"), ErrorWritingConfig)
+    assertErr(t, runErr(s, "profile", "add", "x"), NotEnoughArgs)
+    assertErr(t, runErr(s, "profile", "remove", "x"), NoCommandExists)
+    assertErr(t, runErr(s, "profile"), EmptyArgList)
+}
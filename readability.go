@@ -0,0 +1,365 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "io"
+    "math"
+    "net/http"
+    "net/url"
+    "regexp"
+    "strings"
+    "time"
+    "golang.org/x/net/html"
+    "golang.org/x/net/html/atom"
+    "golang.org/x/net/html/charset"
+)
+
+// Pages bigger than this are cut short rather than read whole
+const maxArticleBytes = 5 << 20
+
+// How long one post's page may take to download
+const articleTimeout = 30 * time.Second
+
+// How many new posts' pages one scrape downloads for full text, and how many at a time
+const articlesPerRound = 10
+const articleWorkers   = 4
+
+// Classes and ids that say what part of a page an element is, after Arc90's readability
+var (
+    unlikelyNames = regexp.MustCompile(`(?i)-ad-|agegate|banner|breadcrumb|combx|comment|community|cookie|disqus|extra|footer|gdpr|header|legends|menu|newsletter|pager|pagination|popup|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|supplemental`)
+    maybeNames    = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+    positiveNames = regexp.MustCompile(`(?i)article|blog|body|content|entry|h-entry|hentry|main|page|post|story|text`)
+    negativeNames = regexp.MustCompile(`(?i)-ad-|banner|combx|comment|com-|contact|foot|footnote|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shopping|shoutbox|sidebar|skyscraper|sponsor|tags|tool|widget`)
+)
+
+// Elements that never hold the article
+var readabilityRemoved = map[atom.Atom]bool{
+    atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Iframe: true, atom.Form: true, atom.Nav: true,
+    atom.Aside: true, atom.Footer: true, atom.Button: true, atom.Input: true, atom.Select: true, atom.Textarea: true,
+    atom.Svg: true, atom.Template: true, atom.Object: true, atom.Embed: true, atom.Link: true, atom.Meta: true,
+}
+
+// A div holding none of these is scored like a paragraph
+var readabilityBlocks = map[atom.Atom]bool{
+    atom.A: true, atom.Blockquote: true, atom.Dl: true, atom.Div: true, atom.Img: true, atom.Ol: true, atom.P: true,
+    atom.Pre: true, atom.Table: true, atom.Ul: true, atom.Section: true, atom.Article: true, atom.Figure: true,
+}
+
+// fetchArticle downloads a post's page and returns its main article as sanitised html
+func fetchArticle(ctx context.Context, pageURL string) (string, error) {
+    req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+    if err != nil {
+        return "", fmt.Errorf("Error formulating request: %w", err)
+    }
+
+    req.Header.Add("User-Agent", "gator")
+
+    resp, err := httpClient.Do(req)
+    if err != nil {
+        return "", fmt.Errorf("Error while fetching page: %w", err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        return "", fmt.Errorf("Error while fetching page: %v", resp.Status)
+    }
+    contentType := resp.Header.Get("Content-Type")
+    if contentType != "" && !strings.Contains(contentType, "html") {
+        return "", fmt.Errorf("Error while fetching page: %v is not html", contentType)
+    }
+
+    body, err := charset.NewReader(io.LimitReader(resp.Body, maxArticleBytes), contentType)
+    if err != nil {
+        return "", fmt.Errorf("Error while reading page: %w", err)
+    }
+    data, err := io.ReadAll(body)
+    if err != nil {
+        return "", fmt.Errorf("Error while reading page: %w", err)
+    }
+
+    return extractArticle(string(data), resp.Request.URL)
+}
+
+// extractArticle finds the main article in a page the way readability does.  Menus, comments and
+// other boilerplate are removed, paragraphs score their parents by length and commas, the best
+// scoring element (less the share of its text in links) is the article, and siblings that score
+// nearly as well are kept with it.  The result is sanitised, with links resolved against base.
+func extractArticle(page string, base *url.URL) (string, error) {
+    doc, err := html.Parse(strings.NewReader(page))
+    if err != nil {
+        return "", fmt.Errorf("Error while parsing page: %w", err)
+    }
+    removeNodes(doc, isBoilerplate)
+
+    scores     := map[*html.Node]float64{}
+    candidates := []*html.Node{}
+    for _, n := range elements(doc) {
+        if !isParagraph(n) {
+            continue
+        }
+        text := innerText(n)
+        if len(text) < 25 {
+            continue
+        }
+
+        // Parents get the paragraph's whole score, grandparents half of it
+        score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text) / 100), 3)
+        for level, ancestor := 0, n.Parent; level < 2 && ancestor != nil && ancestor.Type == html.ElementNode; level, ancestor = level + 1, ancestor.Parent {
+            if _, ok := scores[ancestor]; !ok {
+                scores[ancestor] = initialScore(ancestor)
+                candidates = append(candidates, ancestor)
+            }
+            scores[ancestor] += score / float64(level + 1)
+        }
+    }
+
+    var top *html.Node
+    for _, candidate := range candidates {
+        scores[candidate] *= 1 - linkDensity(candidate)
+        if top == nil || scores[candidate] > scores[top] {
+            top = candidate
+        }
+    }
+    if top == nil {
+        return "", fmt.Errorf("Error while extracting article: no article found")
+    }
+
+    article := &html.Node{ Type: html.ElementNode, Data: "div", DataAtom: atom.Div }
+    for _, n := range articleSiblings(top, scores) {
+        n.Parent.RemoveChild(n)
+        article.AppendChild(n)
+    }
+    cleanArticle(article)
+
+    if len(innerText(article)) < 200 {
+        return "", fmt.Errorf("Error while extracting article: too little text found")
+    }
+
+    out := &strings.Builder{}
+    for child := article.FirstChild; child != nil; child = child.NextSibling {
+        html.Render(out, child)
+    }
+    return sanitizeHTML(out.String(), base), nil
+}
+
+// articleSiblings is the top candidate with the siblings that look like part of the same article:
+// ones scoring at least a fifth as well, and paragraphs of prose that are mostly not links
+func articleSiblings(top *html.Node, scores map[*html.Node]float64) []*html.Node {
+    if top.Parent == nil {
+        return []*html.Node{ top }
+    }
+    threshold := math.Max(10, scores[top] * 0.2)
+
+    kept := []*html.Node{}
+    for sibling := top.Parent.FirstChild; sibling != nil; sibling = sibling.NextSibling {
+        if sibling.Type != html.ElementNode {
+            continue
+        }
+        keep := sibling == top
+        if score, ok := scores[sibling]; ok && score >= threshold {
+            keep = true
+        }
+        if sibling.DataAtom == atom.P {
+            text    := innerText(sibling)
+            density := linkDensity(sibling)
+            if (len(text) > 80 && density < 0.25) || (len(text) > 0 && density == 0 && strings.Contains(text, ". ")) {
+                keep = true
+            }
+        }
+        if keep {
+            kept = append(kept, sibling)
+        }
+    }
+    return kept
+}
+
+// cleanArticle takes out what is left of the page inside the article: blocks marked as boilerplate
+// by their class and lists or tables of links.  Lazily loaded images get their real source.
+func cleanArticle(article *html.Node) {
+    leftover := func(n *html.Node) bool {
+        switch n.DataAtom {
+        case atom.Div, atom.Section, atom.Ul, atom.Ol, atom.Table, atom.Header, atom.H1, atom.H2:
+            if classWeight(n) < 0 {
+                return true
+            }
+            text := innerText(n)
+            return text != "" && linkDensity(n) > 0.5 && len(text) < 1000
+        }
+        return false
+    }
+    for child := article.FirstChild; child != nil; child = child.NextSibling {
+        removeNodes(child, leftover)
+    }
+
+    for _, n := range elements(article) {
+        if n.DataAtom != atom.Img {
+            continue
+        }
+        src := htmlAttr(n, "src")
+        if lazy := htmlAttr(n, "data-src"); lazy != "" && (src == "" || strings.HasPrefix(src, "data:")) {
+            setAttr(n, "src", lazy)
+        }
+    }
+}
+
+// isBoilerplate spots elements that never hold the article: scripts, menus, hidden elements and
+// those whose class or id says they are comments, sidebars and the like
+func isBoilerplate(n *html.Node) bool {
+    if n.Type == html.CommentNode {
+        return true
+    }
+    if n.Type != html.ElementNode {
+        return false
+    }
+    if readabilityRemoved[n.DataAtom] {
+        return true
+    }
+    if hasAttr(n, "hidden") || htmlAttr(n, "aria-hidden") == "true" {
+        return true
+    }
+    if style := strings.Join(strings.Fields(strings.ToLower(htmlAttr(n, "style"))), ""); strings.Contains(style, "display:none") {
+        return true
+    }
+
+    switch n.DataAtom {
+    case atom.Html, atom.Body, atom.Article, atom.Main, atom.A:
+        return false
+    }
+    names := htmlAttr(n, "class") + " " + htmlAttr(n, "id")
+    return unlikelyNames.MatchString(names) && !maybeNames.MatchString(names)
+}
+
+// isParagraph reports whether n holds text to be scored: paragraphs, code, table cells, and divs
+// used as paragraphs
+func isParagraph(n *html.Node) bool {
+    switch n.DataAtom {
+    case atom.P, atom.Pre, atom.Td:
+        return true
+    case atom.Div:
+        for _, child := range elements(n)[1:] {
+            if readabilityBlocks[child.DataAtom] {
+                return false
+            }
+        }
+        return true
+    }
+    return false
+}
+
+// initialScore is how likely an element is to hold the article before its paragraphs are counted
+func initialScore(n *html.Node) float64 {
+    score := classWeight(n)
+    switch n.DataAtom {
+    case atom.Article:
+        score += 10
+    case atom.Div, atom.Main:
+        score += 5
+    case atom.Pre, atom.Td, atom.Blockquote, atom.Section:
+        score += 3
+    case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li, atom.Form:
+        score -= 3
+    case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+        score -= 5
+    }
+    return score
+}
+
+// classWeight scores an element's class and id: +25 each for names like article or content,
+// -25 each for names like comment or sidebar
+func classWeight(n *html.Node) float64 {
+    weight := 0.0
+    for _, name := range []string{ "class", "id" } {
+        value := htmlAttr(n, name)
+        if value == "" {
+            continue
+        }
+        if negativeNames.MatchString(value) {
+            weight -= 25
+        }
+        if positiveNames.MatchString(value) {
+            weight += 25
+        }
+    }
+    return weight
+}
+
+// linkDensity is the share of an element's text that is inside links
+func linkDensity(n *html.Node) float64 {
+    text := innerText(n)
+    if text == "" {
+        return 0
+    }
+    linked := 0
+    for _, link := range elements(n) {
+        if link.DataAtom == atom.A {
+            linked += len(innerText(link))
+        }
+    }
+    return float64(linked) / float64(len(text))
+}
+
+// innerText is the text inside n with its spaces collapsed
+func innerText(n *html.Node) string {
+    text := &strings.Builder{}
+    var walk func(*html.Node)
+    walk = func(n *html.Node) {
+        if n.Type == html.TextNode {
+            text.WriteString(n.Data)
+            text.WriteString(" ")
+        }
+        for child := n.FirstChild; child != nil; child = child.NextSibling {
+            walk(child)
+        }
+    }
+    walk(n)
+    return strings.Join(strings.Fields(text.String()), " ")
+}
+
+// elements lists n and the elements inside it in document order
+func elements(n *html.Node) []*html.Node {
+    found := []*html.Node{}
+    var walk func(*html.Node)
+    walk = func(n *html.Node) {
+        if n.Type == html.ElementNode {
+            found = append(found, n)
+        }
+        for child := n.FirstChild; child != nil; child = child.NextSibling {
+            walk(child)
+        }
+    }
+    walk(n)
+    return found
+}
+
+// removeNodes takes every node matching remove out of the tree under root
+func removeNodes(root *html.Node, remove func(*html.Node) bool) {
+    for child := root.FirstChild; child != nil; {
+        next := child.NextSibling
+        if remove(child) {
+            root.RemoveChild(child)
+        } else {
+            removeNodes(child, remove)
+        }
+        child = next
+    }
+}
+
+func hasAttr(n *html.Node, name string) bool {
+    for _, a := range n.Attr {
+        if a.Key == name {
+            return true
+        }
+    }
+    return false
+}
+
+func setAttr(n *html.Node, name, value string) {
+    for i := range n.Attr {
+        if n.Attr[i].Key == name {
+            n.Attr[i].Val = value
+            return
+        }
+    }
+    n.Attr = append(n.Attr, html.Attribute{ Key: name, Val: value })
+}
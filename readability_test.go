@@ -0,0 +1,208 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+)
+
+func readFixture(t *testing.T, name string) string {
+    t.Helper()
+    data, err := os.ReadFile(filepath.Join("testdata", "articles", name))
+    if err != nil {
+        t.Fatal(err)
+    }
+    return string(data)
+}
+
+func TestExtractArticle(t *testing.T) {
+    tests := []struct {
+        fixture string
+        want    []string
+        not     []string
+    }{
+        { "blog.html",
+          []string{ "The old parser was written in a weekend", "<h2>What we kept</h2>", "<li>The fixture files",
+                    `<img src="https://blog.example.com/posts/images/parser.png" alt="The new parser&#39;s pipeline"/>`,
+                    `<a href="https://blog.example.com/docs/parser"` },
+          []string{ "Archive", "cookies", "Popular posts", "Great write up", "Share", "All rights reserved", "analytics" } },
+        { "news.html",
+          []string{ "The old bridge over the river reopened", "the damage turned out to be worse" },
+          []string{ "Council approves budget", "Weather warning", "Sport" } },
+    }
+
+    base, _ := url.Parse("https://blog.example.com/posts/parser")
+    for _, tt := range tests {
+        article, err := extractArticle(readFixture(t, tt.fixture), base)
+        if err != nil {
+            t.Errorf("%v: %v", tt.fixture, err)
+            continue
+        }
+        for _, want := range tt.want {
+            if !strings.Contains(article, want) {
+                t.Errorf("%v: article is missing %q:\n%v", tt.fixture, want, article)
+            }
+        }
+        for _, not := range tt.not {
+            if strings.Contains(article, not) {
+                t.Errorf("%v: article kept %q:\n%v", tt.fixture, not, article)
+            }
+        }
+    }
+
+    if _, err := extractArticle(readFixture(t, "nothing.html"), base); err == nil {
+        t.Error("found an article in a page of pictures")
+    }
+}
+
+func TestFetchArticle(t *testing.T) {
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        switch r.URL.Path {
+        case "/latin1":
+            // The page says what it is encoded in, and é arrives as a single byte
+            w.Header().Set("Content-Type", "text/html; charset=iso-8859-1")
+            w.Write([]byte(strings.Replace(readFixture(t, "news.html"), "The old bridge", "The old caf\xe9 bridge", 1)))
+        case "/feed.xml":
+            w.Header().Set("Content-Type", "application/rss+xml")
+            w.Write([]byte(testFeedXML))
+        default:
+            http.NotFound(w, r)
+        }
+    }))
+    t.Cleanup(server.Close)
+
+    article, err := fetchArticle(context.Background(), server.URL + "/latin1")
+    if err != nil || !strings.Contains(article, "The old café bridge") {
+        t.Errorf("fetchArticle = %q, %v", article, err)
+    }
+    if _, err := fetchArticle(context.Background(), server.URL + "/feed.xml"); err == nil {
+        t.Error("expected an error for a page that is not html")
+    }
+    if _, err := fetchArticle(context.Background(), server.URL + "/missing"); err == nil {
+        t.Error("expected an error for a missing page")
+    }
+}
+
+func TestScrapeFeedsFullText(t *testing.T) {
+    s, fake := newTestState(t)
+    alice := loginAs(t, s, fake, "alice", roleUser)
+
+    pages := 0
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.URL.Path == "/feed" {
+            w.Write([]byte(`<rss version="2.0"><channel><title>Blog</title>
+                <item><title>Parser</title><link>/posts/parser</link><description>Teaser</description></item>
+                </channel></rss>`))
+            return
+        }
+        pages++
+        w.Header().Set("Content-Type", "text/html")
+        w.Write([]byte(readFixture(t, "blog.html")))
+    }))
+    t.Cleanup(server.Close)
+    addFeed(t, fake, alice, "Blog", server.URL + "/feed")
+    captureLogs(t, "error")
+
+    // Off by default
+    if err := scrapeFeeds(context.Background(), s); err != nil {
+        t.Fatal(err)
+    }
+    if pages != 0 || fake.posts[0].Article.Valid {
+        t.Fatalf("page fetched %v times without full text on", pages)
+    }
+
+    out := run(t, s, "feed", "settings", "--full-text", "Blog")
+    assertContains(t, out, "Full Text:     true")
+
+    fake.posts = nil
+    if err := scrapeFeeds(context.Background(), s); err != nil {
+        t.Fatal(err)
+    }
+    if pages != 1 || !strings.Contains(fake.posts[0].Article.String, "The old parser was written in a weekend") {
+        t.Fatalf("%v pages fetched, article = %q", pages, fake.posts[0].Article.String)
+    }
+
+    // The reader shows the whole article rather than the teaser
+    m := startTUI(t, s)
+    m  = sendTUI(t, m, keys("tab", "enter")...)
+    assertContains(t, m.View(), "The old parser was written")
+}
+
+func TestScrapeFeedsFullTextLimit(t *testing.T) {
+    s, fake := newTestState(t)
+    alice := loginAs(t, s, fake, "alice", roleUser)
+
+    var mu sync.Mutex
+    pages, running, most := 0, 0, 0
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.URL.Path == "/feed" {
+            items := ""
+            for i := range articlesPerRound + 5 {
+                items += fmt.Sprintf("<item><title>Post %v</title><link>/posts/%v</link><description>Teaser</description></item>", i, i)
+            }
+            w.Write([]byte(`<rss version="2.0"><channel><title>Blog</title>` + items + `</channel></rss>`))
+            return
+        }
+        mu.Lock()
+        pages++
+        running++
+        most = max(most, running)
+        mu.Unlock()
+        time.Sleep(20 * time.Millisecond)
+        mu.Lock()
+        running--
+        mu.Unlock()
+        w.Header().Set("Content-Type", "text/html")
+        w.Write([]byte(readFixture(t, "blog.html")))
+    }))
+    t.Cleanup(server.Close)
+    addFeed(t, fake, alice, "Blog", server.URL + "/feed")
+    fake.feeds[0].FullText = true
+    captureLogs(t, "error")
+
+    if err := scrapeFeeds(context.Background(), s); err != nil {
+        t.Fatal(err)
+    }
+    if pages != articlesPerRound {
+        t.Errorf("%v pages fetched, want %v", pages, articlesPerRound)
+    }
+    if most > articleWorkers || most < 2 {
+        t.Errorf("%v pages fetched at once, want 2 to %v", most, articleWorkers)
+    }
+    saved := 0
+    for _, post := range fake.posts {
+        if post.Article.Valid {
+            saved++
+        }
+    }
+    if saved != articlesPerRound || len(fake.posts) != articlesPerRound + 5 {
+        t.Errorf("%v of %v posts have articles, want %v", saved, len(fake.posts), articlesPerRound)
+    }
+}
+
+func TestFeedSettings(t *testing.T) {
+    s, fake := newTestState(t)
+    alice := addUser(t, fake, "alice", roleUser)
+    addFeed(t, fake, alice, "Blog", "https://blog.example.com/rss")
+    loginAs(t, s, fake, "bob", roleUser)
+
+    out := run(t, s, "feed", "settings", "Blog")
+    assertContains(t, out, "Full Text:     false")
+
+    assertErr(t, runErr(s, "feed", "settings", "--full-text", "Blog"), ErrorNotFeedOwner)
+
+    loginAs(t, s, fake, "root", roleAdmin)
+    run(t, s, "feed", "settings", "--full-text", "Blog")
+    run(t, s, "feed", "settings", "--full-text=false", "Blog")
+    if fake.feeds[0].FullText {
+        t.Error("--full-text=false did not turn full text off")
+    }
+}
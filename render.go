@@ -0,0 +1,355 @@
+package main
+
+import (
+    "fmt"
+    "os"
+    "strings"
+    "golang.org/x/net/html"
+    "golang.org/x/net/html/atom"
+    "golang.org/x/term"
+)
+
+// renderHTML turns a post's html into text for the terminal: paragraphs wrapped to width, lists
+// bulleted or numbered, quotes marked with >, images as [image: alt] and links numbered, with
+// their urls listed at the end
+func renderHTML(s string, width int) string {
+    doc, err := html.Parse(strings.NewReader(s))
+    if err != nil {
+        return strings.TrimSpace(s)
+    }
+
+    r := &htmlRenderer{ width: width }
+    r.walk(doc)
+    r.flush()
+
+    if len(r.links) > 0 {
+        r.lines = append(r.lines, "")
+        for i, link := range r.links {
+            r.lines = append(r.lines, chop(fmt.Sprintf("[%v] %v", i + 1, link), width)...)
+        }
+    }
+    return strings.Join(r.lines, "\n")
+}
+
+// snippetText turns a search snippet, a fragment cut out of a post's html with matches between
+// **, into one line of text.  The fragment can start or end partway through a tag, those pieces
+// go with the whole tags.
+func snippetText(s string) string {
+    if end := strings.IndexByte(s, '>'); end >= 0 && !strings.Contains(s[:end], "<") {
+        s = s[end + 1:]
+    }
+    if start := strings.LastIndexByte(s, '<'); start >= 0 && !strings.Contains(s[start:], ">") {
+        s = s[:start]
+    }
+
+    text := strings.Builder{}
+    z := html.NewTokenizer(strings.NewReader(s))
+    for {
+        switch z.Next() {
+        case html.ErrorToken:
+            return strings.Join(strings.Fields(text.String()), " ")
+        case html.TextToken:
+            text.Write(z.Text())
+        case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+            // Words either side of a block or a line break are not run together
+            name, _ := z.TagName()
+            if tag := atom.Lookup(name); htmlBlocks[tag] || tag == atom.Br || tag == atom.Li {
+                text.WriteByte(' ')
+            }
+        }
+    }
+}
+
+type htmlRenderer struct {
+    width  int
+    lines  []string
+    text   strings.Builder // inline text waiting to be wrapped into lines
+    prefix string          // starts every line of the current block, from lists and quotes
+    marker string          // list marker for the next line, written over the end of the prefix
+    gap    bool            // a blank line is due before the next block
+    pre    bool            // inside <pre>, where text is kept as it is
+    lists  []int           // item count of each open list, -1 for bulleted ones
+    links  []string
+}
+
+// Blocks start on a line of their own, with a blank line before and after
+var htmlBlocks = map[atom.Atom]bool{
+    atom.P: true, atom.Div: true, atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+    atom.Blockquote: true, atom.Pre: true, atom.Table: true, atom.Figure: true, atom.Section: true, atom.Article: true,
+    atom.Header: true, atom.Footer: true, atom.Dl: true, atom.Hr: true,
+}
+
+// Elements whose content is never shown
+var htmlSkipped = map[atom.Atom]bool{
+    atom.Head: true, atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true, atom.Iframe: true,
+}
+
+func (r *htmlRenderer) walk(n *html.Node) {
+    switch n.Type {
+    case html.TextNode:
+        r.text.WriteString(n.Data)
+        return
+    case html.ElementNode:
+    default:
+        r.children(n)
+        return
+    }
+
+    switch {
+    case htmlSkipped[n.DataAtom]:
+        return
+
+    case n.DataAtom == atom.Br:
+        if r.pre {
+            r.text.WriteString("\n")
+            return
+        }
+        if strings.TrimSpace(r.text.String()) == "" {
+            r.gap = true
+        }
+        r.flush()
+
+    case n.DataAtom == atom.Img:
+        r.image(n)
+
+    case n.DataAtom == atom.A:
+        r.link(n)
+
+    case n.DataAtom == atom.Ul, n.DataAtom == atom.Ol:
+        r.block()
+        start := 0
+        if n.DataAtom == atom.Ul {
+            start = -1
+        }
+        r.lists = append(r.lists, start)
+        r.children(n)
+        r.lists = r.lists[:len(r.lists) - 1]
+        r.block()
+
+    case n.DataAtom == atom.Li:
+        r.flush()
+        r.item(n)
+
+    case n.DataAtom == atom.Tr, n.DataAtom == atom.Dt, n.DataAtom == atom.Dd, n.DataAtom == atom.Figcaption:
+        r.flush()
+        r.children(n)
+        r.flush()
+
+    case n.DataAtom == atom.Td, n.DataAtom == atom.Th:
+        r.children(n)
+        r.text.WriteString("  ")
+
+    case n.DataAtom == atom.Hr:
+        r.block()
+        r.emit([]string{ strings.Repeat("-", max(3, min(r.width - len(r.prefix), 40))) })
+        r.block()
+
+    case n.DataAtom == atom.Pre:
+        r.block()
+        r.pre = true
+        r.children(n)
+        r.flush()
+        r.pre = false
+        r.block()
+
+    case n.DataAtom == atom.Blockquote:
+        r.block()
+        old := r.prefix
+        r.prefix += "> "
+        r.children(n)
+        r.flush()
+        r.prefix = old
+        r.block()
+
+    case htmlBlocks[n.DataAtom]:
+        r.block()
+        r.children(n)
+        r.block()
+
+    default:
+        r.children(n)
+    }
+}
+
+func (r *htmlRenderer) children(n *html.Node) {
+    for child := n.FirstChild; child != nil; child = child.NextSibling {
+        r.walk(child)
+    }
+}
+
+// block ends the text so far, leaving a blank line before whatever comes next.
+// Lists inside list items stay tight.
+func (r *htmlRenderer) block() {
+    r.flush()
+    if len(r.lists) == 0 {
+        r.gap = true
+    }
+}
+
+// item writes a list item, its lines indented under a bullet or number
+func (r *htmlRenderer) item(n *html.Node) {
+    marker := "- "
+    if depth := len(r.lists); depth > 0 && r.lists[depth - 1] >= 0 {
+        r.lists[depth - 1]++
+        marker = fmt.Sprintf("%v. ", r.lists[depth - 1])
+    }
+
+    old := r.prefix
+    r.prefix += strings.Repeat(" ", len(marker))
+    r.marker  = marker
+    r.children(n)
+    r.flush()
+    r.prefix, r.marker = old, ""
+}
+
+// link writes the link's text followed by a footnote number for its url, unless the text is the url
+func (r *htmlRenderer) link(n *html.Node) {
+    start := r.text.Len()
+    r.children(n)
+
+    href := strings.TrimSpace(htmlAttr(n, "href"))
+    if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+        return
+    }
+    if text := strings.TrimSpace(r.text.String()[min(start, r.text.Len()):]); text == href {
+        return
+    }
+
+    number := 0
+    for i, link := range r.links {
+        if link == href {
+            number = i + 1
+        }
+    }
+    if number == 0 {
+        r.links = append(r.links, href)
+        number  = len(r.links)
+    }
+    r.text.WriteString(fmt.Sprintf("[%v]", number))
+}
+
+// image writes a placeholder with the image's alt text, leaving out tracking pixels
+func (r *htmlRenderer) image(n *html.Node) {
+    if isTrackingPixel(n) {
+        return
+    }
+    if alt := strings.Join(strings.Fields(htmlAttr(n, "alt")), " "); alt != "" {
+        r.text.WriteString("[image: " + alt + "]")
+        return
+    }
+    r.text.WriteString("[image]")
+}
+
+// isTrackingPixel spots the 1x1 (or 0x0) and hidden images feeds use to count readers
+func isTrackingPixel(n *html.Node) bool {
+    for _, name := range []string{ "width", "height" } {
+        switch strings.TrimSuffix(strings.TrimSpace(htmlAttr(n, name)), "px") {
+        case "0", "1":
+            return true
+        }
+    }
+    style := strings.Join(strings.Fields(strings.ToLower(htmlAttr(n, "style"))), "")
+    for _, hidden := range []string{ "display:none", "visibility:hidden", "width:0", "width:1px", "height:0", "height:1px" } {
+        if strings.Contains(";" + style, ";" + hidden) {
+            return true
+        }
+    }
+    return false
+}
+
+func htmlAttr(n *html.Node, name string) string {
+    for _, attr := range n.Attr {
+        if attr.Key == name {
+            return attr.Val
+        }
+    }
+    return ""
+}
+
+// flush wraps the text so far into lines
+func (r *htmlRenderer) flush() {
+    text := r.text.String()
+    r.text.Reset()
+
+    if r.pre {
+        text = strings.Trim(text, "\n")
+        if text == "" {
+            return
+        }
+        lines := []string{}
+        for _, line := range strings.Split(text, "\n") {
+            lines = append(lines, chop(line, r.width - len(r.prefix))...)
+        }
+        r.emit(lines)
+        return
+    }
+
+    text = strings.Join(strings.Fields(text), " ")
+    if text == "" {
+        return
+    }
+    r.emit(wrapText(text, r.width - len(r.prefix)))
+}
+
+// emit adds lines under the current prefix, after a blank line if one is due
+func (r *htmlRenderer) emit(lines []string) {
+    if r.gap && len(r.lines) > 0 {
+        r.lines = append(r.lines, "")
+    }
+    r.gap = false
+
+    for _, line := range lines {
+        lead := r.prefix
+        if r.marker != "" {
+            lead, r.marker = r.prefix[:len(r.prefix) - len(r.marker)] + r.marker, ""
+        }
+        r.lines = append(r.lines, strings.TrimRight(lead + line, " "))
+    }
+}
+
+// wrapText breaks a line on spaces so no piece is wider than width, cutting words that are too long alone
+func wrapText(s string, width int) []string {
+    if width < 1 {
+        width = 1
+    }
+    lines := []string{}
+    line  := []rune{}
+    for _, word := range strings.Fields(s) {
+        runes := []rune(word)
+        for len(runes) > width {
+            if len(line) > 0 {
+                lines, line = append(lines, string(line)), nil
+            }
+            lines, runes = append(lines, string(runes[:width])), runes[width:]
+        }
+        if len(line) > 0 && len(line) + 1 + len(runes) > width {
+            lines, line = append(lines, string(line)), nil
+        }
+        if len(line) > 0 {
+            line = append(line, ' ')
+        }
+        line = append(line, runes...)
+    }
+    return append(lines, string(line))
+}
+
+// chop cuts a line into pieces no wider than width, keeping its spaces
+func chop(s string, width int) []string {
+    if width < 1 {
+        width = 1
+    }
+    runes  := []rune(strings.TrimRight(s, " \t\r"))
+    pieces := []string{}
+    for len(runes) > width {
+        pieces, runes = append(pieces, string(runes[:width])), runes[width:]
+    }
+    return append(pieces, string(runes))
+}
+
+// terminalWidth is how wide stdout is, or 80 when it is not a terminal
+func terminalWidth() int {
+    if width, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && width > 0 {
+        return width
+    }
+    return 80
+}
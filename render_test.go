@@ -0,0 +1,64 @@
+package main
+
+import (
+    "strings"
+    "testing"
+)
+
+func TestRenderHTML(t *testing.T) {
+    tests := []struct {
+        name string
+        in   string
+        want string
+    }{
+        { "plain text", "plain text", "plain text" },
+        { "entities",   "<p>Tom &amp; Jerry &lt;3</p>", "Tom & Jerry <3" },
+        { "paragraphs", "<p>One</p>\n\n<p>Two</p><div>Three</div>", "One\n\nTwo\n\nThree" },
+        { "breaks",     "line<br>break<br/><br/>after a gap", "line\nbreak\n\nafter a gap" },
+        { "wrapping",   "<p>the quick brown fox jumps over the lazy dog</p>", "the quick brown fox\njumps over the lazy\ndog" },
+        { "bullets",    "<p>Shopping:</p><ul><li>eggs</li><li>a very long list item that wraps</li></ul><p>Done</p>",
+                        "Shopping:\n\n- eggs\n- a very long list\n  item that wraps\n\nDone" },
+        { "numbers",    "<ol><li>first</li><li>second<ul><li>nested</li></ul></li></ol>", "1. first\n2. second\n   - nested" },
+        { "quotes",     "<blockquote><p>To be or not to be</p></blockquote>said someone", "> To be or not to be\n\nsaid someone" },
+        { "links",      `Read <a href="https://a.ex/x">this</a> and <a href="https://b.ex/">that</a>, then <a href="https://a.ex/x">this again</a>`,
+                        "Read this[1] and\nthat[2], then this\nagain[1]\n\n[1] https://a.ex/x\n[2] https://b.ex/" },
+        { "bare links", `<a href="https://a.ex/">https://a.ex/</a> <a href="#top">top</a>`, "https://a.ex/ top" },
+        { "images",     `<img src="a.png" alt=" cat "><img src="b.png"><img src="t.gif" width="1" height="1">`, "[image: cat][image]" },
+        { "skipped",    "<style>p { color: red }</style><script>alert(1)</script>shown", "shown" },
+        { "pre",        "<pre>  indented\n    code</pre>", "  indented\n    code" },
+    }
+    for _, tt := range tests {
+        if got := renderHTML(tt.in, 20); got != tt.want {
+            t.Errorf("%v: renderHTML(%q) =\n%v\nwant:\n%v", tt.name, tt.in, got, tt.want)
+        }
+    }
+}
+
+func TestSnippetText(t *testing.T) {
+    tests := []struct {
+        in   string
+        want string
+    }{
+        { "<p>Type **parameters**</p>",                            "Type **parameters**" },
+        { `ample.com/x">Read **this**</a> and <a href="https://b`, "Read **this** and" },
+        { "<p>One</p><p>**Two**</p>line<br>break",                 "One **Two** line break" },
+        { "<b>Go</b>lang &amp; friends",                           "Golang & friends" },
+        { "",                                                      "" },
+    }
+    for _, tt := range tests {
+        if got := snippetText(tt.in); got != tt.want {
+            t.Errorf("snippetText(%q) = %q, want %q", tt.in, got, tt.want)
+        }
+    }
+}
+
+func TestWrapText(t *testing.T) {
+    lines := wrapText("the quick brown fox jumps", 10)
+    if strings.Join(lines, "|") != "the quick|brown fox|jumps" {
+        t.Errorf("wrapText = %q", lines)
+    }
+    lines = wrapText("https://example.com/a/long/url", 10)
+    if strings.Join(lines, "|") != "https://ex|ample.com/|a/long/url" {
+        t.Errorf("wrapText cut long words as %q", lines)
+    }
+}
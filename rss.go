@@ -14,35 +14,34 @@ import (
 func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
     req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
     if err != nil {
-        return nil, fmt.Errorf("Error formulating request: %v", err)
+        return nil, fmt.Errorf("Error formulating request: %w", err)
     }
 
     req.Header.Add("User-Agent", "gator")
 
-    client    := http.DefaultClient
-    resp, err := client.Do(req)
+    resp, err := httpClient.Do(req)
     if err != nil {
-        return nil, fmt.Errorf("Error while fetching aggregation data: %v", err)
+        return nil, fmt.Errorf("Error while fetching aggregation data: %w", err)
     }
     defer resp.Body.Close()
 
     data, err := io.ReadAll(resp.Body)
     if err != nil {
-        return nil, fmt.Errorf("Error while reading aggregation data: %v", err)
+        return nil, fmt.Errorf("Error while reading aggregation data: %w", err)
     }
 
     rss := RSSFeed{}
     err = xml.Unmarshal(data, &rss)
     if err != nil {
-        return nil, fmt.Errorf("Error while parsing xml to struct: %v", err)
+        return nil, fmt.Errorf("Error while parsing xml to struct: %w", err)
     }
     
-    // Unescape HTML entities
-    rss.Channel.Title       = html.UnescapeString(rss.Channel.Title)
-    rss.Channel.Description = html.UnescapeString(rss.Channel.Description)
-    for _, item := range rss.Channel.Item {
-        item.Title       = html.UnescapeString(item.Title)
-        item.Description = html.UnescapeString(item.Description)
+    // Titles are plain text but often arrive with html entities in them.  Descriptions and content
+    // are html, their entities are left for the sanitiser and renderer, unescaping them here as
+    // well would turn text like &lt;script&gt; into markup.
+    rss.Channel.Title = html.UnescapeString(rss.Channel.Title)
+    for i := range rss.Channel.Item {
+        rss.Channel.Item[i].Title = html.UnescapeString(rss.Channel.Item[i].Title)
     }
 
     return &rss, nil
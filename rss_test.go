@@ -0,0 +1,199 @@
+package main
+
+import (
+    "context"
+    "internal/database"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+)
+
+const testFeedXML = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0">
+<channel>
+    <title>Tom &amp;amp; Jerry</title>
+    <link>https://example.com/</link>
+    <description>Cartoons</description>
+    <item>
+        <title>First</title>
+        <link>https://example.com/1</link>
+        <description>One</description>
+        <pubDate>Mon, 02 Jan 2006 15:04:05 -0700</pubDate>
+    </item>
+    <item>
+        <title>Second &amp;amp; last</title>
+        <link>https://example.com/2</link>
+        <description>&lt;p&gt;Two &amp;amp;amp; more, in &amp;lt;script&amp;gt; tags&lt;/p&gt;</description>
+        <pubDate>not a date</pubDate>
+    </item>
+</channel>
+</rss>`
+
+// feedServer serves body on /feed and counts requests
+func feedServer(t *testing.T, body string) (*httptest.Server, *int) {
+    t.Helper()
+    requests := 0
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        requests++
+        if r.Header.Get("User-Agent") != "gator" {
+            t.Errorf("User-Agent = %q, want gator", r.Header.Get("User-Agent"))
+        }
+        w.Header().Set("Content-Type", "application/rss+xml")
+        w.Write([]byte(body))
+    }))
+    t.Cleanup(server.Close)
+    return server, &requests
+}
+
+func TestFetchFeed(t *testing.T) {
+    server, _ := feedServer(t, testFeedXML)
+
+    rss, err := fetchFeed(context.Background(), server.URL + "/feed")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if rss.Channel.Title != "Tom & Jerry" {
+        t.Errorf("title = %q, want the html entity unescaped too", rss.Channel.Title)
+    }
+    if len(rss.Channel.Item) != 2 || rss.Channel.Item[0].Link != "https://example.com/1" {
+        t.Errorf("items = %+v", rss.Channel.Item)
+    }
+    // Descriptions are html, so the entities left after parsing the xml are kept for the sanitiser
+    item := rss.Channel.Item[1]
+    if item.Title != "Second & last" || item.Description != "<p>Two &amp;amp; more, in &lt;script&gt; tags</p>" {
+        t.Errorf("item title %q and description %q, want the title unescaped and the description as sent", item.Title, item.Description)
+    }
+    if got := sanitizeHTML(item.Description, nil); got != item.Description {
+        t.Errorf("sanitised description = %q, want the escaped text kept", got)
+    }
+}
+
+func TestFetchFeedErrors(t *testing.T) {
+    server, _ := feedServer(t, "this is not xml")
+    if _, err := fetchFeed(context.Background(), server.URL); err == nil {
+        t.Error("expected an error for a body that is not xml")
+    }
+
+    server.Close()
+    if _, err := fetchFeed(context.Background(), server.URL); err == nil {
+        t.Error("expected an error for a server that is gone")
+    }
+
+    if _, err := fetchFeed(context.Background(), "://bad"); err == nil {
+        t.Error("expected an error for a bad url")
+    }
+}
+
+func TestScrapeFeeds(t *testing.T) {
+    s, fake := newTestState(t)
+    alice := addUser(t, fake, "alice", roleUser)
+    server, requests := feedServer(t, testFeedXML)
+    feed := addFeed(t, fake, alice, "Cartoons", server.URL + "/feed")
+
+    if err := scrapeFeeds(context.Background(), s); err != nil {
+        t.Fatal(err)
+    }
+    if *requests != 1 || len(fake.posts) != 2 {
+        t.Fatalf("%v requests and %v posts, want 1 and 2", *requests, len(fake.posts))
+    }
+    if !fake.posts[0].PublishedAt.Valid || fake.posts[1].PublishedAt.Valid {
+        t.Error("only the first post has a parseable publish date")
+    }
+    feed, _ = fake.GetFeedUrl(context.Background(), feed.Url)
+    if !feed.LastFetchedAt.Valid {
+        t.Error("feed was not marked as fetched")
+    }
+
+    // Posts seen before are skipped
+    if err := scrapeFeeds(context.Background(), s); err != nil {
+        t.Fatal(err)
+    }
+    if len(fake.posts) != 2 {
+        t.Errorf("%v posts after a second scrape, want 2", len(fake.posts))
+    }
+}
+
+func TestAggregateKeepGoing(t *testing.T) {
+    s, fake := newTestState(t)
+    alice := addUser(t, fake, "alice", roleUser)
+    captureLogs(t, "error")
+
+    requests := make(chan string, 100)
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        requests <- r.URL.Path
+        if r.URL.Path == "/broken" {
+            http.Error(w, "down", http.StatusInternalServerError)
+            return
+        }
+        w.Write([]byte(testFeedXML))
+    }))
+    t.Cleanup(server.Close)
+    addFeed(t, fake, alice, "Broken",   server.URL + "/broken")
+    addFeed(t, fake, alice, "Cartoons", server.URL + "/feed")
+
+    // Without keepGoing the broken feed, fetched first, stops agg
+    assertErr(t, aggregate(context.Background(), s, time.Millisecond, false), ErrorFetchingFeed)
+    <-requests
+
+    // With it agg moves on to the next feed, and a third request means that round is over
+    ctx, cancel := context.WithCancel(context.Background())
+    done := make(chan error)
+    go func() { done <- aggregate(ctx, s, time.Millisecond, true) }()
+    for range 3 {
+        <-requests
+    }
+    cancel()
+    if err := <-done; err != nil {
+        t.Errorf("aggregate = %v, want nil once cancelled", err)
+    }
+    if len(fake.posts) != 2 {
+        t.Errorf("%v posts saved, want the 2 from the working feed", len(fake.posts))
+    }
+}
+
+func TestAggregateCancelsFetch(t *testing.T) {
+    eachStore(t, func(t *testing.T, s *state, q database.Querier) {
+        alice := addUser(t, q, "alice", roleUser)
+        captureLogs(t, "error")
+
+        // The server never answers, only cancelling the request ends it
+        started := make(chan struct{}, 1)
+        server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            started <- struct{}{}
+            <-r.Context().Done()
+        }))
+        t.Cleanup(server.Close)
+        addFeed(t, q, alice, "Slow", server.URL + "/feed")
+
+        ctx, cancel := context.WithCancel(context.Background())
+        done := make(chan error)
+        go func() { done <- aggregate(ctx, s, time.Hour, false) }()
+        <-started
+        cancel()
+
+        select {
+        case err := <-done:
+            if err != nil {
+                t.Errorf("aggregate = %v, want nil once cancelled", err)
+            }
+        case <-time.After(5 * time.Second):
+            t.Fatal("aggregate kept waiting for the fetch after being cancelled")
+        }
+    })
+}
+
+func TestScrapeFeedsFetchError(t *testing.T) {
+    s, fake := newTestState(t)
+    alice := addUser(t, fake, "alice", roleUser)
+    server, _ := feedServer(t, "not a feed")
+    addFeed(t, fake, alice, "Broken", server.URL)
+
+    assertErr(t, scrapeFeeds(context.Background(), s), ErrorFetchingFeed)
+
+    // Nothing to fetch is not an error, agg just waits for feeds to be added
+    empty, _ := newTestState(t)
+    if err := scrapeFeeds(context.Background(), empty); err != nil {
+        t.Errorf("scrapeFeeds with no feeds = %v, want nil", err)
+    }
+}
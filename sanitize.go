@@ -0,0 +1,204 @@
+package main
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "internal/database"
+    "net/url"
+    "strings"
+    "golang.org/x/net/html"
+    "golang.org/x/net/html/atom"
+)
+
+// Publishers' html is cleaned before it is saved, so gator's data is safe to serve as it is.  Only
+// the elements and attributes below survive.  Other elements are dropped but their text kept,
+// and the ones in sanitizeDropped go along with everything inside them.
+var sanitizeElements = map[atom.Atom]bool{
+    atom.A: true, atom.Abbr: true, atom.B: true, atom.Blockquote: true, atom.Br: true, atom.Caption: true, atom.Cite: true,
+    atom.Code: true, atom.Dd: true, atom.Del: true, atom.Details: true, atom.Dfn: true, atom.Div: true, atom.Dl: true,
+    atom.Dt: true, atom.Em: true, atom.Figcaption: true, atom.Figure: true, atom.H1: true, atom.H2: true, atom.H3: true,
+    atom.H4: true, atom.H5: true, atom.H6: true, atom.Hr: true, atom.I: true, atom.Img: true, atom.Ins: true, atom.Kbd: true,
+    atom.Li: true, atom.Mark: true, atom.Ol: true, atom.P: true, atom.Pre: true, atom.Q: true, atom.S: true, atom.Samp: true,
+    atom.Small: true, atom.Span: true, atom.Strong: true, atom.Sub: true, atom.Summary: true, atom.Sup: true, atom.Table: true,
+    atom.Tbody: true, atom.Td: true, atom.Tfoot: true, atom.Th: true, atom.Thead: true, atom.Time: true, atom.Tr: true,
+    atom.U: true, atom.Ul: true,
+}
+
+var sanitizeDropped = map[atom.Atom]bool{
+    atom.Script: true, atom.Style: true, atom.Iframe: true, atom.Object: true, atom.Embed: true, atom.Noscript: true,
+    atom.Template: true, atom.Head: true, atom.Title: true, atom.Form: true, atom.Input: true, atom.Button: true,
+    atom.Select: true, atom.Textarea: true, atom.Svg: true, atom.Math: true, atom.Frame: true, atom.Frameset: true,
+}
+
+// Attributes kept on every element, then those kept on particular ones
+var sanitizeGlobalAttrs = map[string]bool{ "title": true, "lang": true, "dir": true }
+
+var sanitizeAttrs = map[atom.Atom]map[string]bool{
+    atom.A:          { "href": true },
+    atom.Img:        { "src": true, "alt": true, "width": true, "height": true },
+    atom.Td:         { "colspan": true, "rowspan": true },
+    atom.Th:         { "colspan": true, "rowspan": true, "scope": true },
+    atom.Ol:         { "start": true, "reversed": true },
+    atom.Time:       { "datetime": true },
+    atom.Blockquote: { "cite": true },
+    atom.Q:          { "cite": true },
+    atom.Del:        { "cite": true, "datetime": true },
+    atom.Ins:        { "cite": true, "datetime": true },
+}
+
+// Attributes holding urls, and the schemes they may use once resolved
+var sanitizeURLAttrs = map[string]map[string]bool{
+    "href": { "http": true, "https": true, "mailto": true },
+    "src":  { "http": true, "https": true },
+    "cite": { "http": true, "https": true },
+}
+
+// sanitizePosts cleans stored posts from their raw html: all of them, or only those saved before
+// sanitising was added, whose description is kept empty until this has run over them
+func sanitizePosts(ctx context.Context, q database.Querier, unsanitizedOnly bool) (int64, error) {
+    posts, err := q.GetPostsRaw(ctx, unsanitizedOnly)
+    if err != nil {
+        return 0, fmt.Errorf("%w | Reason: %w", ErrorGettingPosts, err)
+    }
+
+    updated := int64(0)
+    for _, post := range posts {
+        base        := postBase(post.Url, post.FeedUrl)
+        description := sql.NullString{}
+        if post.DescriptionRaw.Valid {
+            description = sql.NullString{ String: sanitizeHTML(post.DescriptionRaw.String, base), Valid: true }
+        }
+
+        n, err := q.UpdatePostSanitized(ctx, database.UpdatePostSanitizedParams{ ID: post.ID, Description: description,
+                                                                                 Content: nullString(sanitizeHTML(post.ContentRaw.String, base)) })
+        if err != nil {
+            return updated, fmt.Errorf("%w | Reason: %w", ErrorSanitizingPosts, err)
+        }
+        updated += n
+    }
+    return updated, nil
+}
+
+// sanitizeHTML cleans html from a feed against the allowlist above.  Relative links and image
+// sources are resolved against base, the post's link, so they still work away from the publisher's
+// site.  Tracking pixels are left out.
+func sanitizeHTML(s string, base *url.URL) string {
+    if strings.TrimSpace(s) == "" {
+        return ""
+    }
+    body := &html.Node{ Type: html.ElementNode, Data: "body", DataAtom: atom.Body }
+    nodes, err := html.ParseFragment(strings.NewReader(s), body)
+    if err != nil {
+        return html.EscapeString(s)
+    }
+
+    out := &strings.Builder{}
+    for _, n := range nodes {
+        for _, clean := range sanitizeNode(n, base) {
+            html.Render(out, clean)
+        }
+    }
+    return strings.TrimSpace(out.String())
+}
+
+// sanitizeNode returns n rebuilt from what the allowlist keeps: nothing, the node itself, or just
+// its children when the element is not allowed
+func sanitizeNode(n *html.Node, base *url.URL) []*html.Node {
+    switch n.Type {
+    case html.TextNode:
+        return []*html.Node{ { Type: html.TextNode, Data: n.Data } }
+    case html.ElementNode:
+    default:
+        // Comments, doctypes and the like
+        return nil
+    }
+
+    switch {
+    case sanitizeDropped[n.DataAtom]:
+        return nil
+    case n.DataAtom == atom.Img && isTrackingPixel(n):
+        return nil
+    case !sanitizeElements[n.DataAtom]:
+        // Elements not on the list, unknown ones included, keep their text but not their markup
+        return sanitizeChildren(n, base)
+    }
+
+    clean := &html.Node{ Type: html.ElementNode, Data: n.DataAtom.String(), DataAtom: n.DataAtom }
+    for _, attr := range n.Attr {
+        key := strings.ToLower(attr.Key)
+        if attr.Namespace != "" || !(sanitizeGlobalAttrs[key] || sanitizeAttrs[n.DataAtom][key]) {
+            continue
+        }
+        val := attr.Val
+        if schemes, ok := sanitizeURLAttrs[key]; ok {
+            resolved, ok := resolveURL(val, base, schemes)
+            if !ok {
+                continue
+            }
+            val = resolved
+        }
+        clean.Attr = append(clean.Attr, html.Attribute{ Key: key, Val: val })
+    }
+
+    switch n.DataAtom {
+    case atom.Img:
+        if htmlAttr(clean, "src") == "" {
+            return nil
+        }
+    case atom.A:
+        if htmlAttr(clean, "href") != "" {
+            clean.Attr = append(clean.Attr, html.Attribute{ Key: "rel", Val: "nofollow noopener noreferrer" })
+        }
+    }
+
+    for _, child := range sanitizeChildren(n, base) {
+        clean.AppendChild(child)
+    }
+    return []*html.Node{ clean }
+}
+
+func sanitizeChildren(n *html.Node, base *url.URL) []*html.Node {
+    nodes := []*html.Node{}
+    for child := n.FirstChild; child != nil; child = child.NextSibling {
+        nodes = append(nodes, sanitizeNode(child, base)...)
+    }
+    return nodes
+}
+
+// resolveURL makes a link absolute against base, and reports false for schemes not allowed,
+// ex: javascript: and data:
+func resolveURL(raw string, base *url.URL, schemes map[string]bool) (string, bool) {
+    raw = strings.TrimSpace(raw)
+    if raw == "" {
+        return "", false
+    }
+    if strings.HasPrefix(raw, "#") {
+        return raw, true
+    }
+    u, err := url.Parse(raw)
+    if err != nil {
+        return "", false
+    }
+    if base != nil {
+        u = base.ResolveReference(u)
+    }
+    if !schemes[strings.ToLower(u.Scheme)] {
+        return "", false
+    }
+    return u.String(), true
+}
+
+// postBase is the url relative links in a post are resolved against: the item's link, itself
+// resolved against the feed's url in case it is relative too
+func postBase(itemLink, feedURL string) *url.URL {
+    feed, err := url.Parse(feedURL)
+    if err != nil {
+        return nil
+    }
+    link, err := url.Parse(strings.TrimSpace(itemLink))
+    if err != nil || itemLink == "" {
+        return feed
+    }
+    return feed.ResolveReference(link)
+}
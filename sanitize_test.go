@@ -0,0 +1,145 @@
+package main
+
+import (
+    "context"
+    "database/sql"
+    "net/url"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestSanitizeHTML(t *testing.T) {
+    base, _ := url.Parse("https://blog.example.com/posts/hello")
+
+    tests := []struct {
+        name string
+        in   string
+        want string
+    }{
+        { "formatting kept",    `<p>Some <b>bold</b> and <em>em</em></p><ul><li>one</li></ul>`, `<p>Some <b>bold</b> and <em>em</em></p><ul><li>one</li></ul>` },
+        { "scripts dropped",    `<p>Hi</p><script>alert(1)</script><style>p{}</style>`,        `<p>Hi</p>` },
+        { "handlers dropped",   `<img src="a.png" onerror="alert(1)" alt="A">`,                `<img src="https://blog.example.com/posts/a.png" alt="A"/>` },
+        { "javascript links",   `<a href="javascript:alert(1)">x</a>`,                          `<a>x</a>` },
+        { "data images",        `<img src="data:image/png;base64,AAAA">`,                       `` },
+        { "relative links",     `<a href="/about" onclick="x()">About</a>`,                     `<a href="https://blog.example.com/about" rel="nofollow noopener noreferrer">About</a>` },
+        { "fragments kept",     `<a href="#notes">Notes</a>`,                                   `<a href="#notes" rel="nofollow noopener noreferrer">Notes</a>` },
+        { "tracking pixels",    `<p>Text<img src="https://t.example.com/p.gif" width="1" height="1"></p>`, `<p>Text</p>` },
+        { "unknown elements",   `<custom-tag class="x">inside</custom-tag> <font color="red">red</font>`,   `inside red` },
+        { "frames dropped",     `<iframe src="https://evil.example.com"></iframe><p>After</p>`,            `<p>After</p>` },
+        { "styles dropped",     `<p style="color:red" class="lead" id="x" title="T">Text</p>`,             `<p title="T">Text</p>` },
+        { "comments dropped",   `<!-- hidden --><p>Shown</p>`,                                  `<p>Shown</p>` },
+        { "text escaped",       `1 &lt; 2 &amp; 3`,                                             `1 &lt; 2 &amp; 3` },
+        { "empty",              `   `,                                                          `` },
+    }
+    for _, tt := range tests {
+        if got := sanitizeHTML(tt.in, base); got != tt.want {
+            t.Errorf("%v: sanitizeHTML(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+        }
+    }
+}
+
+func TestPostBase(t *testing.T) {
+    tests := []struct {
+        link string
+        feed string
+        want string
+    }{
+        { "https://blog.example.com/1", "https://feeds.example.com/rss", "https://blog.example.com/1" },
+        { "/posts/1",                   "https://blog.example.com/rss",  "https://blog.example.com/posts/1" },
+        { "",                           "https://blog.example.com/rss",  "https://blog.example.com/rss" },
+    }
+    for _, tt := range tests {
+        if got := postBase(tt.link, tt.feed); got == nil || got.String() != tt.want {
+            t.Errorf("postBase(%q, %q) = %v, want %v", tt.link, tt.feed, got, tt.want)
+        }
+    }
+}
+
+func TestScrapeFeedsSanitizes(t *testing.T) {
+    s, fake := newTestState(t)
+    alice := addUser(t, fake, "alice", roleUser)
+    server, _ := feedServer(t, `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/">
+<channel>
+    <title>Blog</title>
+    <item>
+        <title>Hello</title>
+        <link>/posts/hello</link>
+        <description><![CDATA[<p onclick="x()">Summary<script>alert(1)</script></p>]]></description>
+        <content:encoded><![CDATA[<p>Full <img src="pic.png" alt="Pic"></p>]]></content:encoded>
+    </item>
+</channel>
+</rss>`)
+    addFeed(t, fake, alice, "Blog", server.URL + "/feed")
+
+    if err := scrapeFeeds(context.Background(), s); err != nil {
+        t.Fatal(err)
+    }
+    if len(fake.posts) != 1 {
+        t.Fatalf("%v posts, want 1", len(fake.posts))
+    }
+    post := fake.posts[0]
+    if post.Description.String != "<p>Summary</p>" {
+        t.Errorf("description = %q", post.Description.String)
+    }
+    if post.DescriptionRaw.String != `<p onclick="x()">Summary<script>alert(1)</script></p>` {
+        t.Errorf("raw description = %q", post.DescriptionRaw.String)
+    }
+    if want := `<p>Full <img src="` + server.URL + `/posts/pic.png" alt="Pic"/></p>`; post.Content.String != want {
+        t.Errorf("content = %q, want %q", post.Content.String, want)
+    }
+    if !post.ContentRaw.Valid || !strings.Contains(post.ContentRaw.String, `src="pic.png"`) {
+        t.Errorf("raw content = %q", post.ContentRaw.String)
+    }
+}
+
+func TestSanitizeUpgradedPosts(t *testing.T) {
+    s, fake := newTestState(t)
+    alice := addUser(t, fake, "alice", roleUser)
+    feed  := addFeed(t, fake, alice, "Blog", "https://blog.example.com/rss")
+    addPost(t, fake, feed, "Upgraded", "https://blog.example.com/old", "", 2 * time.Hour)
+    addPost(t, fake, feed, "Current",  "https://blog.example.com/new", "", time.Hour)
+
+    // As migration 014 leaves a post saved before sanitising: its html only in description_raw
+    fake.posts[0].Description    = sql.NullString{}
+    fake.posts[0].DescriptionRaw = sql.NullString{ String: `<p onclick="x()">Old<script>x()</script></p>`, Valid: true }
+    fake.posts[1].Description    = sql.NullString{ String: "<p>Cleaned at ingest</p>", Valid: true }
+    fake.posts[1].DescriptionRaw = sql.NullString{ String: "<p>Cleaned at ingest</p><!-- raw -->", Valid: true }
+
+    n, err := sanitizePosts(context.Background(), s.dbState, true)
+    if err != nil || n != 1 {
+        t.Fatalf("sanitizePosts = %v, %v; want only the upgraded post", n, err)
+    }
+    if got := fake.posts[0].Description; !got.Valid || got.String != "<p>Old</p>" {
+        t.Errorf("upgraded post description = %+v", got)
+    }
+
+    // Nothing left to do the next time gator starts
+    if n, _ := sanitizePosts(context.Background(), s.dbState, true); n != 0 {
+        t.Errorf("%v posts sanitised again", n)
+    }
+}
+
+func TestSanitizeCommand(t *testing.T) {
+    s, fake := newTestState(t)
+    admin := loginAs(t, s, fake, "admin", roleAdmin)
+    feed  := addFeed(t, fake, admin, "Blog", "https://blog.example.com/rss")
+    post  := addPost(t, fake, feed, "Old", "https://blog.example.com/old", "", time.Hour)
+
+    // A post saved before sanitising, with only its raw html
+    fake.posts[0].Description    = sql.NullString{ String: `<p>Old<script>x()</script> <a href="next">next</a></p>`, Valid: true }
+    fake.posts[0].DescriptionRaw = fake.posts[0].Description
+
+    out := run(t, s, "sanitize")
+    assertContains(t, out, "Sanitised 1 posts")
+    if got := fake.posts[0].Description.String; got != `<p>Old <a href="https://blog.example.com/next" rel="nofollow noopener noreferrer">next</a></p>` {
+        t.Errorf("post %v description = %q", post.Title, got)
+    }
+    if fake.posts[0].Content.Valid {
+        t.Error("content set for a post without any")
+    }
+
+    loginAs(t, s, fake, "alice", roleUser)
+    assertErr(t, runErr(s, "sanitize"), ErrorNotAdmin)
+}
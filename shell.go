@@ -0,0 +1,305 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "flag"
+    "fmt"
+    "io"
+    "log/slog"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+    "unicode"
+    "github.com/peterh/liner"
+)
+
+// shellReader is the part of liner the shell needs, tests type lines at it instead
+type shellReader interface {
+    Prompt(prompt string) (string, error)
+    AppendHistory(item string)
+}
+
+// shell runs command lines against one state, so config is read and the database opened only once
+type shell struct {
+    coms *commands
+    s    *state
+    in   shellReader
+
+    // agg runs in the background while other commands run at the prompt
+    aggInterval time.Duration
+    aggCancel   context.CancelFunc
+    aggDone     chan struct{}
+}
+
+func (c *commands) handlerShell(s *state, cmd command) error {
+    historyPath := cmd.flagString("history")
+    if historyPath == "" {
+        historyPath = defaultHistoryPath()
+    }
+
+    line := liner.NewLiner()
+    defer line.Close()
+    line.SetCtrlCAborts(true)
+    line.SetCompleter(func(input string) []string { return c.shellComplete(s, input) })
+
+    if f, err := os.Open(historyPath); err == nil {
+        line.ReadHistory(f)
+        f.Close()
+    }
+
+    err := c.runShell(s, line)
+
+    if historyPath != "" {
+        if saveErr := saveHistory(line, historyPath); saveErr != nil {
+            slog.Warn("could not save shell history", "path", historyPath, "error", saveErr)
+        }
+    }
+    return err
+}
+
+// defaultHistoryPath is $XDG_STATE_HOME/gator/history, or ~/.local/state/gator/history
+func defaultHistoryPath() string {
+    if stateHome := os.Getenv("XDG_STATE_HOME"); stateHome != "" {
+        return filepath.Join(stateHome, "gator", "history")
+    }
+    home, err := os.UserHomeDir()
+    if err != nil {
+        return ""
+    }
+    return filepath.Join(home, ".local", "state", "gator", "history")
+}
+
+func saveHistory(line *liner.State, path string) error {
+    if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+        return err
+    }
+    f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+    if err != nil {
+        return err
+    }
+    if _, err := line.WriteHistory(f); err != nil {
+        f.Close()
+        return err
+    }
+    return f.Close()
+}
+
+// runShell reads and runs lines until exit, quit or end of input
+func (c *commands) runShell(s *state, in shellReader) error {
+    sh := &shell{ coms: c, s: s, in: in }
+    defer sh.stopAgg()
+
+    fmt.Println("gator shell, type help for a list of commands or exit to leave")
+    for {
+        input, err := sh.in.Prompt(sh.prompt())
+        if errors.Is(err, liner.ErrPromptAborted) {
+            continue
+        }
+        if errors.Is(err, io.EOF) {
+            fmt.Println()
+            return nil
+        }
+        if err != nil {
+            return fmt.Errorf("%w | Reason: %w", ErrorReadingInput, err)
+        }
+
+        words, err := splitShellWords(input)
+        if err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            continue
+        }
+        if len(words) == 0 {
+            continue
+        }
+        sh.in.AppendHistory(input)
+
+        if words[0] == "exit" || words[0] == "quit" {
+            return nil
+        }
+        sh.exec(words)
+    }
+}
+
+func (sh *shell) prompt() string {
+    if sh.aggRunning() {
+        return fmt.Sprintf("gator (agg %v)> ", sh.aggInterval)
+    }
+    return "gator> "
+}
+
+// exec runs one command line, printing any error rather than leaving the shell
+func (sh *shell) exec(words []string) {
+    switch words[0] {
+    case "agg":
+        sh.agg(words[1:])
+        return
+    case "shell":
+        fmt.Fprintln(os.Stderr, "Already in the shell")
+        return
+    }
+
+    com, err := sh.coms.parse(words)
+    if errors.Is(err, flag.ErrHelp) {
+        com.info.printHelp()
+        return
+    }
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        if com.info != nil {
+            fmt.Fprintln(os.Stderr, "usage:", com.info.usage())
+        } else {
+            fmt.Fprintln(os.Stderr, "Type help for a list of commands.")
+        }
+        return
+    }
+
+    if err := sh.coms.run(sh.s, com); err != nil {
+        fmt.Fprintf(os.Stderr, "Error while running command. Reason: %v\n", err)
+    }
+}
+
+// agg starts collecting feeds in the background, or restarts it at a new interval.
+// agg stop stops it, and agg on its own says whether it is running.
+func (sh *shell) agg(args []string) {
+    switch {
+    case len(args) == 0:
+        if sh.aggRunning() {
+            fmt.Printf("agg is collecting feeds every %v, agg stop to stop it\n", sh.aggInterval)
+        } else {
+            fmt.Println("agg is not running, agg <time_between_requests> to start it")
+        }
+        return
+    case len(args) == 1 && args[0] == "stop":
+        if !sh.aggRunning() {
+            fmt.Println("agg is not running")
+            return
+        }
+        sh.stopAgg()
+        fmt.Println("agg stopped")
+        return
+    }
+
+    com, err := sh.coms.parse(append([]string{ "agg" }, args...))
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        fmt.Fprintln(os.Stderr, "usage: agg <time_between_requests> | agg stop | agg")
+        return
+    }
+    interval, err := parseInterval(com.args[0])
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Error while running command. Reason: %v\n", err)
+        return
+    }
+
+    sh.stopAgg()
+    ctx, cancel := context.WithCancel(context.Background())
+    done := make(chan struct{})
+    sh.aggInterval, sh.aggCancel, sh.aggDone = interval, cancel, done
+    go func() {
+        defer close(done)
+        if err := aggregate(ctx, sh.s, interval, true); err != nil {
+            slog.Error("background agg stopped", "error", err)
+        }
+    }()
+    fmt.Printf("agg collecting feeds every %v in the background\n", interval)
+}
+
+func (sh *shell) aggRunning() bool {
+    if sh.aggDone == nil {
+        return false
+    }
+    select {
+    case <-sh.aggDone:
+        return false
+    default:
+        return true
+    }
+}
+
+// stopAgg stops background collection, cancelling a fetch or archive in progress, and waits for it to wind down
+func (sh *shell) stopAgg() {
+    if sh.aggCancel == nil {
+        return
+    }
+    sh.aggCancel()
+    <-sh.aggDone
+    sh.aggCancel, sh.aggDone = nil, nil
+}
+
+// shellComplete offers whole lines for liner, the line so far with each candidate for its last word
+func (c *commands) shellComplete(s *state, input string) []string {
+    words := strings.Fields(input)
+    if input == "" || unicode.IsSpace(rune(input[len(input) - 1])) {
+        words = append(words, "")
+    }
+    current := words[len(words) - 1]
+    head    := input[:len(input) - len(current)]
+
+    candidates := c.complete(s, words)
+    if len(words) == 1 {
+        candidates = append(candidates, matching([]string{ "exit", "quit" }, current)...)
+    }
+    if len(words) == 2 && words[0] == "agg" {
+        candidates = matching([]string{ "stop" }, current)
+    }
+
+    lines := []string{}
+    for _, candidate := range candidates {
+        lines = append(lines, head + candidate + " ")
+    }
+    return lines
+}
+
+// splitShellWords splits a line on spaces like a shell would, honouring 'single' and "double"
+// quotes and backslash escapes
+func splitShellWords(line string) ([]string, error) {
+    words   := []string{}
+    word    := strings.Builder{}
+    inWord  := false
+    var quote rune
+
+    runes := []rune(line)
+    for i := 0; i < len(runes); i++ {
+        r := runes[i]
+        switch {
+        case quote == '\'':
+            if r == '\'' {
+                quote = 0
+            } else {
+                word.WriteRune(r)
+            }
+        case r == '\\' && quote != '\'':
+            if i + 1 < len(runes) {
+                i++
+                word.WriteRune(runes[i])
+            }
+            inWord = true
+        case quote == '"':
+            if r == '"' {
+                quote = 0
+            } else {
+                word.WriteRune(r)
+            }
+        case r == '\'' || r == '"':
+            quote, inWord = r, true
+        case unicode.IsSpace(r):
+            if inWord {
+                words = append(words, word.String())
+                word.Reset()
+                inWord = false
+            }
+        default:
+            word.WriteRune(r)
+            inWord = true
+        }
+    }
+    if quote != 0 {
+        return nil, fmt.Errorf("%w | Reason: unterminated %c quote", ErrorReadingInput, quote)
+    }
+    if inWord {
+        words = append(words, word.String())
+    }
+    return words, nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+    "flag"
+    "internal/database"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync/atomic"
+    "testing"
+)
+
+// scriptedLines plays the part of the terminal, answering prompts with lines in order
+type scriptedLines struct {
+    lines   []string
+    prompts []string
+    history []string
+    holds   map[int]chan struct{} // lines held back until their channel is closed, by position
+}
+
+func (l *scriptedLines) Prompt(prompt string) (string, error) {
+    l.prompts = append(l.prompts, prompt)
+    if hold, ok := l.holds[len(l.prompts) - 1]; ok {
+        <-hold
+    }
+    if len(l.lines) == 0 {
+        return "", io.EOF
+    }
+    line := l.lines[0]
+    l.lines = l.lines[1:]
+    return line, nil
+}
+
+func (l *scriptedLines) AppendHistory(item string) {
+    l.history = append(l.history, item)
+}
+
+func runShellLines(t *testing.T, s *state, lines ...string) (string, *scriptedLines) {
+    t.Helper()
+    in   := &scriptedLines{ lines: lines }
+    coms := newCommands(flag.NewFlagSet("gator", flag.ContinueOnError))
+    out, err := capture(func() error { return coms.runShell(s, in) })
+    if err != nil {
+        t.Fatalf("shell failed: %v\noutput:\n%v", err, out)
+    }
+    return out, in
+}
+
+func TestShellRunsCommands(t *testing.T) {
+    s, fake := newTestState(t)
+    loginAs(t, s, fake, "alice", roleUser)
+
+    out, in := runShellLines(t, s, "folder create 'Long Reads'", "", "frobnicate", "folder list", "exit", "folder create Never")
+    assertContains(t, out, "Folder Long Reads created", "Long Reads\n")
+    if len(fake.folders) != 1 {
+        t.Errorf("%v folders, want 1, lines after exit must not run", len(fake.folders))
+    }
+
+    // Blank lines stay out of the history, mistakes do not
+    if strings.Join(in.history, "|") != "folder create 'Long Reads'|frobnicate|folder list|exit" {
+        t.Errorf("history = %q", in.history)
+    }
+}
+
+func TestShellKeepsState(t *testing.T) {
+    eachStore(t, func(t *testing.T, s *state, q database.Querier) {
+        addUser(t, q, "alice", roleUser)
+        withInput(t, "password1")
+
+        out, _ := runShellLines(t, s, "login alice", "users")
+        assertContains(t, out, "* alice (current)")
+    })
+}
+
+func TestShellAgg(t *testing.T) {
+    s, fake := newTestState(t)
+    alice := loginAs(t, s, fake, "alice", roleUser)
+    captureLogs(t, "error")
+
+    // Rounds run one after another, so a second request means the first round is over
+    requests  := atomic.Int32{}
+    collected := make(chan struct{})
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if requests.Add(1) == 2 {
+            close(collected)
+        }
+        w.Write([]byte(testFeedXML))
+    }))
+    t.Cleanup(server.Close)
+    addFeed(t, fake, alice, "Cartoons", server.URL + "/feed")
+
+    in   := &scriptedLines{ lines: []string{ "agg", "agg 10ms", "agg", "agg stop", "agg stop", "agg soon" }, holds: map[int]chan struct{}{ 3: collected } }
+    coms := newCommands(flag.NewFlagSet("gator", flag.ContinueOnError))
+    out, err := capture(func() error { return coms.runShell(s, in) })
+    if err != nil {
+        t.Fatal(err)
+    }
+    assertContains(t, out, "agg is not running, agg <time_between_requests> to start it",
+                           "agg collecting feeds every 10ms in the background",
+                           "agg is collecting feeds every 10ms",
+                           "agg stopped\n",
+                           "agg is not running\n")
+    if len(fake.posts) != 2 {
+        t.Errorf("%v posts saved, want 2", len(fake.posts))
+    }
+    if in.prompts[2] != "gator (agg 10ms)> " || in.prompts[4] != "gator> " {
+        t.Errorf("prompts = %q", in.prompts)
+    }
+}
+
+func TestShellComplete(t *testing.T) {
+    eachStore(t, func(t *testing.T, s *state, q database.Querier) {
+        alice := loginAs(t, s, q, "alice", roleUser)
+        addFeed(t, q, alice, "Blog", "https://blog.example.com/rss")
+        coms := newCommands(flag.NewFlagSet("gator", flag.ContinueOnError))
+
+        tests := []struct {
+            input string
+            want  string
+        }{
+            { "fol",            "folder |follow |follow-settings |following " },
+            { "folder c",       "folder create " },
+            { "unfollow B",     "unfollow Blog " },
+            { "ex",             "exit " },
+            { "agg s",          "agg stop " },
+        }
+        for _, tt := range tests {
+            if got := strings.Join(coms.shellComplete(s, tt.input), "|"); got != tt.want {
+                t.Errorf("shellComplete(%q) = %q, want %q", tt.input, got, tt.want)
+            }
+        }
+    })
+}
+
+func TestSplitShellWords(t *testing.T) {
+    tests := []struct {
+        line string
+        want string
+    }{
+        { `search rust async`,            "search|rust|async" },
+        { `  search  "rust async"  -vim`, "search|rust async|-vim" },
+        { `folder create 'It''s'`,        "folder|create|Its" },
+        { `folder create It\'s`,          "folder|create|It's" },
+        { `say "a \"b\""`,                `say|a "b"` },
+        { `empty ""`,                     "empty|" },
+    }
+    for _, tt := range tests {
+        words, err := splitShellWords(tt.line)
+        if err != nil || strings.Join(words, "|") != tt.want {
+            t.Errorf("splitShellWords(%q) = %q, %v, want %q", tt.line, words, err, tt.want)
+        }
+    }
+
+    _, err := splitShellWords(`search "unfinished`)
+    assertErr(t, err, ErrorReadingInput)
+}
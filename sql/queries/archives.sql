@@ -0,0 +1,59 @@
+-- name: CreateArchiveBlob :exec
+INSERT INTO archive_blobs ( sha256, data )
+VALUES ( $1, $2 )
+ON CONFLICT DO NOTHING;
+
+-- name: CreateArchive :one
+INSERT INTO archives ( id, post_id, url, archived_at, error )
+VALUES ( $1, $2, $3, $4, $5 )
+RETURNING *;
+
+-- name: CreateArchiveResource :exec
+INSERT INTO archive_resources ( archive_id, url, status, content_type, sha256, fetched_at )
+VALUES ( $1, $2, $3, $4, $5, $6 )
+ON CONFLICT DO NOTHING;
+
+-- name: GetPostsToArchive :many
+SELECT posts.id, posts.url, feeds.url AS feed_url FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+WHERE (feeds.archive OR EXISTS ( SELECT 1 FROM post_stars WHERE post_stars.post_id = posts.id ))
+  AND NOT EXISTS ( SELECT 1 FROM archives WHERE archives.post_id = posts.id )
+ORDER BY posts.created_at, posts.id
+LIMIT $1;
+
+-- name: GetPostToArchiveByUrl :one
+SELECT posts.id, posts.url, feeds.url AS feed_url FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+WHERE posts.url = @url
+  AND (EXISTS ( SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = @user_id )
+    OR EXISTS ( SELECT 1 FROM post_stars   WHERE post_stars.post_id     = posts.id      AND post_stars.user_id   = @user_id ));
+
+-- name: DeleteArchiveForPost :exec
+DELETE FROM archives
+WHERE post_id = $1;
+
+-- name: DeleteUnusedArchiveBlobs :execrows
+DELETE FROM archive_blobs
+WHERE NOT EXISTS ( SELECT 1 FROM archive_resources WHERE archive_resources.sha256 = archive_blobs.sha256 );
+
+-- name: GetArchivesForUser :many
+SELECT archives.*, posts.title FROM archives
+JOIN posts ON archives.post_id = posts.id
+WHERE EXISTS ( SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = @user_id )
+   OR EXISTS ( SELECT 1 FROM post_stars   WHERE post_stars.post_id     = posts.id      AND post_stars.user_id   = @user_id )
+ORDER BY archives.archived_at DESC;
+
+-- name: GetArchiveForPostUrl :one
+SELECT archives.*, posts.title FROM archives
+JOIN posts ON archives.post_id = posts.id
+WHERE posts.url = @url
+  AND (EXISTS ( SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = @user_id )
+    OR EXISTS ( SELECT 1 FROM post_stars   WHERE post_stars.post_id     = posts.id      AND post_stars.user_id   = @user_id ));
+
+-- name: GetArchiveResources :many
+SELECT archive_resources.url, archive_resources.status, archive_resources.content_type, archive_resources.sha256,
+       archive_resources.fetched_at, archive_blobs.data
+FROM archive_resources
+JOIN archive_blobs ON archive_resources.sha256 = archive_blobs.sha256
+WHERE archive_resources.archive_id = $1
+ORDER BY archive_resources.fetched_at, archive_resources.url;
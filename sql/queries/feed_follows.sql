@@ -1,6 +1,10 @@
--- name: DeleteFeedFollows :exec
+-- name: DeleteFeedFollows :execrows
 DELETE FROM feed_follows;
 
+-- name: DeleteFeedFollowsForUser :execrows
+DELETE FROM feed_follows
+WHERE user_id = $1;
+
 -- name: CreateFeedFollow :one
 WITH feed_follow_insert AS (
     INSERT INTO feed_follows ( id, created_at, updated_at, user_id, feed_id )
@@ -13,12 +17,35 @@ INNER JOIN users ON users.id = feed_follow_insert.user_id
 INNER JOIN feeds ON feeds.id = feed_follow_insert.feed_id;
 
 -- name: GetFeedFollowsForUser :many
-SELECT feed_follows.*, users.name AS user_name, feeds.name AS feed_name
+SELECT feed_follows.*, users.name AS user_name, COALESCE(feed_follows.title, feeds.name) AS feed_name, feeds.url AS feed_url
 FROM feed_follows
 INNER JOIN feeds ON feed_follows.feed_id = feeds.id
 INNER JOIN users ON feed_follows.user_id = users.id
-WHERE users.name = $1;
+WHERE users.name = @name
+  AND (sqlc.narg('folder_id')::uuid IS NULL OR feed_follows.folder_id IN (
+        WITH RECURSIVE subtree AS (
+            SELECT folders.id FROM folders WHERE folders.id = sqlc.narg('folder_id')
+            UNION ALL
+            SELECT folders.id FROM folders JOIN subtree ON folders.parent_id = subtree.id )
+        SELECT subtree.id FROM subtree ))
+ORDER BY feed_follows.priority DESC, feed_name;
 
--- name: DeleteFeedFollowsForUserUrl :exec
+-- name: DeleteFeedFollowsForUserUrl :execrows
 DELETE FROM feed_follows
 WHERE feed_follows.user_id = $1 AND feed_follows.feed_id = $2;
+
+-- name: UpdateFeedFollowSettings :one
+UPDATE feed_follows
+SET title      = NULLIF(COALESCE(sqlc.narg('title'), title), ''),
+    muted      = COALESCE(sqlc.narg('muted'),    muted),
+    priority   = COALESCE(sqlc.narg('priority'), priority),
+    notify     = COALESCE(sqlc.narg('notify'),   notify),
+    updated_at = NOW()
+WHERE user_id = @user_id AND feed_id = @feed_id
+RETURNING *;
+
+-- name: GetNextFeedOwner :one
+SELECT user_id FROM feed_follows
+WHERE feed_id = $1 AND user_id <> $2
+ORDER BY created_at
+LIMIT 1;
@@ -7,10 +7,20 @@ RETURNING *;
 SELECT name, url, user_id FROM feeds;
 
 -- name: GetFeedsWithName :many
-SELECT feeds.name, feeds.url, users.name AS username FROM feeds
+SELECT feeds.id, feeds.name, feeds.url, users.name AS username FROM feeds
 INNER JOIN users
 ON feeds.user_id = users.id;
 
+-- name: GetFeedsByName :many
+SELECT * FROM feeds
+WHERE feeds.name = $1
+ORDER BY created_at;
+
+-- name: GetFeedsByIDPrefix :many
+SELECT * FROM feeds
+WHERE feeds.id::text LIKE @prefix::text || '%'
+ORDER BY created_at;
+
 -- name: GetFeedUrl :one
 SELECT * FROM feeds
 WHERE feeds.url = $1;
@@ -27,5 +37,31 @@ SELECT * FROM feeds
 ORDER BY last_fetched_at ASC NULLS FIRST
 LIMIT 1;
 
--- name: DeleteFeeds :exec
+-- name: GetFeedsForUser :many
+SELECT * FROM feeds
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: SetFeedOwner :exec
+UPDATE feeds
+SET user_id = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: DeleteFeed :execrows
+DELETE FROM feeds
+WHERE id = $1;
+
+-- name: DeleteFeeds :execrows
 DELETE FROM feeds;
+
+-- name: DeleteFeedsWithoutFollowers :execrows
+DELETE FROM feeds
+WHERE NOT EXISTS ( SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id );
+
+-- name: UpdateFeedSettings :one
+UPDATE feeds
+SET full_text  = COALESCE(sqlc.narg('full_text'), full_text),
+    archive    = COALESCE(sqlc.narg('archive'),   archive),
+    updated_at = NOW()
+WHERE id = @id
+RETURNING *;
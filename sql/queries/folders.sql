@@ -0,0 +1,23 @@
+-- name: CreateFolder :one
+INSERT INTO folders ( id, created_at, updated_at, user_id, name, parent_id )
+             VALUES ( $1, $2,         $3,         $4,      $5,   $6        )
+RETURNING *;
+
+-- name: GetFolderByName :one
+SELECT * FROM folders
+WHERE user_id = $1 AND name = $2;
+
+-- name: GetFoldersForUser :many
+SELECT * FROM folders
+WHERE user_id = $1
+ORDER BY name;
+
+-- name: RenameFolder :execrows
+UPDATE folders
+SET name = @new_name, updated_at = NOW()
+WHERE user_id = @user_id AND name = @old_name;
+
+-- name: SetFeedFollowFolder :execrows
+UPDATE feed_follows
+SET folder_id = $3, updated_at = NOW()
+WHERE user_id = $1 AND feed_id = $2;
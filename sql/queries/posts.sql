@@ -1,12 +1,115 @@
 -- name: CreatePost :one
-INSERT INTO posts ( id, created_at, updated_at, title, url, description, published_at, feed_id )
-            VALUES ( $1, $2,         $3,         $4,    $5,  $6,          $7,           $8      )
+INSERT INTO posts ( id, created_at, updated_at, title, url, description, published_at, feed_id, description_raw, content, content_raw )
+            VALUES ( $1, $2,         $3,         $4,    $5,  $6,          $7,           $8,      $9,              $10,     $11         )
 RETURNING *;
 
+-- name: DeletePosts :execrows
+DELETE FROM posts;
+
 -- name: GetPostsForUser :many
-SELECT posts.*, feeds.name AS feed_name FROM posts
+SELECT posts.*, COALESCE(feed_follows.title, feeds.name) AS feed_name FROM posts
 JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
 JOIN feeds        ON posts.feed_id        = feeds.id
 WHERE feed_follows.user_id = $1
 ORDER BY posts.published_at DESC
 LIMIT $2;
+
+-- name: GetPostsForUserFiltered :many
+SELECT posts.*, COALESCE(feed_follows.title, feeds.name) AS feed_name FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+JOIN feeds        ON posts.feed_id        = feeds.id
+WHERE feed_follows.user_id = @user_id
+  AND (sqlc.narg('feed')::text        IS NULL OR feeds.name = sqlc.narg('feed') OR feeds.url = sqlc.narg('feed') OR feed_follows.title = sqlc.narg('feed'))
+  AND (sqlc.narg('since')::timestamp  IS NULL OR (CASE WHEN @sort_by::text = 'fetched' THEN posts.created_at ELSE posts.published_at END) >= sqlc.narg('since'))
+  AND (sqlc.narg('until')::timestamp  IS NULL OR (CASE WHEN @sort_by::text = 'fetched' THEN posts.created_at ELSE posts.published_at END) <  sqlc.narg('until'))
+  AND (sqlc.narg('match')::text       IS NULL OR posts.title ILIKE '%' || sqlc.narg('match') || '%' OR posts.description ILIKE '%' || sqlc.narg('match') || '%')
+  AND (NOT @unread_only::boolean OR NOT EXISTS ( SELECT 1 FROM post_reads WHERE post_reads.post_id = posts.id AND post_reads.user_id = @user_id ))
+  AND (sqlc.narg('folder_id')::uuid IS NULL OR feed_follows.folder_id IN (
+        WITH RECURSIVE subtree AS (
+            SELECT folders.id FROM folders WHERE folders.id = sqlc.narg('folder_id')
+            UNION ALL
+            SELECT folders.id FROM folders JOIN subtree ON folders.parent_id = subtree.id )
+        SELECT subtree.id FROM subtree ))
+  AND (@include_muted::boolean OR NOT feed_follows.muted)
+ORDER BY (CASE WHEN @sort_by::text = 'fetched' THEN posts.created_at ELSE posts.published_at END) DESC NULLS LAST, posts.id
+LIMIT @page_size OFFSET @page_offset;
+
+-- name: MarkPostRead :execrows
+INSERT INTO post_reads ( user_id, post_id, read_at )
+SELECT $1, posts.id, NOW() FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1
+  AND posts.url = $2
+ON CONFLICT DO NOTHING;
+
+-- name: MarkAllPostsRead :execrows
+INSERT INTO post_reads ( user_id, post_id, read_at )
+SELECT @user_id, posts.id, NOW() FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+JOIN feeds        ON posts.feed_id        = feeds.id
+WHERE feed_follows.user_id = @user_id
+  AND (sqlc.narg('feed')::text IS NULL OR feeds.name = sqlc.narg('feed') OR feeds.url = sqlc.narg('feed') OR feed_follows.title = sqlc.narg('feed'))
+  AND (sqlc.narg('folder_id')::uuid IS NULL OR feed_follows.folder_id IN (
+        WITH RECURSIVE subtree AS (
+            SELECT folders.id FROM folders WHERE folders.id = sqlc.narg('folder_id')
+            UNION ALL
+            SELECT folders.id FROM folders JOIN subtree ON folders.parent_id = subtree.id )
+        SELECT subtree.id FROM subtree ))
+ON CONFLICT DO NOTHING;
+
+-- name: SearchPostsForUser :many
+SELECT posts.id, posts.title, posts.url, posts.published_at, COALESCE(feed_follows.title, feeds.name) AS feed_name,
+       ts_rank(posts.search_vector, query) AS rank,
+       ts_headline('english', concat_ws(' ', posts.description, posts.content), query, 'StartSel=**, StopSel=**, MaxFragments=1, MaxWords=20, MinWords=5')::text AS snippet
+FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+JOIN feeds        ON posts.feed_id        = feeds.id
+CROSS JOIN websearch_to_tsquery('english', @query) AS query
+WHERE feed_follows.user_id = @user_id
+  AND posts.search_vector @@ query
+  AND (sqlc.narg('since')::timestamp IS NULL OR posts.published_at >= sqlc.narg('since'))
+ORDER BY rank DESC, posts.published_at DESC NULLS LAST
+LIMIT @page_size;
+
+-- name: GetPostsForReader :many
+SELECT posts.id, posts.title, posts.url, posts.description, posts.content, posts.article, posts.published_at, posts.created_at, posts.feed_id,
+       COALESCE(feed_follows.title, feeds.name) AS feed_name, feed_follows.folder_id,
+       EXISTS ( SELECT 1 FROM post_reads WHERE post_reads.post_id = posts.id AND post_reads.user_id = @user_id ) AS is_read,
+       EXISTS ( SELECT 1 FROM post_stars WHERE post_stars.post_id = posts.id AND post_stars.user_id = @user_id ) AS is_starred
+FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+JOIN feeds        ON posts.feed_id        = feeds.id
+WHERE feed_follows.user_id = @user_id
+  AND NOT feed_follows.muted
+ORDER BY COALESCE(posts.published_at, posts.created_at) DESC, posts.id
+LIMIT @page_size;
+
+-- name: MarkPostReadByID :execrows
+INSERT INTO post_reads ( user_id, post_id, read_at )
+VALUES ( $1, $2, NOW() )
+ON CONFLICT DO NOTHING;
+
+-- name: StarPost :execrows
+INSERT INTO post_stars ( user_id, post_id, starred_at )
+VALUES ( $1, $2, NOW() )
+ON CONFLICT DO NOTHING;
+
+-- name: UnstarPost :execrows
+DELETE FROM post_stars
+WHERE user_id = $1 AND post_id = $2;
+
+-- name: GetPostsRaw :many
+SELECT posts.id, posts.url, posts.description_raw, posts.content_raw, feeds.url AS feed_url FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+WHERE NOT @unsanitized_only::boolean OR (posts.description IS NULL AND posts.description_raw IS NOT NULL)
+ORDER BY posts.created_at, posts.id;
+
+-- name: UpdatePostSanitized :execrows
+UPDATE posts
+SET description = $2, content = $3, updated_at = NOW()
+WHERE id = $1;
+
+-- name: SetPostArticle :exec
+UPDATE posts
+SET article = $2, updated_at = NOW()
+WHERE id = $1;
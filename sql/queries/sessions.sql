@@ -0,0 +1,23 @@
+-- name: CreateSession :exec
+INSERT INTO sessions ( token_hash, user_id, created_at, last_used_at )
+              VALUES ( $1,         $2,      $3,         $4           );
+
+-- name: GetUserBySession :one
+UPDATE sessions
+SET last_used_at = NOW()
+FROM users
+WHERE sessions.token_hash = $1 AND users.id = sessions.user_id
+RETURNING users.*;
+
+-- name: GetSessionUser :one
+SELECT users.* FROM users
+JOIN sessions ON sessions.user_id = users.id
+WHERE sessions.token_hash = $1;
+
+-- name: DeleteSession :exec
+DELETE FROM sessions
+WHERE token_hash = $1;
+
+-- name: DeleteSessionsForUser :execrows
+DELETE FROM sessions
+WHERE user_id = $1 AND token_hash <> @keep_token_hash;
@@ -1,6 +1,6 @@
 -- name: CreateUser :one
-INSERT INTO users ( id, created_at, updated_at, name )
-VALUES            ( $1, $2,         $3,         $4   )
+INSERT INTO users ( id, created_at, updated_at, name, password_hash, role )
+VALUES            ( $1, $2,         $3,         $4,   $5,            $6   )
 RETURNING *;
 
 -- name: GetUser :one
@@ -14,5 +14,34 @@ WHERE id = $1;
 -- name: GetUsers :many
 SELECT name FROM users;
 
--- name: DeleteUsers :exec
+-- name: CountUsers :one
+SELECT COUNT(*) FROM users;
+
+-- name: CountUsableAdmins :one
+SELECT COUNT(*) FROM users
+WHERE role = 'admin' AND password_hash IS NOT NULL;
+
+-- name: LockUsers :exec
+LOCK TABLE users IN SHARE ROW EXCLUSIVE MODE;
+
+-- name: SetUserRole :execrows
+UPDATE users
+SET role = $2, updated_at = NOW()
+WHERE name = $1;
+
+-- name: RenameUser :execrows
+UPDATE users
+SET name = @new_name, updated_at = NOW()
+WHERE name = @old_name;
+
+-- name: DeleteUsers :execrows
 DELETE FROM users;
+
+-- name: DeleteUserByName :execrows
+DELETE FROM users
+WHERE name = $1;
+
+-- name: SetUserPassword :exec
+UPDATE users
+SET password_hash = $2, updated_at = NOW()
+WHERE id = $1;
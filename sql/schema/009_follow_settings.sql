@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE feed_follows
+ADD title    TEXT,
+ADD muted    BOOLEAN NOT NULL DEFAULT FALSE,
+ADD priority INTEGER NOT NULL DEFAULT 0,
+ADD notify   BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- +goose Down
+ALTER TABLE feed_follows
+DROP COLUMN title,
+DROP COLUMN muted,
+DROP COLUMN priority,
+DROP COLUMN notify;
@@ -0,0 +1,10 @@
+-- +goose Up
+-- Deleting a user must not silently delete feeds other people follow, gator hands them over first
+ALTER TABLE feeds
+DROP CONSTRAINT feeds_user_id_fkey,
+ADD  CONSTRAINT feeds_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE RESTRICT;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP CONSTRAINT feeds_user_id_fkey,
+ADD  CONSTRAINT feeds_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
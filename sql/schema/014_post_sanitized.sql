@@ -0,0 +1,24 @@
+-- +goose Up
+-- description and content hold html cleaned at ingest, safe to serve.  The _raw columns keep what
+-- the publisher sent, so posts can be cleaned again when the rules change (gator sanitize).
+ALTER TABLE posts
+ADD COLUMN description_raw TEXT,
+ADD COLUMN content         TEXT,
+ADD COLUMN content_raw     TEXT;
+
+-- Posts saved before this were never cleaned.  Their html moves to description_raw and description
+-- stays empty until gator sanitises them, which it does the first time it starts on this schema.
+UPDATE posts SET description_raw = description, description = NULL;
+
+CREATE INDEX posts_unsanitized_idx ON posts (created_at)
+WHERE description IS NULL AND description_raw IS NOT NULL;
+
+-- +goose Down
+UPDATE posts SET description = description_raw WHERE description_raw IS NOT NULL;
+
+DROP INDEX posts_unsanitized_idx;
+
+ALTER TABLE posts
+DROP COLUMN description_raw,
+DROP COLUMN content,
+DROP COLUMN content_raw;
@@ -0,0 +1,39 @@
+-- +goose Up
+-- Snapshots of the pages posts link to, taken for starred posts and for every post from feeds
+-- with archive on.  Each snapshot is the page and the stylesheets and images it uses, as they
+-- were fetched; bodies live in archive_blobs under their sha256, so a stylesheet shared by many
+-- pages is stored once.  A page that could not be saved gets an archive with its error and no
+-- resources, so it is not tried again on every agg round.
+ALTER TABLE feeds
+ADD archive BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE archive_blobs(
+    sha256 TEXT  PRIMARY KEY,
+    data   BYTEA NOT NULL
+);
+
+CREATE TABLE archives(
+    id          UUID      PRIMARY KEY,
+    post_id     UUID      NOT NULL UNIQUE REFERENCES posts (id) ON DELETE CASCADE,
+    url         TEXT      NOT NULL,
+    archived_at TIMESTAMP NOT NULL,
+    error       TEXT
+);
+
+CREATE TABLE archive_resources(
+    archive_id   UUID      NOT NULL REFERENCES archives (id) ON DELETE CASCADE,
+    url          TEXT      NOT NULL,
+    status       INTEGER   NOT NULL,
+    content_type TEXT      NOT NULL,
+    sha256       TEXT      NOT NULL REFERENCES archive_blobs (sha256),
+    fetched_at   TIMESTAMP NOT NULL,
+    PRIMARY KEY (archive_id, url)
+);
+
+-- +goose Down
+DROP TABLE archive_resources;
+DROP TABLE archives;
+DROP TABLE archive_blobs;
+
+ALTER TABLE feeds
+DROP COLUMN archive;
@@ -0,0 +1,109 @@
+-- SQLite versions of the queries in sql/queries whose Postgres SQL does not run on SQLite.
+-- Each one stands in for the sqlc generated query with the same name, so it takes the same
+-- parameters in the same order and returns the same columns.  Queries not listed here run on
+-- SQLite as they are.  NOW() and websearch_to_fts() are Go functions registered with the driver.
+
+-- name: Delete :exec
+DELETE FROM feeds;
+
+-- name: LockUsers :exec
+-- SQLite transactions take the write lock as they begin, which already keeps registrations apart
+SELECT 1;
+
+-- name: CreateFeedFollow :one
+-- No INSERT in a WITH on SQLite, so the row is read back once it is in
+INSERT INTO feed_follows ( id, created_at, updated_at, user_id, feed_id )
+                  VALUES ( $1, $2,         $3,         $4,      $5      );
+
+SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id, feed_follows.folder_id, feed_follows.title, feed_follows.muted, feed_follows.priority, feed_follows.notify, feeds.name AS feed_name, users.name AS user_name
+FROM feed_follows
+INNER JOIN users ON users.id = feed_follows.user_id
+INNER JOIN feeds ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.id = $1;
+
+-- name: GetFeedFollowsForUser :many
+SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id, feed_follows.folder_id, feed_follows.title, feed_follows.muted, feed_follows.priority, feed_follows.notify, users.name AS user_name, COALESCE(feed_follows.title, feeds.name) AS feed_name, feeds.url AS feed_url
+FROM feed_follows
+INNER JOIN feeds ON feed_follows.feed_id = feeds.id
+INNER JOIN users ON feed_follows.user_id = users.id
+WHERE users.name = $1
+  AND ($2 IS NULL OR feed_follows.folder_id IN (
+        WITH RECURSIVE subtree AS (
+            SELECT folders.id FROM folders WHERE folders.id = $2
+            UNION ALL
+            SELECT folders.id FROM folders JOIN subtree ON folders.parent_id = subtree.id )
+        SELECT subtree.id FROM subtree ))
+ORDER BY feed_follows.priority DESC, feed_name;
+
+-- name: GetFeedsByIDPrefix :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, full_text, archive FROM feeds
+WHERE feeds.id LIKE $1 || '%'
+ORDER BY created_at;
+
+-- name: GetPostsForUserFiltered :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.description_raw, posts.content, posts.content_raw, posts.article, posts.search_vector, COALESCE(feed_follows.title, feeds.name) AS feed_name FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+JOIN feeds        ON posts.feed_id        = feeds.id
+WHERE feed_follows.user_id = $1
+  AND ($2 IS NULL OR feeds.name = $2 OR feeds.url = $2 OR feed_follows.title = $2)
+  AND ($3 IS NULL OR (CASE WHEN $4 = 'fetched' THEN posts.created_at ELSE posts.published_at END) >= $3)
+  AND ($5 IS NULL OR (CASE WHEN $4 = 'fetched' THEN posts.created_at ELSE posts.published_at END) <  $5)
+  AND ($6 IS NULL OR posts.title LIKE '%' || $6 || '%' OR posts.description LIKE '%' || $6 || '%')
+  AND (NOT $7 OR NOT EXISTS ( SELECT 1 FROM post_reads WHERE post_reads.post_id = posts.id AND post_reads.user_id = $1 ))
+  AND ($8 IS NULL OR feed_follows.folder_id IN (
+        WITH RECURSIVE subtree AS (
+            SELECT folders.id FROM folders WHERE folders.id = $8
+            UNION ALL
+            SELECT folders.id FROM folders JOIN subtree ON folders.parent_id = subtree.id )
+        SELECT subtree.id FROM subtree ))
+  AND ($9 OR NOT feed_follows.muted)
+ORDER BY (CASE WHEN $4 = 'fetched' THEN posts.created_at ELSE posts.published_at END) DESC NULLS LAST, posts.id
+LIMIT $10 OFFSET $11;
+
+-- name: MarkAllPostsRead :execrows
+INSERT INTO post_reads ( user_id, post_id, read_at )
+SELECT $1, posts.id, NOW() FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+JOIN feeds        ON posts.feed_id        = feeds.id
+WHERE feed_follows.user_id = $1
+  AND ($2 IS NULL OR feeds.name = $2 OR feeds.url = $2 OR feed_follows.title = $2)
+  AND ($3 IS NULL OR feed_follows.folder_id IN (
+        WITH RECURSIVE subtree AS (
+            SELECT folders.id FROM folders WHERE folders.id = $3
+            UNION ALL
+            SELECT folders.id FROM folders JOIN subtree ON folders.parent_id = subtree.id )
+        SELECT subtree.id FROM subtree ))
+ON CONFLICT DO NOTHING;
+
+-- name: SearchPostsForUser :many
+-- posts_search is the fts5 index, bm25 ranks weighting titles over descriptions over content like
+-- setweight A, B and C.  The snippet is one fragment from whichever column matches best, with the
+-- same markers and no ellipses, like ts_headline's.
+SELECT posts.id, posts.title, posts.url, posts.published_at, COALESCE(feed_follows.title, feeds.name) AS feed_name,
+       -bm25(posts_search, 1.0, 0.4, 0.2) AS rank,
+       COALESCE(snippet(posts_search, -1, '**', '**', '', 20), '') AS snippet
+FROM posts_search
+JOIN posts        ON posts.rowid          = posts_search.rowid
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+JOIN feeds        ON posts.feed_id        = feeds.id
+WHERE posts_search MATCH websearch_to_fts($1)
+  AND feed_follows.user_id = $2
+  AND ($3 IS NULL OR posts.published_at >= $3)
+ORDER BY rank DESC, posts.published_at DESC NULLS LAST
+LIMIT $4;
+
+-- name: GetPostsRaw :many
+SELECT posts.id, posts.url, posts.description_raw, posts.content_raw, feeds.url AS feed_url FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+WHERE NOT $1 OR (posts.description IS NULL AND posts.description_raw IS NOT NULL)
+ORDER BY posts.created_at, posts.id;
+
+-- name: GetUserBySession :one
+-- RETURNING only sees the updated table on SQLite, so the user is read after the update
+UPDATE sessions
+SET last_used_at = NOW()
+WHERE token_hash = $1;
+
+SELECT users.id, users.created_at, users.updated_at, users.name, users.password_hash, users.role FROM users
+JOIN sessions ON sessions.user_id = users.id
+WHERE sessions.token_hash = $1;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- SQLite has no UUID type, ids are kept as their text form, which is what uuid.UUID reads and writes
+CREATE TABLE users(
+    id         TEXT      PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    name       TEXT      UNIQUE NOT NULL
+);
+
+-- +goose Down
+DROP TABLE users;
@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE feed_follows(
+    id         TEXT      PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id    TEXT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    feed_id    TEXT      NOT NULL REFERENCES feeds (id) ON DELETE CASCADE,
+    UNIQUE (user_id, feed_id)
+);
+
+-- +goose Down
+DROP TABLE feed_follows;
@@ -0,0 +1,10 @@
+-- +goose Up
+CREATE TABLE post_reads(
+    user_id    TEXT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    post_id    TEXT      NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    read_at    TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, post_id)
+);
+
+-- +goose Down
+DROP TABLE post_reads;
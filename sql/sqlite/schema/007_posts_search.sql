@@ -0,0 +1,33 @@
+-- +goose Up
+-- Postgres searches a tsvector column, SQLite an fts5 index over the posts table kept up to date
+-- by triggers.  search_vector stays so posts has the same columns on both, it is always NULL here.
+ALTER TABLE posts
+ADD search_vector TEXT;
+
+-- The porter tokenizer stems words the way Postgres' english configuration does.
+CREATE VIRTUAL TABLE posts_search USING fts5 (title, description, content = 'posts', content_rowid = 'rowid', tokenize = 'porter unicode61');
+
+INSERT INTO posts_search (posts_search) VALUES ('rebuild');
+
+CREATE TRIGGER posts_search_insert AFTER INSERT ON posts BEGIN
+    INSERT INTO posts_search (rowid, title, description) VALUES (new.rowid, new.title, new.description);
+END;
+
+CREATE TRIGGER posts_search_delete AFTER DELETE ON posts BEGIN
+    INSERT INTO posts_search (posts_search, rowid, title, description) VALUES ('delete', old.rowid, old.title, old.description);
+END;
+
+CREATE TRIGGER posts_search_update AFTER UPDATE OF title, description ON posts BEGIN
+    INSERT INTO posts_search (posts_search, rowid, title, description) VALUES ('delete', old.rowid, old.title, old.description);
+    INSERT INTO posts_search (rowid, title, description) VALUES (new.rowid, new.title, new.description);
+END;
+
+-- +goose Down
+DROP TRIGGER posts_search_update;
+DROP TRIGGER posts_search_delete;
+DROP TRIGGER posts_search_insert;
+
+DROP TABLE posts_search;
+
+ALTER TABLE posts
+DROP COLUMN search_vector;
@@ -0,0 +1,34 @@
+-- +goose Up
+CREATE TABLE folders(
+    id         TEXT      PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id    TEXT      NOT NULL REFERENCES users (id)   ON DELETE CASCADE,
+    name       TEXT      NOT NULL,
+    parent_id  TEXT               REFERENCES folders (id) ON DELETE CASCADE,
+    UNIQUE (user_id, name)
+);
+
+ALTER TABLE feed_follows
+ADD folder_id TEXT REFERENCES folders (id) ON DELETE SET NULL;
+
+-- +goose Down
+-- SQLite cannot drop a column with a foreign key, so feed_follows is rebuilt without it
+CREATE TABLE feed_follows_old(
+    id         TEXT      PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id    TEXT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    feed_id    TEXT      NOT NULL REFERENCES feeds (id) ON DELETE CASCADE,
+    UNIQUE (user_id, feed_id)
+);
+
+INSERT INTO feed_follows_old ( id, created_at, updated_at, user_id, feed_id )
+SELECT id, created_at, updated_at, user_id, feed_id FROM feed_follows;
+
+DROP TABLE feed_follows;
+
+ALTER TABLE feed_follows_old
+RENAME TO feed_follows;
+
+DROP TABLE folders;
@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE feed_follows ADD title    TEXT;
+ALTER TABLE feed_follows ADD muted    BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE feed_follows ADD priority INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE feed_follows ADD notify   BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- +goose Down
+ALTER TABLE feed_follows DROP COLUMN title;
+ALTER TABLE feed_follows DROP COLUMN muted;
+ALTER TABLE feed_follows DROP COLUMN priority;
+ALTER TABLE feed_follows DROP COLUMN notify;
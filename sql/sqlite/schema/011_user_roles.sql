@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE users
+ADD role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'admin'));
+
+-- Someone has to be able to run admin commands.  Only a user who already has a password can be
+-- picked, one without could be claimed by whoever logs in as them first.  When nobody qualifies,
+-- gator bootstrap makes the first admin.
+UPDATE users SET role = 'admin'
+WHERE id = ( SELECT id FROM users WHERE password_hash IS NOT NULL ORDER BY created_at LIMIT 1 );
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN role;
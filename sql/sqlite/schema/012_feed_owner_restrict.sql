@@ -0,0 +1,12 @@
+-- +goose Up
+-- Deleting a user must not silently delete feeds other people follow, gator hands them over first.
+-- SQLite cannot change a foreign key without rebuilding feeds, which would cascade into posts and
+-- follows, so a trigger refuses the delete before the cascade runs instead.
+CREATE TRIGGER feeds_user_id_restrict BEFORE DELETE ON users
+WHEN EXISTS ( SELECT 1 FROM feeds WHERE feeds.user_id = old.id )
+BEGIN
+    SELECT RAISE(ABORT, 'FOREIGN KEY constraint failed');
+END;
+
+-- +goose Down
+DROP TRIGGER feeds_user_id_restrict;
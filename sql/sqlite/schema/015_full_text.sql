@@ -0,0 +1,15 @@
+-- +goose Up
+-- Feeds with full_text on have each new post's page fetched, and the article found in it saved
+-- (sanitised) in posts.article, for feeds whose descriptions are only teasers.
+ALTER TABLE feeds
+ADD full_text BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE posts
+ADD article TEXT;
+
+-- +goose Down
+ALTER TABLE posts
+DROP COLUMN article;
+
+ALTER TABLE feeds
+DROP COLUMN full_text;
@@ -0,0 +1,35 @@
+-- +goose Up
+-- Snapshots of the pages posts link to, see the Postgres migration for how they are kept
+ALTER TABLE feeds
+ADD archive BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE archive_blobs(
+    sha256 TEXT PRIMARY KEY,
+    data   BLOB NOT NULL
+);
+
+CREATE TABLE archives(
+    id          TEXT      PRIMARY KEY,
+    post_id     TEXT      NOT NULL UNIQUE REFERENCES posts (id) ON DELETE CASCADE,
+    url         TEXT      NOT NULL,
+    archived_at TIMESTAMP NOT NULL,
+    error       TEXT
+);
+
+CREATE TABLE archive_resources(
+    archive_id   TEXT      NOT NULL REFERENCES archives (id) ON DELETE CASCADE,
+    url          TEXT      NOT NULL,
+    status       INTEGER   NOT NULL,
+    content_type TEXT      NOT NULL,
+    sha256       TEXT      NOT NULL REFERENCES archive_blobs (sha256),
+    fetched_at   TIMESTAMP NOT NULL,
+    PRIMARY KEY (archive_id, url)
+);
+
+-- +goose Down
+DROP TABLE archive_resources;
+DROP TABLE archives;
+DROP TABLE archive_blobs;
+
+ALTER TABLE feeds
+DROP COLUMN archive;
@@ -0,0 +1,55 @@
+-- +goose Up
+-- Full articles and feed content are searched too, so the index gets a content column.  search_vector
+-- moves to the end of posts as it does on Postgres, where it has to be made again.
+DROP TRIGGER posts_search_update;
+DROP TRIGGER posts_search_delete;
+DROP TRIGGER posts_search_insert;
+
+DROP TABLE posts_search;
+
+ALTER TABLE posts
+DROP COLUMN search_vector;
+
+ALTER TABLE posts
+ADD search_vector TEXT;
+
+CREATE VIRTUAL TABLE posts_search USING fts5 (title, description, content, content = 'posts', content_rowid = 'rowid', tokenize = 'porter unicode61');
+
+INSERT INTO posts_search (posts_search) VALUES ('rebuild');
+
+CREATE TRIGGER posts_search_insert AFTER INSERT ON posts BEGIN
+    INSERT INTO posts_search (rowid, title, description, content) VALUES (new.rowid, new.title, new.description, new.content);
+END;
+
+CREATE TRIGGER posts_search_delete AFTER DELETE ON posts BEGIN
+    INSERT INTO posts_search (posts_search, rowid, title, description, content) VALUES ('delete', old.rowid, old.title, old.description, old.content);
+END;
+
+CREATE TRIGGER posts_search_update AFTER UPDATE OF title, description, content ON posts BEGIN
+    INSERT INTO posts_search (posts_search, rowid, title, description, content) VALUES ('delete', old.rowid, old.title, old.description, old.content);
+    INSERT INTO posts_search (rowid, title, description, content) VALUES (new.rowid, new.title, new.description, new.content);
+END;
+
+-- +goose Down
+DROP TRIGGER posts_search_update;
+DROP TRIGGER posts_search_delete;
+DROP TRIGGER posts_search_insert;
+
+DROP TABLE posts_search;
+
+CREATE VIRTUAL TABLE posts_search USING fts5 (title, description, content = 'posts', content_rowid = 'rowid', tokenize = 'porter unicode61');
+
+INSERT INTO posts_search (posts_search) VALUES ('rebuild');
+
+CREATE TRIGGER posts_search_insert AFTER INSERT ON posts BEGIN
+    INSERT INTO posts_search (rowid, title, description) VALUES (new.rowid, new.title, new.description);
+END;
+
+CREATE TRIGGER posts_search_delete AFTER DELETE ON posts BEGIN
+    INSERT INTO posts_search (posts_search, rowid, title, description) VALUES ('delete', old.rowid, old.title, old.description);
+END;
+
+CREATE TRIGGER posts_search_update AFTER UPDATE OF title, description ON posts BEGIN
+    INSERT INTO posts_search (posts_search, rowid, title, description) VALUES ('delete', old.rowid, old.title, old.description);
+    INSERT INTO posts_search (rowid, title, description) VALUES (new.rowid, new.title, new.description);
+END;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- Blobs no snapshot uses any more are deleted, which looks up the resources using each one
+CREATE INDEX archive_resources_sha256_idx ON archive_resources (sha256);
+
+-- +goose Down
+DROP INDEX archive_resources_sha256_idx;
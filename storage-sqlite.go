@@ -0,0 +1,221 @@
+package main
+
+import (
+    "context"
+    "database/sql"
+    "database/sql/driver"
+    _ "embed"
+    "fmt"
+    "internal/database"
+    "strings"
+    "time"
+    "unicode"
+    "modernc.org/sqlite"
+)
+
+// The SQLite versions of the queries whose Postgres SQL does not run there, see the file's header
+//go:embed sql/sqlite/queries.sql
+var sqliteQueriesSQL string
+
+var sqliteQueries = parseQueries(sqliteQueriesSQL)
+
+// sqliteTimeFormat is how times are written, the driver's _time_format=sqlite.  Times are kept in
+// UTC so comparing and sorting them as text, which is all SQLite can do, matches comparing times.
+const sqliteTimeFormat = "2006-01-02 15:04:05.999999999-07:00"
+
+// sqliteParams are added to every SQLite url: foreign keys on (SQLite has them off by default),
+// transactions that take the write lock when they begin, and a wait instead of an error when
+// another gator, say agg, holds it
+const sqliteParams = "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate&_time_format=sqlite"
+
+func init() {
+    sqlite.MustRegisterScalarFunction("now", 0, func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+        return time.Now().UTC().Format(sqliteTimeFormat), nil
+    })
+    sqlite.MustRegisterDeterministicScalarFunction("websearch_to_fts", 1, func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+        search, _ := args[0].(string)
+        if query := ftsQuery(search); query != "" {
+            return query, nil
+        }
+        // Nothing to search for matches nothing
+        return nil, nil
+    })
+}
+
+// sqliteStore is a store on a SQLite file, running the sqlc generated queries through sqliteDB
+type sqliteStore struct {
+    *database.Queries
+    db *sql.DB
+}
+
+func (st sqliteStore) begin(ctx context.Context) (database.Querier, txn, error) {
+    tx, err := st.db.BeginTx(ctx, nil)
+    if err != nil {
+        return nil, nil, err
+    }
+    return database.New(sqliteDB{ db: tx }), tx, nil
+}
+
+// openSQLite opens the file a sqlite://, file: or *.db url names, SQLite creates it when missing
+func openSQLite(dbURL string) (store, *sql.DB, error) {
+    path := strings.TrimPrefix(dbURL, "file:")
+    if _, rest, found := strings.Cut(dbURL, "://"); found {
+        path = rest
+    }
+    path, params, _ := strings.Cut(path, "?")
+    if path == "" {
+        return nil, nil, fmt.Errorf("%w | Reason: no file in sqlite url %v", ErrorConnectingDB, dbURL)
+    }
+
+    dsn := "file:" + path + "?" + sqliteParams
+    if params != "" {
+        dsn += "&" + params
+    }
+    db, err := sql.Open("sqlite", dsn)
+    if err != nil {
+        return nil, nil, err
+    }
+    return sqliteStore{ Queries: database.New(sqliteDB{ db: db }), db: db }, db, nil
+}
+
+// isSQLite tells the backends apart, for the few things done on the raw connection like migrations
+func isSQLite(db *sql.DB) bool {
+    _, ok := db.Driver().(*sqlite.Driver)
+    return ok
+}
+
+// sqliteDB runs the sqlc generated queries on SQLite, swapping in the SQLite version of a query
+// where there is one and putting times in UTC
+type sqliteDB struct {
+    db database.DBTX
+}
+
+func (d sqliteDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+    return d.db.ExecContext(ctx, sqliteQuery(query), sqliteArgs(args)...)
+}
+
+func (d sqliteDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+    return d.db.PrepareContext(ctx, sqliteQuery(query))
+}
+
+func (d sqliteDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+    return d.db.QueryContext(ctx, sqliteQuery(query), sqliteArgs(args)...)
+}
+
+func (d sqliteDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+    return d.db.QueryRowContext(ctx, sqliteQuery(query), sqliteArgs(args)...)
+}
+
+// sqliteQuery looks a generated query up by the name in its "-- name: <Name> :<kind>" header
+func sqliteQuery(query string) string {
+    header, _, _ := strings.Cut(query, "\n")
+    fields := strings.Fields(header)
+    if len(fields) < 3 || fields[1] != "name:" {
+        return query
+    }
+    if sqliteVersion, ok := sqliteQueries[fields[2]]; ok {
+        return sqliteVersion
+    }
+    return query
+}
+
+// parseQueries splits a file of queries in sqlc's format into queries by name
+func parseQueries(text string) map[string]string {
+    queries := map[string]string{}
+    for _, query := range strings.Split(text, "\n-- name: ")[1:] {
+        name, _, _ := strings.Cut(query, " ")
+        queries[name] = "-- name: " + query
+    }
+    return queries
+}
+
+func sqliteArgs(args []interface{}) []interface{} {
+    for i, arg := range args {
+        switch arg := arg.(type) {
+        case time.Time:
+            args[i] = arg.UTC()
+        case sql.NullTime:
+            arg.Time = arg.Time.UTC()
+            args[i]  = arg
+        }
+    }
+    return args
+}
+
+// ftsQuery turns a search in websearch_to_tsquery's syntax (words, "quoted phrases", or, and
+// -excluded words) into an fts5 query that means the same.  Every term is quoted, so nothing a
+// user types is taken for fts5's own syntax.
+func ftsQuery(search string) string {
+    query, excluded, or := "", []string{}, false
+    for _, term := range searchTerms(search) {
+        if term.or {
+            or = true
+            continue
+        }
+
+        quoted := `"` + strings.ReplaceAll(term.text, `"`, `""`) + `"`
+        switch {
+        case term.excluded && query == "":
+            // fts5's NOT needs something on its left, so leading exclusions wait for the first term
+            excluded = append(excluded, quoted)
+        case term.excluded:
+            query += " NOT " + quoted
+        case query == "":
+            query = quoted
+            for _, e := range excluded {
+                query += " NOT " + e
+            }
+        case or:
+            query += " OR " + quoted
+        default:
+            query += " AND " + quoted
+        }
+        or = false
+    }
+    return query
+}
+
+type searchTerm struct {
+    text     string
+    excluded bool
+    or       bool
+}
+
+func searchTerms(search string) []searchTerm {
+    terms := []searchTerm{}
+    rest  := []rune(search)
+    for len(rest) > 0 {
+        if unicode.IsSpace(rest[0]) {
+            rest = rest[1:]
+            continue
+        }
+
+        term := searchTerm{}
+        if rest[0] == '-' {
+            term.excluded = true
+            rest = rest[1:]
+        }
+
+        end := 0
+        if len(rest) > 0 && rest[0] == '"' {
+            rest = rest[1:]
+            for end < len(rest) && rest[end] != '"' {
+                end++
+            }
+            term.text = string(rest[:end])
+            end++ // the closing quote
+        } else {
+            for end < len(rest) && !unicode.IsSpace(rest[end]) {
+                end++
+            }
+            term.text = string(rest[:end])
+            term.or   = !term.excluded && strings.EqualFold(term.text, "or")
+        }
+        rest = rest[min(end, len(rest)):]
+
+        if strings.TrimSpace(term.text) != "" {
+            terms = append(terms, term)
+        }
+    }
+    return terms
+}
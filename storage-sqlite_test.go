@@ -0,0 +1,200 @@
+package main
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "internal/database"
+    "reflect"
+    "strings"
+    "testing"
+    "time"
+    "github.com/google/uuid"
+)
+
+// queryRecorder is a DBTX that keeps the sql of every query sent to it, without running any
+type queryRecorder struct {
+    db      *sql.DB
+    queries []string
+}
+
+var errRecorded = errors.New("recorded")
+
+func (r *queryRecorder) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+    r.queries = append(r.queries, query)
+    return nil, errRecorded
+}
+
+func (r *queryRecorder) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+    r.queries = append(r.queries, query)
+    return nil, errRecorded
+}
+
+func (r *queryRecorder) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+    r.queries = append(r.queries, query)
+    return nil, errRecorded
+}
+
+func (r *queryRecorder) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+    r.queries = append(r.queries, query)
+    return r.db.QueryRowContext(ctx, "SELECT 1 WHERE FALSE")
+}
+
+// Every generated query, or the SQLite version standing in for it, compiles against the SQLite schema
+func TestSQLiteQueries(t *testing.T) {
+    s, _ := newSQLiteState(t)
+
+    // Calling each query method with zero arguments is enough to see its sql
+    recorder := &queryRecorder{ db: s.dbConn }
+    queries  := reflect.ValueOf(database.New(recorder))
+    for i := range queries.NumMethod() {
+        method := queries.Method(i)
+        if queries.Type().Method(i).Name == "WithTx" {
+            continue
+        }
+        args := []reflect.Value{ reflect.ValueOf(context.Background()) }
+        for j := 1; j < method.Type().NumIn(); j++ {
+            args = append(args, reflect.Zero(method.Type().In(j)))
+        }
+        method.Call(args)
+    }
+    if len(recorder.queries) != queries.NumMethod() - 1 {
+        t.Fatalf("recorded %v queries for %v methods", len(recorder.queries), queries.NumMethod() - 1)
+    }
+
+    names := map[string]bool{}
+    for _, query := range recorder.queries {
+        name := strings.Fields(query)[2]
+        names[name] = true
+
+        // EXPLAIN compiles a statement without running it, the driver only does that on first use.
+        // The driver still wants a value for every parameter, NULL does.
+        nulls := make([]interface{}, 20)
+        for _, statement := range strings.Split(sqliteQuery(query), ";\n") {
+            if strings.TrimSpace(statement) == "" {
+                continue
+            }
+            rows, err := s.dbConn.Query("EXPLAIN " + statement, nulls...)
+            if err != nil {
+                t.Errorf("%v does not run on SQLite: %v", name, err)
+                continue
+            }
+            rows.Close()
+        }
+    }
+
+    for name := range sqliteQueries {
+        if !names[name] {
+            t.Errorf("sql/sqlite/queries.sql has %v, which is not a generated query", name)
+        }
+    }
+}
+
+func TestSQLiteMigrations(t *testing.T) {
+    s, st := newSQLiteState(t)
+    alice := loginAs(t, s, st, "alice", roleUser)
+    feed  := addFeed(t, st, alice, "Blog", "https://blog.example.com/rss")
+    addPost(t, st, feed, "Go generics", "https://blog.example.com/1", `<p onclick="track()">Type parameters</p>`, time.Hour)
+
+    // Back to before folders, sessions and sanitising, then up again, keeping the data
+    for range 11 {
+        run(t, s, "migrate", "down")
+    }
+    assertContains(t, run(t, s, "migrate", "status"), "Database version 7, gator expects 18")
+    run(t, s, "migrate", "up")
+    if err := checkSchema(s.dbConn); err != nil {
+        t.Fatal(err)
+    }
+
+    // The post's html waits unserved until it is sanitised, and is searchable again after
+    if err := newSession(s, alice); err != nil {
+        t.Fatal(err)
+    }
+    if out := run(t, s, "browse"); strings.Contains(out, "Type parameters") {
+        t.Errorf("browse served html that was not sanitised:\n%v", out)
+    }
+    if n, err := sanitizePosts(context.Background(), st, true); err != nil || n != 1 {
+        t.Fatalf("sanitizePosts = %v, %v; want the one post", n, err)
+    }
+    out := run(t, s, "browse")
+    assertContains(t, out, "Go generics", "Type parameters")
+    if strings.Contains(out, "onclick") {
+        t.Errorf("browse showed unsanitised html:\n%v", out)
+    }
+    assertContains(t, run(t, s, "search", "parameter"), `1 results for "parameter"`)
+
+    // All the way down leaves nothing but goose's table
+    for range 18 {
+        run(t, s, "migrate", "down")
+    }
+    assertContains(t, run(t, s, "migrate", "down"), "No migration to roll back.")
+    var tables string
+    err := s.dbConn.QueryRow("SELECT group_concat(name) FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'").Scan(&tables)
+    if err != nil || tables != "goose_db_version" {
+        t.Errorf("tables left = %v, %v", tables, err)
+    }
+}
+
+// The constraints Postgres enforces with foreign keys hold on SQLite too
+func TestSQLiteForeignKeys(t *testing.T) {
+    s, st := newSQLiteState(t)
+    ctx := context.Background()
+    alice   := loginAs(t, s, st, "alice", roleUser)
+    bob     := addUser(t, st, "bob", roleUser)
+    shared  := addFeed(t, st, alice, "Shared", "https://shared.example.com/rss")
+    private := addFeed(t, st, alice, "Private", "https://private.example.com/rss")
+    addPost(t, st, private, "Private post", "https://private.example.com/1", "Only for alice", time.Hour)
+    follow(t, st, bob, shared)
+
+    // A user who still owns feeds cannot be deleted, gator hands the feeds over first
+    if _, err := st.DeleteUserByName(ctx, "alice"); err == nil {
+        t.Fatal("deleted a user who owns feeds")
+    }
+    if _, err := st.CreateFeedFollow(ctx, database.CreateFeedFollowParams{ ID: uuid.New(), CreatedAt: time.Now(), UpdatedAt: time.Now(), UserID: uuid.New(), FeedID: shared.ID }); err == nil {
+        t.Error("followed a feed as a user who does not exist")
+    }
+    if _, err := st.CreateFeedFollow(ctx, database.CreateFeedFollowParams{ ID: uuid.New(), CreatedAt: time.Now(), UpdatedAt: time.Now(), UserID: bob.ID, FeedID: shared.ID }); !isDuplicate(err) {
+        t.Errorf("following a feed twice = %v, want a unique constraint error", err)
+    }
+
+    out := run(t, s, "user", "delete", "--yes", "alice")
+    assertContains(t, out, "1 feeds handed to other followers, 1 feeds deleted")
+    if feed, err := st.GetFeedUrl(ctx, shared.Url); err != nil || feed.UserID != bob.ID {
+        t.Errorf("shared feed = %+v, %v; want it handed to bob", feed, err)
+    }
+
+    // The private feed's posts and alice's sessions went with them, and so did the posts' search entries
+    for _, table := range []string{ "feeds", "posts", "sessions", "feed_follows" } {
+        var rows int
+        s.dbConn.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&rows)
+        if want := map[string]int{ "feeds": 1, "feed_follows": 1 }[table]; rows != want {
+            t.Errorf("%v rows in %v, want %v", rows, table, want)
+        }
+    }
+    if _, err := s.dbConn.Exec("INSERT INTO posts_search (posts_search) VALUES ('integrity-check')"); err != nil {
+        t.Errorf("search index out of step with posts: %v", err)
+    }
+}
+
+func TestFTSQuery(t *testing.T) {
+    tests := []struct {
+        in   string
+        want string
+    }{
+        { "go",                    `"go"` },
+        { "go generics",           `"go" AND "generics"` },
+        { `"type parameters" go`,  `"type parameters" AND "go"` },
+        { "go or rust",            `"go" OR "rust"` },
+        { "go -rust",              `"go" NOT "rust"` },
+        { "-rust go or zig",       `"go" NOT "rust" OR "zig"` },
+        { `say "hi`,               `"say" AND "hi"` },
+        { `a"b NEAR(c)`,           `"a""b" AND "NEAR(c)"` },
+        { "-rust",                 "" },
+        { "  - or ",               "" },
+    }
+    for _, tt := range tests {
+        if got := ftsQuery(tt.in); got != tt.want {
+            t.Errorf("ftsQuery(%q) = %q, want %q", tt.in, got, tt.want)
+        }
+    }
+}
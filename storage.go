@@ -0,0 +1,82 @@
+package main
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "internal/database"
+    "strings"
+    "github.com/lib/pq"
+    "modernc.org/sqlite"
+    sqlite3 "modernc.org/sqlite/lib"
+)
+
+// store is everything commands need from the database.  The sqlc Querier covers the queries,
+// begin covers transactions, so a backend only has to provide both.
+type store interface {
+    database.Querier
+
+    // begin starts a transaction and returns queries that run inside it
+    begin(ctx context.Context) (database.Querier, txn, error)
+}
+
+type txn interface {
+    Commit() error
+    Rollback() error
+}
+
+// sqlStore is a store on a database/sql connection, using the sqlc generated queries
+type sqlStore struct {
+    *database.Queries
+    db *sql.DB
+}
+
+func (st sqlStore) begin(ctx context.Context) (database.Querier, txn, error) {
+    tx, err := st.db.BeginTx(ctx, nil)
+    if err != nil {
+        return nil, nil, err
+    }
+    return st.WithTx(tx), tx, nil
+}
+
+// openStore picks the backend from the db url's scheme: Postgres for postgres:// urls and
+// connection strings, SQLite for sqlite:// and file: urls and paths ending in .db or .sqlite
+func openStore(dbURL string) (store, *sql.DB, error) {
+    scheme, _, found := strings.Cut(dbURL, "://")
+    if !found {
+        // Not a url, lib/pq also accepts "host=... dbname=..." connection strings
+        scheme = "postgres"
+        if strings.HasPrefix(dbURL, "file:") || strings.HasSuffix(dbURL, ".db") || strings.HasSuffix(dbURL, ".sqlite") {
+            scheme = "sqlite"
+        }
+    }
+
+    switch strings.ToLower(scheme) {
+    case "postgres", "postgresql":
+        db, err := sql.Open("postgres", dbURL)
+        if err != nil {
+            return nil, nil, err
+        }
+        return sqlStore{ Queries: database.New(db), db: db }, db, nil
+    case "sqlite", "sqlite3", "file":
+        return openSQLite(dbURL)
+    }
+    return nil, nil, fmt.Errorf("%w | Reason: unknown database scheme %v (use postgres:// or sqlite://)", ErrorConnectingDB, scheme)
+}
+
+// pqUniqueViolation is Postgres' error code for a unique constraint failing
+const pqUniqueViolation = "23505"
+
+// isDuplicate reports whether err is a unique constraint failing, on either backend
+func isDuplicate(err error) bool {
+    var pqErr     *pq.Error
+    var sqliteErr *sqlite.Error
+    switch {
+    case errors.As(err, &pqErr):
+        return pqErr.Code == pqUniqueViolation
+    case errors.As(err, &sqliteErr):
+        return sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+    }
+    return false
+}
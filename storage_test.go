@@ -0,0 +1,70 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "internal/database"
+    "path/filepath"
+    "testing"
+    "github.com/google/uuid"
+    "github.com/lib/pq"
+)
+
+func TestOpenStore(t *testing.T) {
+    for _, url := range []string{ "postgres://localhost/gator", "postgresql://localhost/gator", "host=localhost dbname=gator" } {
+        st, db, err := openStore(url)
+        if err != nil || st == nil || db == nil || isSQLite(db) {
+            t.Errorf("openStore(%q) = %v", url, err)
+            continue
+        }
+        db.Close()
+    }
+
+    dir := t.TempDir()
+    for _, url := range []string{ "sqlite://" + filepath.Join(dir, "a.db"), "file:" + filepath.Join(dir, "b.db"), filepath.Join(dir, "c.sqlite"),
+                                  "sqlite://" + filepath.Join(dir, "d.db") + "?_pragma=synchronous(1)" } {
+        st, db, err := openStore(url)
+        if err != nil || st == nil || db == nil || !isSQLite(db) {
+            t.Errorf("openStore(%q) = %v", url, err)
+            continue
+        }
+        if err := db.Ping(); err != nil {
+            t.Errorf("openStore(%q) cannot reach the file: %v", url, err)
+        }
+        db.Close()
+    }
+
+    _, _, err := openStore("sqlite://")
+    assertErr(t, err, ErrorConnectingDB)
+    _, _, err = openStore("mysql://localhost/gator")
+    assertErr(t, err, ErrorConnectingDB)
+}
+
+func TestIsDuplicate(t *testing.T) {
+    _, st := newSQLiteState(t)
+    addUser(t, st, "alice", roleUser)
+    _, sqliteUnique := st.CreateUser(context.Background(), database.CreateUserParams{ ID: uuid.New(), Name: "alice", Role: roleUser })
+    sqliteFK        := st.CreateSession(context.Background(), database.CreateSessionParams{ TokenHash: "token", UserID: uuid.New() })
+    if sqliteUnique == nil || sqliteFK == nil {
+        t.Fatalf("constraints did not fail: %v, %v", sqliteUnique, sqliteFK)
+    }
+
+    tests := []struct {
+        err  error
+        want bool
+    }{
+        { &pq.Error{ Code: pqUniqueViolation },                             true  },
+        { fmt.Errorf("wrapped: %w", &pq.Error{ Code: pqUniqueViolation }), true  },
+        { &pq.Error{ Code: "23503" },                                       false },
+        { sqliteUnique,                                                     true  },
+        { sqliteFK,                                                         false },
+        { errors.New("duplicate key value violates unique constraint"),     false },
+        { nil,                                                              false },
+    }
+    for _, tt := range tests {
+        if got := isDuplicate(tt.err); got != tt.want {
+            t.Errorf("isDuplicate(%v) = %v, want %v", tt.err, got, tt.want)
+        }
+    }
+}
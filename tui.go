@@ -0,0 +1,557 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "internal/database"
+    "net/url"
+    "os/exec"
+    "runtime"
+    "sort"
+    "strings"
+    "time"
+    "github.com/charmbracelet/bubbletea"
+    "github.com/charmbracelet/lipgloss"
+    "github.com/google/uuid"
+)
+
+// The reader has three panes side by side: what to read from, the posts in it, and the open post
+const (
+    paneSources = iota
+    panePosts
+    paneReader
+    paneCount
+)
+
+// tuiSource is one line of the left pane, everything, starred posts, a folder or a feed
+type tuiSource struct {
+    label string
+    match func(post database.GetPostsForReaderRow) bool
+}
+
+type tuiModel struct {
+    s        *state
+    user     database.User
+    limit    int
+    refresh  time.Duration
+
+    sources  []tuiSource
+    posts    []database.GetPostsForReaderRow
+    loaded   bool
+
+    pane     int
+    source   int
+    cursor   int
+    open     uuid.UUID
+    scroll   int
+
+    width    int
+    height   int
+    status   string
+}
+
+type tuiLoadedMsg struct {
+    posts   []database.GetPostsForReaderRow
+    follows []database.GetFeedFollowsForUserRow
+    folders []database.Folder
+    err     error
+    tick    bool
+}
+
+type tuiTickMsg struct{}
+
+// openURL hands a link to the desktop's browser, tests swap it out
+var openURL = func(url string) error {
+    switch runtime.GOOS {
+    case "darwin":
+        return exec.Command("open", url).Start()
+    case "windows":
+        return exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start()
+    }
+    return exec.Command("xdg-open", url).Start()
+}
+
+func handlerTUI(s *state, cmd command, user database.User) error {
+    m := newTUIModel(s, user, cmd.flagInt("limit"), cmd.flagDuration("refresh"))
+    if _, err := tea.NewProgram(m, tea.WithAltScreen()).Run(); err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorRunningTUI, err)
+    }
+    return nil
+}
+
+func newTUIModel(s *state, user database.User, limit int, refresh time.Duration) tuiModel {
+    return tuiModel{ s: s, user: user, limit: limit, refresh: refresh, status: "Loading..." }
+}
+
+func (m tuiModel) Init() tea.Cmd {
+    return tea.Batch(m.load(false), m.tick())
+}
+
+// load fetches posts, follows and folders, tick marks loads that were due to live refresh
+func (m tuiModel) load(tick bool) tea.Cmd {
+    s, user, limit := m.s, m.user, m.limit
+    return func() tea.Msg {
+        ctx := context.Background()
+        msg := tuiLoadedMsg{ tick: tick }
+
+        msg.posts, msg.err = s.dbState.GetPostsForReader(ctx, database.GetPostsForReaderParams{ UserID: user.ID, PageSize: int32(limit) })
+        if msg.err != nil {
+            msg.err = fmt.Errorf("%w | Reason: %w", ErrorGettingPosts, msg.err)
+            return msg
+        }
+        msg.follows, msg.err = s.dbState.GetFeedFollowsForUser(ctx, database.GetFeedFollowsForUserParams{ Name: user.Name, FolderID: uuid.NullUUID{} })
+        if msg.err != nil {
+            msg.err = fmt.Errorf("%w | Reason: %w", ErrorGettingUserFeedFollows, msg.err)
+            return msg
+        }
+        msg.folders, msg.err = s.dbState.GetFoldersForUser(ctx, user.ID)
+        if msg.err != nil {
+            msg.err = fmt.Errorf("%w | Reason: %w", ErrorGettingFolder, msg.err)
+        }
+        return msg
+    }
+}
+
+// tick waits out --refresh before the next live refresh, never if it is 0
+func (m tuiModel) tick() tea.Cmd {
+    if m.refresh <= 0 {
+        return nil
+    }
+    return tea.Tick(m.refresh, func(time.Time) tea.Msg { return tuiTickMsg{} })
+}
+
+func (m tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+    switch msg := msg.(type) {
+    case tea.WindowSizeMsg:
+        m.width, m.height = msg.Width, msg.Height
+        return m, nil
+
+    case tuiTickMsg:
+        return m, m.load(true)
+
+    case tuiLoadedMsg:
+        var next tea.Cmd
+        if msg.tick {
+            next = m.tick()
+        }
+        if msg.err != nil {
+            m.status = msg.err.Error()
+            return m, next
+        }
+        m.apply(msg)
+        return m, next
+
+    case tea.KeyMsg:
+        return m.key(msg)
+    }
+    return m, nil
+}
+
+// apply swaps in freshly loaded posts, keeping the same source and post selected
+func (m *tuiModel) apply(msg tuiLoadedMsg) {
+    label    := ""
+    if m.source < len(m.sources) {
+        label = m.sources[m.source].label
+    }
+    selected := uuid.Nil
+    if visible := m.visible(); m.cursor < len(visible) {
+        selected = visible[m.cursor].ID
+    }
+
+    known := map[uuid.UUID]bool{}
+    for _, post := range m.posts {
+        known[post.ID] = true
+    }
+    fresh := 0
+    for _, post := range msg.posts {
+        if !known[post.ID] {
+            fresh++
+        }
+    }
+
+    m.sources = tuiSources(msg.follows, msg.folders)
+    m.posts   = msg.posts
+    m.source, m.cursor = 0, 0
+    for i, source := range m.sources {
+        if source.label == label {
+            m.source = i
+        }
+    }
+    for i, post := range m.visible() {
+        if post.ID == selected {
+            m.cursor = i
+        }
+    }
+
+    switch {
+    case !m.loaded:
+        m.status = fmt.Sprintf("%v posts", len(m.posts))
+    case fresh == 1:
+        m.status = "1 new post"
+    case fresh > 1:
+        m.status = fmt.Sprintf("%v new posts", fresh)
+    }
+    m.loaded = true
+}
+
+// tuiSources lists everything, starred posts, each folder (with its subfolders) and each feed followed
+func tuiSources(follows []database.GetFeedFollowsForUserRow, folders []database.Folder) []tuiSource {
+    sources := []tuiSource{
+        { label: "All",     match: func(post database.GetPostsForReaderRow) bool { return true } },
+        { label: "Starred", match: func(post database.GetPostsForReaderRow) bool { return post.IsStarred } },
+    }
+
+    paths := folderPaths(folders)
+    for _, folder := range folders {
+        path := paths[folder.ID]
+        sources = append(sources, tuiSource{ label: path + "/",
+                                             match: func(post database.GetPostsForReaderRow) bool {
+                                                 if !post.FolderID.Valid {
+                                                     return false
+                                                 }
+                                                 postPath := paths[post.FolderID.UUID]
+                                                 return postPath == path || strings.HasPrefix(postPath, path + "/")
+                                             } })
+    }
+    folderSources := sources[2:]
+    sort.SliceStable(folderSources, func(i, j int) bool { return folderSources[i].label < folderSources[j].label })
+
+    for _, follow := range follows {
+        label := follow.FeedName
+        if follow.Title.Valid {
+            label = follow.Title.String
+        }
+        if follow.Muted {
+            continue
+        }
+        feedID := follow.FeedID
+        sources = append(sources, tuiSource{ label: label, match: func(post database.GetPostsForReaderRow) bool { return post.FeedID == feedID } })
+    }
+    return sources
+}
+
+// visible is the posts in the selected source
+func (m tuiModel) visible() []database.GetPostsForReaderRow {
+    if m.source >= len(m.sources) {
+        return nil
+    }
+    posts := []database.GetPostsForReaderRow{}
+    for _, post := range m.posts {
+        if m.sources[m.source].match(post) {
+            posts = append(posts, post)
+        }
+    }
+    return posts
+}
+
+func (m tuiModel) key(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+    switch msg.String() {
+    case "q", "ctrl+c":
+        return m, tea.Quit
+    case "tab":
+        m.pane = (m.pane + 1) % paneCount
+    case "shift+tab":
+        m.pane = (m.pane + paneCount - 1) % paneCount
+    case "esc":
+        if m.pane > paneSources {
+            m.pane--
+        }
+    case "j", "down":
+        m.move(1)
+    case "k", "up":
+        m.move(-1)
+    case "pgdown", " ":
+        m.move(m.bodyHeight())
+    case "pgup":
+        m.move(-m.bodyHeight())
+    case "g", "home":
+        m.move(-1 << 30)
+    case "G", "end":
+        m.move(1 << 30)
+    case "enter":
+        switch m.pane {
+        case paneSources:
+            m.pane = panePosts
+        case panePosts:
+            m.openSelected()
+        }
+    case "s":
+        m.toggleStar()
+    case "o":
+        if post, ok := m.current(); ok {
+            // The link comes from the publisher, only web pages are handed on, not files or other apps
+            if u, err := url.Parse(post.Url); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+                m.status = fmt.Sprintf("Could not open %v: only http and https links are opened", post.Url)
+            } else if err := openURL(post.Url); err != nil {
+                m.status = fmt.Sprintf("Could not open %v: %v", post.Url, err)
+            } else {
+                m.status = "Opened " + post.Url
+            }
+        }
+    case "r":
+        m.status = "Refreshing..."
+        return m, m.load(false)
+    }
+    return m, nil
+}
+
+// move shifts the cursor of the focused pane, or scrolls the reading pane
+func (m *tuiModel) move(n int) {
+    clamp := func(v, count int) int {
+        return max(0, min(v, count - 1))
+    }
+    switch m.pane {
+    case paneSources:
+        if next := clamp(m.source + n, len(m.sources)); next != m.source {
+            m.source, m.cursor = next, 0
+        }
+    case panePosts:
+        m.cursor = clamp(m.cursor + n, len(m.visible()))
+    case paneReader:
+        m.scroll = max(0, min(m.scroll + n, len(m.readerLines()) - m.bodyHeight()))
+    }
+}
+
+// current is the post the keys act on, the open one in the reading pane or else the one selected
+func (m tuiModel) current() (database.GetPostsForReaderRow, bool) {
+    if m.pane == paneReader {
+        return m.opened()
+    }
+    visible := m.visible()
+    if m.cursor >= len(visible) {
+        return database.GetPostsForReaderRow{}, false
+    }
+    return visible[m.cursor], true
+}
+
+// openSelected shows the selected post in the reading pane and marks it read
+func (m *tuiModel) openSelected() {
+    post, ok := m.current()
+    if !ok {
+        return
+    }
+    m.open, m.scroll, m.pane = post.ID, 0, paneReader
+    if post.IsRead {
+        return
+    }
+
+    _, err := m.s.dbState.MarkPostReadByID(context.Background(), database.MarkPostReadByIDParams{ UserID: m.user.ID, PostID: post.ID })
+    if err != nil {
+        m.status = fmt.Errorf("%w | Reason: %w", ErrorMarkingPostRead, err).Error()
+        return
+    }
+    m.update(post.ID, func(post *database.GetPostsForReaderRow) { post.IsRead = true })
+}
+
+func (m *tuiModel) toggleStar() {
+    post, ok := m.current()
+    if !ok {
+        return
+    }
+
+    var err error
+    if post.IsStarred {
+        _, err = m.s.dbState.UnstarPost(context.Background(), database.UnstarPostParams{ UserID: m.user.ID, PostID: post.ID })
+    } else {
+        _, err = m.s.dbState.StarPost(context.Background(), database.StarPostParams{ UserID: m.user.ID, PostID: post.ID })
+    }
+    if err != nil {
+        m.status = fmt.Errorf("%w | Reason: %w", ErrorStarringPost, err).Error()
+        return
+    }
+    m.update(post.ID, func(post *database.GetPostsForReaderRow) { post.IsStarred = !post.IsStarred })
+    if post.IsStarred {
+        m.status = "Unstarred " + post.Title
+    } else {
+        m.status = "Starred " + post.Title
+    }
+}
+
+func (m *tuiModel) update(id uuid.UUID, change func(post *database.GetPostsForReaderRow)) {
+    for i := range m.posts {
+        if m.posts[i].ID == id {
+            change(&m.posts[i])
+        }
+    }
+}
+
+// Layout, the sources pane takes a fifth of the width and the post list a third of what is left
+var (
+    tuiPaneStyle    = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("8"))
+    tuiFocusStyle   = tuiPaneStyle.BorderForeground(lipgloss.Color("12"))
+    tuiCursorStyle  = lipgloss.NewStyle().Reverse(true)
+    tuiUnreadStyle  = lipgloss.NewStyle().Bold(true)
+    tuiReadStyle    = lipgloss.NewStyle().Faint(true)
+    tuiTitleStyle   = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12"))
+    tuiStatusStyle  = lipgloss.NewStyle().Faint(true)
+)
+
+func (m tuiModel) widths() (int, int, int) {
+    sources := max(16, m.width / 5)
+    posts   := max(20, (m.width - sources) / 3)
+    reader  := max(20, m.width - sources - posts)
+    return sources, posts, reader
+}
+
+// bodyHeight is the lines inside a pane, less the borders and the status line
+func (m tuiModel) bodyHeight() int {
+    return max(1, m.height - 3)
+}
+
+func (m tuiModel) View() string {
+    if m.width == 0 {
+        return m.status
+    }
+    sourcesWidth, postsWidth, readerWidth := m.widths()
+    plain  := func(i int) lipgloss.Style { return lipgloss.NewStyle() }
+    visible := m.visible()
+    posts  := func(i int) lipgloss.Style {
+        if visible[i].IsRead {
+            return tuiReadStyle
+        }
+        return tuiUnreadStyle
+    }
+    title  := len(m.titleLines(readerWidth - 2))
+    reader := func(i int) lipgloss.Style {
+        if i < title {
+            return tuiTitleStyle
+        }
+        return plain(i)
+    }
+    if len(visible) == 0 {
+        posts = plain
+    }
+
+    panes := []string{
+        m.box(paneSources, sourcesWidth, m.sourceLines(sourcesWidth - 2), m.source, plain),
+        m.box(panePosts,   postsWidth,   m.postLines(postsWidth - 2),     m.cursor, posts),
+        m.box(paneReader,  readerWidth,  m.readerLines(),                 -1,       reader),
+    }
+    help := "tab: pane  j/k: move  enter: open  s: star  o: browser  r: refresh  q: quit"
+    return lipgloss.JoinHorizontal(lipgloss.Top, panes...) + "\n" + tuiStatusStyle.Render(clip(m.status + "  |  " + help, m.width))
+}
+
+// box draws a pane around lines already clipped to fit, scrolled to keep the cursor line in view
+// (or by m.scroll for the reading pane)
+func (m tuiModel) box(which, width int, lines []string, cursor int, style func(i int) lipgloss.Style) string {
+    height := m.bodyHeight()
+    offset := m.scroll
+    if cursor >= 0 {
+        offset = max(0, cursor - height + 1)
+    }
+
+    inner := []string{}
+    for i := offset; i < len(lines) && i < offset + height; i++ {
+        line := lines[i]
+        if i == cursor && which == m.pane {
+            line = tuiCursorStyle.Render(line + strings.Repeat(" ", max(0, width - 2 - len([]rune(line)))))
+        } else {
+            line = style(i).Render(line)
+        }
+        inner = append(inner, line)
+    }
+
+    frame := tuiPaneStyle
+    if which == m.pane {
+        frame = tuiFocusStyle
+    }
+    return frame.Width(width - 2).Height(height).Render(strings.Join(inner, "\n"))
+}
+
+func (m tuiModel) sourceLines(width int) []string {
+    lines := []string{}
+    for _, source := range m.sources {
+        unread := 0
+        for _, post := range m.posts {
+            if !post.IsRead && source.match(post) {
+                unread++
+            }
+        }
+        label := source.label
+        if unread > 0 {
+            count := fmt.Sprintf(" %v", unread)
+            label = clip(label, width - len(count)) + count
+        }
+        lines = append(lines, label)
+    }
+    return lines
+}
+
+func (m tuiModel) postLines(width int) []string {
+    lines := []string{}
+    for _, post := range m.visible() {
+        mark := " "
+        switch {
+        case post.IsStarred:
+            mark = "*"
+        case !post.IsRead:
+            mark = "+"
+        }
+        lines = append(lines, clip(mark + " " + post.Title, width))
+    }
+    if len(lines) == 0 && m.loaded {
+        lines = append(lines, "No posts")
+    }
+    return lines
+}
+
+func (m tuiModel) readerLines() []string {
+    post, found := m.opened()
+    if !found {
+        return []string{ "Select a post and press enter to read it" }
+    }
+
+    _, _, width := m.widths()
+    width -= 2
+    published := post.CreatedAt
+    if post.PublishedAt.Valid {
+        published = post.PublishedAt.Time
+    }
+
+    lines := m.titleLines(width)
+    lines = append(lines, clip(post.FeedName + " - " + published.Format("Mon, 02 Jan 2006 15:04"), width), clip(post.Url, width), "")
+    if post.IsStarred {
+        lines[len(lines) - 1] = "Starred"
+        lines = append(lines, "")
+    }
+    // The article fetched from the post's page for full text feeds, then the one from content:encoded
+    // when the feed sends it, and the summary otherwise
+    switch {
+    case post.Article.Valid:
+        lines = append(lines, strings.Split(renderHTML(post.Article.String, width), "\n")...)
+    case post.Content.Valid:
+        lines = append(lines, strings.Split(renderHTML(post.Content.String, width), "\n")...)
+    case post.Description.Valid:
+        lines = append(lines, strings.Split(renderHTML(post.Description.String, width), "\n")...)
+    }
+    return lines
+}
+
+// titleLines is the open post's title wrapped to width, nothing if no post is open
+func (m tuiModel) titleLines(width int) []string {
+    post, found := m.opened()
+    if !found {
+        return nil
+    }
+    return wrapText(post.Title, width)
+}
+
+func (m tuiModel) opened() (database.GetPostsForReaderRow, bool) {
+    for _, post := range m.posts {
+        if post.ID == m.open {
+            return post, true
+        }
+    }
+    return database.GetPostsForReaderRow{}, false
+}
+
+func clip(s string, width int) string {
+    runes := []rune(s)
+    if width < 1 {
+        return ""
+    }
+    if len(runes) <= width {
+        return s
+    }
+    return string(runes[:width - 1]) + "~"
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+    "internal/database"
+    "strings"
+    "testing"
+    "time"
+    "github.com/charmbracelet/bubbletea"
+)
+
+// startTUI loads a reader for the logged in user and gives it a screen to draw on
+func startTUI(t *testing.T, s *state) tuiModel {
+    t.Helper()
+    user, err := currentUser(s)
+    if err != nil {
+        t.Fatal(err)
+    }
+    m := newTUIModel(s, user, 100, time.Minute)
+    m  = sendTUI(t, m, m.load(false)())
+    return sendTUI(t, m, tea.WindowSizeMsg{ Width: 120, Height: 30 })
+}
+
+func sendTUI(t *testing.T, m tuiModel, msgs ...tea.Msg) tuiModel {
+    t.Helper()
+    for _, msg := range msgs {
+        next, _ := m.Update(msg)
+        m = next.(tuiModel)
+    }
+    return m
+}
+
+// keys turns key names into the messages bubbletea sends for them
+func keys(names ...string) []tea.Msg {
+    special := map[string]tea.KeyType{ "tab": tea.KeyTab, "enter": tea.KeyEnter, "esc": tea.KeyEsc, "down": tea.KeyDown, "up": tea.KeyUp }
+    msgs := []tea.Msg{}
+    for _, name := range names {
+        if key, ok := special[name]; ok {
+            msgs = append(msgs, tea.KeyMsg{ Type: key })
+            continue
+        }
+        msgs = append(msgs, tea.KeyMsg{ Type: tea.KeyRunes, Runes: []rune(name) })
+    }
+    return msgs
+}
+
+func TestTUIReadAndStar(t *testing.T) {
+    s, fake := newTestState(t)
+    alice := loginAs(t, s, fake, "alice", roleUser)
+    feed  := addFeed(t, fake, alice, "Blog", "https://blog.example.com/rss")
+    older := addPost(t, fake, feed, "Older", "https://blog.example.com/1", "<p>First &amp; <b>oldest</b></p>", 2 * time.Hour)
+    newer := addPost(t, fake, feed, "Newer", "https://blog.example.com/2", "Second", time.Hour)
+
+    m := startTUI(t, s)
+    view := m.View()
+    assertContains(t, view, "Blog 2")
+    if strings.Index(view, "Newer") > strings.Index(view, "Older") {
+        t.Errorf("posts are not newest first:\n%v", view)
+    }
+
+    // Open the second post, which marks it read
+    m = sendTUI(t, m, keys("tab", "j", "enter")...)
+    if m.pane != paneReader || m.open != older.ID {
+        t.Fatalf("pane %v, open %v, want the reader on %v", m.pane, m.open, older.Title)
+    }
+    if !fake.isRead(alice.ID, older.ID) || fake.isRead(alice.ID, newer.ID) {
+        t.Error("opening a post did not mark just that post read")
+    }
+    assertContains(t, m.View(), "First & oldest")
+
+    m = sendTUI(t, m, keys("s")...)
+    if !fake.isStarred(alice.ID, older.ID) {
+        t.Fatal("s did not star the open post")
+    }
+
+    // The starred source only holds starred posts
+    m = sendTUI(t, m, keys("tab", "j", "enter")...)
+    if visible := m.visible(); len(visible) != 1 || visible[0].ID != older.ID {
+        t.Errorf("starred posts = %v", visible)
+    }
+
+    m = sendTUI(t, m, keys("s")...)
+    if fake.isStarred(alice.ID, older.ID) || len(m.visible()) != 0 {
+        t.Error("s did not unstar the post")
+    }
+}
+
+func TestTUIOpenInBrowser(t *testing.T) {
+    eachStore(t, func(t *testing.T, s *state, q database.Querier) {
+        alice := loginAs(t, s, q, "alice", roleUser)
+        feed  := addFeed(t, q, alice, "Blog", "https://blog.example.com/rss")
+        addPost(t, q, feed, "Hello", "https://blog.example.com/1", "", time.Hour)
+
+        opened := ""
+        old    := openURL
+        openURL = func(url string) error {
+            opened = url
+            return nil
+        }
+        t.Cleanup(func() { openURL = old })
+
+        m := startTUI(t, s)
+        sendTUI(t, m, keys("tab", "o")...)
+        if opened != "https://blog.example.com/1" {
+            t.Errorf("opened %q", opened)
+        }
+
+        // Other schemes are refused rather than handed to whatever handles them
+        addPost(t, q, feed, "Local", "file:///etc/passwd", "", 0)
+        opened = ""
+        m = startTUI(t, s)
+        m = sendTUI(t, m, keys("tab", "o")...)
+        if opened != "" {
+            t.Errorf("opened %q", opened)
+        }
+        assertContains(t, m.View(), "only http and https links are opened")
+    })
+}
+
+func TestTUILiveRefresh(t *testing.T) {
+    eachStore(t, func(t *testing.T, s *state, q database.Querier) {
+        alice := loginAs(t, s, q, "alice", roleUser)
+        feed  := addFeed(t, q, alice, "Blog", "https://blog.example.com/rss")
+        first := addPost(t, q, feed, "First", "https://blog.example.com/1", "", 2 * time.Hour)
+
+        m := startTUI(t, s)
+        m  = sendTUI(t, m, keys("tab")...)
+
+        // agg adds a post, the next tick loads it and keeps the same post selected
+        addPost(t, q, feed, "Second", "https://blog.example.com/2", "", time.Hour)
+        next, cmd := m.Update(tuiTickMsg{})
+        loaded, ok := cmd().(tuiLoadedMsg)
+        if !ok || !loaded.tick {
+            t.Fatalf("tick did not load posts, got %v", loaded)
+        }
+        next, cmd = next.(tuiModel).Update(loaded)
+        m = next.(tuiModel)
+        if cmd == nil {
+            t.Error("live refresh stopped after one tick")
+        }
+
+        if len(m.posts) != 2 || m.status != "1 new post" {
+            t.Errorf("after refresh: %v posts, status %q", len(m.posts), m.status)
+        }
+        if post, _ := m.current(); post.ID != first.ID {
+            t.Errorf("selection moved to %v", post.Title)
+        }
+
+        // --refresh 0 never ticks
+        m.refresh = 0
+        if m.tick() != nil {
+            t.Error("tick scheduled with refresh disabled")
+        }
+    })
+}
+
+func TestTUISources(t *testing.T) {
+    eachStore(t, func(t *testing.T, s *state, q database.Querier) {
+        alice := loginAs(t, s, q, "alice", roleUser)
+        blog  := addFeed(t, q, alice, "Blog", "https://blog.example.com/rss")
+        news  := addFeed(t, q, alice, "News", "https://news.example.com/rss")
+        addPost(t, q, blog, "Blog post", "https://blog.example.com/1", "", time.Hour)
+        addPost(t, q, news, "News post", "https://news.example.com/1", "", time.Hour)
+        run(t, s, "folder", "create", "Reading")
+        run(t, s, "folder", "create", "--parent", "Reading", "Tech")
+        run(t, s, "folder", "move", "Blog", "Tech")
+
+        m := startTUI(t, s)
+        labels := []string{}
+        for _, source := range m.sources {
+            labels = append(labels, source.label)
+        }
+        if strings.Join(labels, ",") != "All,Starred,Reading/,Reading/Tech/,Blog,News" {
+            t.Fatalf("sources = %v", labels)
+        }
+
+        // A folder holds the posts from its subfolders too
+        m = sendTUI(t, m, keys("j", "j")...)
+        if visible := m.visible(); len(visible) != 1 || visible[0].Title != "Blog post" {
+            t.Errorf("Reading/ posts = %v", visible)
+        }
+
+        run(t, s, "follow-settings", "--muted", "News")
+        m = startTUI(t, s)
+        if len(m.posts) != 1 || m.sources[len(m.sources) - 1].label != "Blog" {
+            t.Errorf("muted feed still shown: %v posts", len(m.posts))
+        }
+    })
+}
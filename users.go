@@ -0,0 +1,211 @@
+package main
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "internal/database"
+    "strings"
+)
+
+func userDelete(s *state, cmd command, current database.User) error {
+    name := cmd.args[0]
+    if name != current.Name && current.Role != roleAdmin {
+        return fmt.Errorf("%w | Reason: only admins can delete other users", ErrorNotAdmin)
+    }
+
+    if !cmd.flagBool("yes") {
+        if !stdinIsTerminal() {
+            return fmt.Errorf("%w | Reason: refusing to delete without --yes when not run from a terminal", ErrorResetNotConfirmed)
+        }
+        fmt.Printf("This will delete user %v and their follows.  Feeds they created are handed to another follower, or deleted if nobody else follows them.\nType yes to continue: ", name)
+        answer, _ := stdinReader.ReadString('\n')
+        if strings.TrimSpace(answer) != "yes" {
+            return ErrorResetNotConfirmed
+        }
+    }
+
+    ctx := context.Background()
+    qtx, tx, err := s.dbState.begin(ctx)
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorDeletingUsers, err)
+    }
+    defer tx.Rollback()
+
+    err = qtx.LockUsers(ctx)
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorDeletingUsers, err)
+    }
+    user, err := qtx.GetUser(ctx, name)
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorGettingUser, err)
+    }
+    err = keepLastAdmin(ctx, qtx, user)
+    if err != nil {
+        return err
+    }
+
+    transferred, deleted, err := handOverFeeds(ctx, qtx, user)
+    if err != nil {
+        return err
+    }
+
+    _, err = qtx.DeleteUserByName(ctx, user.Name)
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorDeletingUsers, err)
+    }
+
+    err = tx.Commit()
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorDeletingUsers, err)
+    }
+
+    // Deleting yourself ends your session too, the cascade already removed it from the database
+    if user.ID == current.ID {
+        err = s.cfgState.SetSession("")
+        if err != nil {
+            return fmt.Errorf("%w | Reason: %w", ErrorSettingUser, err)
+        }
+    }
+
+    fmt.Printf("User %v deleted.  %v feeds handed to other followers, %v feeds deleted.\n", user.Name, transferred, deleted)
+    return nil
+}
+
+func userRename(s *state, cmd command, current database.User) error {
+    if cmd.args[0] != current.Name && current.Role != roleAdmin {
+        return fmt.Errorf("%w | Reason: only admins can rename other users", ErrorNotAdmin)
+    }
+
+    n, err := s.dbState.RenameUser(context.Background(), database.RenameUserParams{ OldName: cmd.args[0], NewName: cmd.args[1] })
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorRenamingUser, err)
+    }
+    if n == 0 {
+        return fmt.Errorf("%w | Reason: no user named %v", ErrorGettingUser, cmd.args[0])
+    }
+
+    fmt.Printf("User %v renamed to %v\n", cmd.args[0], cmd.args[1])
+    return nil
+}
+
+// userSetPassword changes your own password, or for admins anyone's.  This is how users registered
+// before passwords existed get one, they cannot log in until an admin has set it.  Changing your
+// own takes the old one first.  Every other session of the user ends, so whoever had the old
+// password is logged out too.
+func userSetPassword(s *state, cmd command, current database.User) error {
+    if cmd.args[0] != current.Name && current.Role != roleAdmin {
+        return fmt.Errorf("%w | Reason: only admins can set other users' passwords", ErrorNotAdmin)
+    }
+
+    ctx := context.Background()
+    user, err := s.dbState.GetUser(ctx, cmd.args[0])
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorGettingUser, err)
+    }
+
+    if user.ID == current.ID && user.PasswordHash.Valid {
+        password, err := promptSecret("Current password: ")
+        if err != nil {
+            return fmt.Errorf("%w | Reason: %w", ErrorWrongPassword, err)
+        }
+        if checkPassword(user.PasswordHash.String, password) != nil {
+            return ErrorWrongPassword
+        }
+    }
+
+    hash, err := promptNewPassword()
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorSettingPassword, err)
+    }
+
+    qtx, tx, err := s.dbState.begin(ctx)
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorSettingPassword, err)
+    }
+    defer tx.Rollback()
+
+    err = qtx.SetUserPassword(ctx, database.SetUserPasswordParams{ ID: user.ID, PasswordHash: sql.NullString{ String: hash, Valid: true } })
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorSettingPassword, err)
+    }
+
+    // The session this runs in is kept, it belongs to whoever just proved they may set the password
+    ended, err := qtx.DeleteSessionsForUser(ctx, database.DeleteSessionsForUserParams{ UserID: user.ID, KeepTokenHash: hashToken(s.cfgState.SessionToken) })
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorDeletingSession, err)
+    }
+
+    err = tx.Commit()
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorSettingPassword, err)
+    }
+
+    fmt.Printf("Password set for %v, %v other sessions logged out\n", user.Name, ended)
+    return nil
+}
+
+// keepLastAdmin refuses to delete or demote the last admin who can log in.  Without one nobody can
+// manage users, and gator bootstrap would be open to whoever runs it first.  Call it with the
+// users locked, so two admins cannot remove each other at once.
+func keepLastAdmin(ctx context.Context, q database.Querier, user database.User) error {
+    if user.Role != roleAdmin || !user.PasswordHash.Valid {
+        return nil
+    }
+
+    admins, err := q.CountUsableAdmins(ctx)
+    if err != nil {
+        return fmt.Errorf("%w | Reason: %w", ErrorGettingUsers, err)
+    }
+    if admins <= 1 {
+        return fmt.Errorf("%w | Reason: %v is the only admin with a password, make another user admin first", ErrorLastAdmin, user.Name)
+    }
+    return nil
+}
+
+// handOverFeeds gets every feed a user owns out of their hands before they are deleted.  Feeds
+// others follow go to another follower, only feeds nobody else follows are deleted, so other
+// users never lose their follows or posts.
+func handOverFeeds(ctx context.Context, q database.Querier, user database.User) (transferred, deleted int64, err error) {
+    feeds, err := q.GetFeedsForUser(ctx, user.ID)
+    if err != nil {
+        return 0, 0, fmt.Errorf("%w | Reason: %w", ErrorGettingFeeds, err)
+    }
+
+    for _, feed := range feeds {
+        handedOver, err := handOverFeed(ctx, q, feed)
+        if err != nil {
+            return transferred, deleted, err
+        }
+        if handedOver {
+            transferred++
+            continue
+        }
+
+        _, err = q.DeleteFeed(ctx, feed.ID)
+        if err != nil {
+            return transferred, deleted, fmt.Errorf("%w | Reason: %w", ErrorDeletingFeeds, err)
+        }
+        deleted++
+    }
+    return transferred, deleted, nil
+}
+
+// handOverFeed gives a feed to its longest standing follower other than the owner.
+// It reports false when nobody else follows the feed.
+func handOverFeed(ctx context.Context, q database.Querier, feed database.Feed) (bool, error) {
+    next, err := q.GetNextFeedOwner(ctx, database.GetNextFeedOwnerParams{ FeedID: feed.ID, UserID: feed.UserID })
+    if errors.Is(err, sql.ErrNoRows) {
+        return false, nil
+    }
+    if err != nil {
+        return false, fmt.Errorf("%w | Reason: %w", ErrorGettingUserFeedFollows, err)
+    }
+
+    err = q.SetFeedOwner(ctx, database.SetFeedOwnerParams{ ID: feed.ID, UserID: next })
+    if err != nil {
+        return false, fmt.Errorf("%w | Reason: %w", ErrorTransferringFeed, err)
+    }
+    return true, nil
+}
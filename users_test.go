@@ -0,0 +1,81 @@
+package main
+
+import (
+    "context"
+    "internal/database"
+    "testing"
+)
+
+func TestUserDelete(t *testing.T) {
+    t.Run("self, handing over and deleting feeds", func(t *testing.T) {
+        s, fake := newTestState(t)
+        alice  := loginAs(t, s, fake, "alice", roleUser)
+        bob    := addUser(t, fake, "bob", roleUser)
+        shared := addFeed(t, fake, alice, "Shared", "https://shared.example.com/rss")
+        addFeed(t, fake, alice, "Private", "https://private.example.com/rss")
+        follow(t, fake, bob, shared)
+
+        out := run(t, s, "user", "delete", "--yes", "alice")
+        assertContains(t, out, "1 feeds handed to other followers, 1 feeds deleted")
+
+        if _, err := fake.GetUser(context.Background(), "alice"); err == nil {
+            t.Error("alice was not deleted")
+        }
+        shared, err := fake.GetFeedUrl(context.Background(), shared.Url)
+        if err != nil || shared.UserID != bob.ID {
+            t.Errorf("shared feed owner = %v, %v; want bob", shared.UserID, err)
+        }
+        if _, err := fake.GetFeedUrl(context.Background(), "https://private.example.com/rss"); err == nil {
+            t.Error("private feed was not deleted")
+        }
+        if s.cfgState.SessionToken != "" {
+            t.Error("deleting yourself should log you out")
+        }
+        if len(fake.sessions) != 0 {
+            t.Error("session outlived its user")
+        }
+        if _, ok := fake.follow(alice.ID, shared.ID); ok {
+            t.Error("follows outlived their user")
+        }
+    })
+
+    t.Run("others need admin", func(t *testing.T) {
+        s, fake := newTestState(t)
+        addUser(t, fake, "bob", roleUser)
+        loginAs(t, s, fake, "alice", roleUser)
+        assertErr(t, runErr(s, "user", "delete", "--yes", "bob"), ErrorNotAdmin)
+
+        loginAs(t, s, fake, "admin", roleAdmin)
+        run(t, s, "user", "delete", "--yes", "bob")
+        if _, err := fake.GetUser(context.Background(), "bob"); err == nil {
+            t.Error("admin could not delete bob")
+        }
+        if s.cfgState.SessionToken == "" {
+            t.Error("deleting someone else should not log you out")
+        }
+    })
+
+    t.Run("needs confirmation", func(t *testing.T) {
+        s, fake := newTestState(t)
+        loginAs(t, s, fake, "alice", roleUser)
+        assertErr(t, runErr(s, "user", "delete", "alice"), ErrorResetNotConfirmed)
+        assertErr(t, runErr(s, "user", "delete"), EmptyArgList)
+    })
+}
+
+func TestUserRename(t *testing.T) {
+    eachStore(t, func(t *testing.T, s *state, q database.Querier) {
+        loginAs(t, s, q, "alice", roleUser)
+        addUser(t, q, "bob", roleUser)
+
+        run(t, s, "user", "rename", "alice", "alicia")
+        if current, err := currentUser(s); err != nil || current.Name != "alicia" {
+            t.Errorf("current user = %v, %v; want alicia", current.Name, err)
+        }
+
+        assertErr(t, runErr(s, "user", "rename", "alicia", "bob"), ErrorRenamingUser)
+        assertErr(t, runErr(s, "user", "rename", "bob", "robert"), ErrorNotAdmin)
+        assertErr(t, runErr(s, "user", "rename", "alicia"), NotEnoughArgs)
+        assertErr(t, runErr(s, "user", "promote"), NoCommandExists)
+    })
+}